@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,11 +16,18 @@ import (
 	"time"
 
 	"github.com/corradoisidoro/sentinel-rbac/internal/config"
+	"github.com/corradoisidoro/sentinel-rbac/internal/extauthz"
 	"github.com/corradoisidoro/sentinel-rbac/internal/handler"
+	"github.com/corradoisidoro/sentinel-rbac/internal/keyring"
+	"github.com/corradoisidoro/sentinel-rbac/internal/mail"
 	"github.com/corradoisidoro/sentinel-rbac/internal/middleware"
+	"github.com/corradoisidoro/sentinel-rbac/internal/policy"
 	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
 	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 
 	_ "github.com/corradoisidoro/sentinel-rbac/docs" // Swagger docs
 	swaggerFiles "github.com/swaggo/files"
@@ -37,10 +45,17 @@ func main() {
 		log.Fatal("database URL cannot be empty")
 	}
 
-	if cfg.JWTSecret == "" {
+	if cfg.JWTSecret == "" && cfg.JWTKeysFile == "" {
 		log.Fatal("JWT secret cannot be empty")
 	}
 
+	// Load signing keys (JWT_SECRET and/or the rotating keys file)
+	keys, err := keyring.Load(cfg.JWTKeysFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatalf("failed to load signing keys: %v", err)
+	}
+	log.Printf("[INFO] signing access tokens with key %q", keys.ActiveKeyID())
+
 	// Connect to Database
 	log.Println("[INFO] connecting to database...")
 
@@ -64,11 +79,112 @@ func main() {
 
 	// Initialize Layers
 	userRepo := repository.NewUserRepository(db)
-	userService := service.NewUserService(userRepo, cfg)
+	roleRepo := repository.NewRoleRepository(db)
+	permissionRepo := repository.NewPermissionRepository(db)
+	roleBindingRepo := repository.NewRoleBindingRepository(db)
+	policyRepo := repository.NewPolicyRepository(db)
+	userAttributeRepo := repository.NewUserAttributeRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+
+	revocations, err := service.NewRevocationStore(context.Background(), revokedTokenRepo)
+	if err != nil {
+		log.Fatalf("failed to load revoked tokens: %v", err)
+	}
+
+	// Prune expired revocations and pick up those made by other instances
+	revocationCtx, stopRevocations := context.WithCancel(context.Background())
+	defer stopRevocations()
+	go revocations.Run(revocationCtx, time.Minute)
+
+	// Mail delivery (SMTP, a file outbox for development, or nothing)
+	var mailer mail.Mailer
+	switch {
+	case cfg.SMTPHost != "":
+		mailer = mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+		log.Printf("[INFO] sending mail via %s:%d", cfg.SMTPHost, cfg.SMTPPort)
+	case cfg.MailOutboxDir != "":
+		mailer, err = mail.NewFileOutbox(cfg.MailOutboxDir, cfg.MailFrom)
+		if err != nil {
+			log.Fatalf("failed to open mail outbox: %v", err)
+		}
+		log.Printf("[INFO] writing mail to %s", cfg.MailOutboxDir)
+	default:
+		mailer = mail.Discard
+		log.Println("[WARN] no SMTP_HOST or MAIL_OUTBOX_DIR set, password reset emails will be discarded")
+	}
+
+	tokenService := service.NewTokenService(refreshTokenRepo, sessionRepo, userRepo, revocations, keys, cfg)
+	mfaService := service.NewMFAService(mfaRepo, cfg)
+	userService := service.NewUserService(userRepo, cfg, tokenService, mfaService)
+	passwordResetService := service.NewPasswordResetService(passwordResetRepo, userRepo, tokenService, mailer, cfg)
+	authzService := service.NewAuthzService(roleRepo, permissionRepo, roleBindingRepo)
 	userHandler := handler.NewUserHandler(userService)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	passwordResetHandler := handler.NewPasswordResetHandler(passwordResetService)
+	authzHandler := handler.NewAuthzHandler(authzService, userService)
+	jwksHandler := handler.NewJWKSHandler(keys)
 
-	jwtSecret := []byte(cfg.JWTSecret)
-	authMiddleware := middleware.NewAuthMiddleware(jwtSecret, userRepo)
+	// Load ABAC policies (database first, then the optional policy file)
+	policies, err := policy.LoadRepository(context.Background(), policyRepo)
+	if err != nil {
+		log.Fatalf("failed to load policies: %v", err)
+	}
+
+	if cfg.PolicyFile != "" {
+		filePolicies, err := policy.LoadFile(cfg.PolicyFile)
+		if err != nil {
+			log.Fatalf("failed to load policy file: %v", err)
+		}
+		policies = append(policies, filePolicies...)
+	}
+
+	policyEngine, err := policy.NewEngine(policies...)
+	if err != nil {
+		log.Fatalf("invalid policies: %v", err)
+	}
+
+	// Load forward-auth route rules (optional; without them every proxied request is denied)
+	var forwardRules []middleware.ForwardRule
+	if cfg.ForwardFile != "" {
+		forwardRules, err = middleware.LoadForwardRules(cfg.ForwardFile)
+		if err != nil {
+			log.Fatalf("failed to load forward auth rules: %v", err)
+		}
+	}
+
+	authOptions := []middleware.AuthOption{
+		middleware.WithKeys(keys),
+		middleware.WithAuthz(authzService),
+		middleware.WithPolicies(policyEngine, userAttributeRepo),
+		middleware.WithRevocation(revocations),
+		middleware.WithSessionActivity(service.NewSessionActivity(sessionRepo, time.Minute)),
+		middleware.WithTokenValidation(
+			authz.WithIssuer(cfg.JWTIssuer),
+			authz.WithAudience(cfg.JWTAudience),
+			authz.WithLeeway(cfg.JWTLeeway),
+			authz.WithRequiredClaims("exp", "iat", "nbf", "jti"),
+		),
+	}
+
+	if len(cfg.AuthTransports) > 0 {
+		transports := make([]middleware.TokenTransport, 0, len(cfg.AuthTransports))
+		for _, t := range cfg.AuthTransports {
+			transports = append(transports, middleware.TokenTransport(t))
+		}
+		authOptions = append(authOptions, middleware.WithTransports(transports...))
+	}
+
+	authMiddleware := middleware.NewAuthMiddleware(nil, userRepo, authOptions...)
 
 	// Setup Router
 	router := gin.New()
@@ -86,6 +202,9 @@ func main() {
 
 		RouteRPS:   100,
 		RouteBurst: 200,
+
+		// Forward-auth subrequests all come from the proxy's IP
+		ProxyPaths: []string{"/api/authz/forward"},
 	})
 
 	router.Use(rateLimiter)
@@ -94,6 +213,9 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
 
+	// Public keys for services verifying access tokens locally
+	router.GET("/.well-known/jwks.json", jwksHandler.JWKS)
+
 	api := router.Group("/api")
 
 	// Auth routes
@@ -101,6 +223,11 @@ func main() {
 	{
 		auth.POST("/register", userHandler.Register)
 		auth.POST("/login", userHandler.Login)
+		auth.POST("/mfa/verify", userHandler.VerifyMFA)
+		auth.POST("/refresh", userHandler.Refresh)
+		auth.POST("/password/forgot", passwordResetHandler.ForgotPassword)
+		auth.POST("/password/reset", passwordResetHandler.ResetPassword)
+		auth.POST("/reauthenticate", authMiddleware.RequireAuth, userHandler.Reauthenticate)
 		auth.POST("/logout", authMiddleware.RequireAuth, userHandler.Logout)
 	}
 
@@ -109,7 +236,24 @@ func main() {
 	users.Use(authMiddleware.RequireAuth)
 	{
 		users.GET("/profile", userHandler.Profile)
+		users.PUT("/password", userHandler.ChangePassword)
+		users.GET("/sessions", userHandler.Sessions)
+		users.DELETE("/sessions", userHandler.RevokeOtherSessions)
+		users.DELETE("/sessions/:id", userHandler.RevokeSession)
+		users.POST("/mfa/totp", mfaHandler.EnrollTOTP)
+		users.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
 		users.GET("/admin", authMiddleware.AuthorizeRole("admin"), userHandler.Admin)
+		users.POST("/:id/revoke-tokens", authMiddleware.AuthorizeRole("admin"), authMiddleware.RequireFreshAuth(cfg.FreshAuthMaxAge), userHandler.RevokeTokens)
+	}
+
+	// Authorization routes
+	api.GET("/authz/forward", authMiddleware.ForwardAuth(forwardRules))
+
+	authzGroup := api.Group("/authz")
+	authzGroup.Use(authMiddleware.RequireAuth)
+	{
+		authzGroup.POST("/check", authzHandler.Check)
+		authzGroup.POST("/explain", authMiddleware.AuthorizeRole("admin"), authzHandler.Explain)
 	}
 
 	// Start Server
@@ -127,6 +271,27 @@ func main() {
 		}
 	}()
 
+	// Envoy ext_authz gRPC server (optional)
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != 0 {
+		grpcAddr := fmt.Sprintf(":%d", cfg.GRPCPort)
+
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatalf("grpc listen error: %v", err)
+		}
+
+		grpcServer = grpc.NewServer()
+		authv3.RegisterAuthorizationServer(grpcServer, extauthz.NewServer(authMiddleware, forwardRules))
+
+		go func() {
+			log.Printf("[INFO] starting ext_authz grpc server on %s", grpcAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("grpc serve error: %v", err)
+			}
+		}()
+	}
+
 	// Graceful Shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
@@ -137,9 +302,16 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("server shutdown failed: %v", err)
 	}
 
+	// Let password reset emails already accepted finish sending
+	passwordResetService.Wait()
+
 	log.Println("[INFO] server exited properly")
 }
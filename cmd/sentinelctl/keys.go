@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/config"
+	"github.com/corradoisidoro/sentinel-rbac/internal/keyring"
+)
+
+func runKeys(args []string) {
+	if len(args) == 0 {
+		usage()
+	}
+
+	switch args[0] {
+	case "list":
+		runKeysList(args[1:])
+	case "add":
+		runKeysAdd(args[1:])
+	case "activate":
+		runKeysActivate(args[1:])
+	case "prune":
+		runKeysPrune(args[1:])
+	default:
+		usage()
+	}
+}
+
+func keysFlags(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("keys "+name, flag.ExitOnError)
+	file := fs.String("f", os.Getenv("JWT_KEYS_FILE"), "keys file (.yaml, .yml or .json)")
+	return fs, file
+}
+
+func runKeysList(args []string) {
+	fs, file := keysFlags("list")
+	_ = fs.Parse(args)
+
+	f := readKeys(*file, false)
+	for _, key := range f.Keys {
+		alg := key.Algorithm
+		if alg == "" && key.Secret != "" {
+			alg = "HS256"
+		}
+		status := "verify"
+		switch {
+		case key.ID == f.Active:
+			status = "active"
+		case key.RetireAt != nil:
+			status = "retires " + key.RetireAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%s\t%s\t%s\n", key.ID, alg, status)
+	}
+}
+
+func runKeysAdd(args []string) {
+	fs, file := keysFlags("add")
+	id := fs.String("id", "", "key ID (default: current UTC time)")
+	alg := fs.String("alg", "HS256", "signing algorithm: HS256, RS256, ES256, ES384, ES512 or EdDSA")
+	_ = fs.Parse(args)
+
+	f := readKeys(*file, true)
+
+	key := keyring.Key{ID: *id}
+	if key.ID == "" {
+		key.ID = time.Now().UTC().Format("20060102T150405Z")
+	}
+
+	var pemData []byte
+	if strings.HasPrefix(*alg, "HS") {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("keys add: %v", err)
+		}
+		key.Secret = base64.RawURLEncoding.EncodeToString(secret)
+		if *alg != "HS256" {
+			key.Algorithm = *alg
+		}
+	} else {
+		var err error
+		if pemData, err = keyring.GeneratePrivateKey(*alg); err != nil {
+			log.Fatalf("keys add: %v", err)
+		}
+		// Written next to the keys file, which refers to it relatively.
+		key.Algorithm = *alg
+		key.PrivateKeyFile = key.ID + ".pem"
+	}
+
+	if err := f.Add(key); err != nil {
+		log.Fatalf("keys add: %v", err)
+	}
+
+	if pemData != nil {
+		pemFile := filepath.Join(filepath.Dir(*file), key.PrivateKeyFile)
+		pem, err := os.OpenFile(pemFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			_, err = pem.Write(pemData)
+			if closeErr := pem.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			log.Fatalf("keys add: %v", err)
+		}
+	}
+
+	// Never activated here: instances still running the old file could not
+	// verify tokens signed with it, so it is deployed first.
+	writeKeys(*file, f)
+	fmt.Printf("added key %s; deploy it everywhere, then run: sentinelctl keys activate -id %s\n", key.ID, key.ID)
+}
+
+func runKeysActivate(args []string) {
+	fs, file := keysFlags("activate")
+	id := fs.String("id", "", "key ID to sign with")
+	retireAfter := fs.Duration("retire-after", config.DefaultAccessTokenTTL, "keep verifying with the previous key for this long (at least ACCESS_TOKEN_TTL)")
+	_ = fs.Parse(args)
+
+	if *id == "" {
+		log.Fatal("keys activate: -id is required")
+	}
+
+	f := readKeys(*file, false)
+	previous := f.Active
+
+	if err := f.Activate(*id, time.Now().Add(*retireAfter)); err != nil {
+		log.Fatalf("keys activate: %v", err)
+	}
+
+	writeKeys(*file, f)
+	if previous != "" && previous != *id {
+		fmt.Printf("activated key %s; key %s retires in %s\n", *id, previous, *retireAfter)
+	} else {
+		fmt.Printf("activated key %s\n", *id)
+	}
+}
+
+func runKeysPrune(args []string) {
+	fs, file := keysFlags("prune")
+	_ = fs.Parse(args)
+
+	f := readKeys(*file, false)
+	removed := f.Prune(time.Now())
+
+	if len(removed) == 0 {
+		fmt.Println("no retired keys")
+		return
+	}
+
+	writeKeys(*file, f)
+	for _, id := range removed {
+		fmt.Printf("removed key %s\n", id)
+	}
+}
+
+// readKeys reads the keys file. A missing file is an empty keyring when
+// create is set.
+func readKeys(file string, create bool) *keyring.File {
+	if file == "" {
+		log.Fatal("keys: -f is required (or set JWT_KEYS_FILE)")
+	}
+
+	f, err := keyring.ReadFile(file)
+	if create && errors.Is(err, os.ErrNotExist) {
+		return &keyring.File{}
+	}
+	if err != nil {
+		log.Fatalf("keys: %v", err)
+	}
+	return f
+}
+
+func writeKeys(file string, f *keyring.File) {
+	if err := keyring.WriteFile(file, f); err != nil {
+		log.Fatalf("keys: %v", err)
+	}
+}
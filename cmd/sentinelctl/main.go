@@ -0,0 +1,145 @@
+// Command sentinelctl provisions the access model from a policy file and
+// dumps it back out, so environments can be managed from version control.
+// It also manages the JWT_KEYS_FILE signing keys.
+//
+//	sentinelctl import -f access.yaml [-dry-run]
+//	sentinelctl export [-o access.yaml] [-format yaml|json]
+//	sentinelctl keys add -f keys.yaml [-id ID] [-alg ALG]
+//	sentinelctl keys activate -f keys.yaml -id ID [-retire-after 15m]
+//	sentinelctl keys prune -f keys.yaml
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/policy"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	// Best-effort .env loading (dev only)
+	_ = godotenv.Load()
+
+	switch os.Args[1] {
+	case "import":
+		runImport(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	case "keys":
+		runKeys(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: sentinelctl import -f FILE [-dry-run] [-db DSN]")
+	fmt.Fprintln(os.Stderr, "       sentinelctl export [-o FILE] [-format yaml|json] [-db DSN]")
+	fmt.Fprintln(os.Stderr, "       sentinelctl keys list [-f FILE]")
+	fmt.Fprintln(os.Stderr, "       sentinelctl keys add [-f FILE] [-id ID] [-alg ALG]")
+	fmt.Fprintln(os.Stderr, "       sentinelctl keys activate [-f FILE] -id ID [-retire-after DURATION]")
+	fmt.Fprintln(os.Stderr, "       sentinelctl keys prune [-f FILE]")
+	os.Exit(2)
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("f", "", "policy file to import (.yaml, .yml or .json)")
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	dsn := fs.String("db", os.Getenv("DATABASE_URL"), "database DSN")
+	_ = fs.Parse(args)
+
+	if *file == "" {
+		log.Fatal("import: -f is required")
+	}
+
+	doc, err := policy.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("import: %v", err)
+	}
+
+	syncer := connect(*dsn)
+
+	changes, err := syncer.Import(context.Background(), doc, *dryRun)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if err != nil {
+		log.Fatalf("import: %v", err)
+	}
+
+	switch {
+	case len(changes) == 0:
+		fmt.Println("already up to date")
+	case *dryRun:
+		fmt.Printf("%d change(s) would be applied\n", len(changes))
+	default:
+		fmt.Printf("%d change(s) applied\n", len(changes))
+	}
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("o", "", "write to FILE instead of stdout")
+	format := fs.String("format", "", "output format, yaml or json (default: from -o, else yaml)")
+	dsn := fs.String("db", os.Getenv("DATABASE_URL"), "database DSN")
+	_ = fs.Parse(args)
+
+	f := policy.FormatYAML
+	if *format != "" {
+		f = *format
+	} else if *out != "" {
+		detected, err := policy.FormatFromPath(*out)
+		if err != nil {
+			log.Fatalf("export: %v", err)
+		}
+		f = detected
+	}
+
+	doc, err := connect(*dsn).Export(context.Background())
+	if err != nil {
+		log.Fatalf("export: %v", err)
+	}
+
+	data, err := policy.Encode(doc, f)
+	if err != nil {
+		log.Fatalf("export: %v", err)
+	}
+
+	if *out == "" {
+		_, _ = os.Stdout.Write(data)
+		return
+	}
+
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("export: %v", err)
+	}
+}
+
+func connect(dsn string) policy.Syncer {
+	if dsn == "" {
+		log.Fatal("database URL cannot be empty (set DATABASE_URL or -db)")
+	}
+
+	db, err := repository.Connect(dsn)
+	if err != nil {
+		log.Fatalf("database connection failed: %v", err)
+	}
+
+	if err := repository.Migrate(db); err != nil {
+		log.Fatalf("migration failed: %v", err)
+	}
+
+	return policy.NewSyncer(db, policy.NewStore)
+}
@@ -3,16 +3,82 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Token lifetimes used when ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL are unset.
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// DefaultFreshAuthMaxAge is how recently a user must have authenticated for
+// routes requiring a fresh login when FRESH_AUTH_MAX_AGE is unset.
+const DefaultFreshAuthMaxAge = 10 * time.Minute
+
+// Password reset and mail defaults used when PASSWORD_RESET_TTL, SMTP_PORT
+// and MAIL_FROM are unset.
+const (
+	DefaultPasswordResetTTL = 30 * time.Minute
+	DefaultSMTPPort         = 587
+	DefaultMailFrom         = "no-reply@localhost"
+)
+
+// Issuer and audience of access tokens when JWT_ISSUER and JWT_AUDIENCE are
+// unset.
+const (
+	DefaultJWTIssuer   = "sentinel-rbac"
+	DefaultJWTAudience = "sentinel-rbac"
+)
+
 type Config struct {
 	ServerPort  int
 	DatabaseURL string
 	JWTSecret   string
+	JWTKeysFile string
+	PolicyFile  string
+	ForwardFile string
+	GRPCPort    int
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// JWTIssuer and JWTAudience are the "iss" and "aud" of issued access
+	// tokens; tokens naming another issuer or audience are rejected.
+	// JWTLeeway is the clock skew tolerated on "exp", "nbf" and "iat".
+	JWTIssuer   string
+	JWTAudience string
+	JWTLeeway   time.Duration
+
+	// FreshAuthMaxAge is how long after logging in or reauthenticating a
+	// user may perform sensitive actions without reauthenticating.
+	FreshAuthMaxAge time.Duration
+
+	// PasswordResetTTL is how long a password reset link stays valid.
+	// PasswordResetURL is the page the link opens, with the token appended
+	// as the "token" query parameter; without it the email holds the bare
+	// token.
+	PasswordResetTTL time.Duration
+	PasswordResetURL string
+
+	// Emails are sent through SMTPHost when set, otherwise written to
+	// MailOutboxDir when set, otherwise discarded.
+	SMTPHost      string
+	SMTPPort      int
+	SMTPUsername  string
+	SMTPPassword  string
+	MailFrom      string
+	MailOutboxDir string
+
+	// AuthTransports lists where access tokens are read from, in order of
+	// precedence: "cookie" and/or "bearer". Empty means cookie, then bearer.
+	AuthTransports []string
 }
 
 func Load() (Config, error) {
@@ -20,7 +86,16 @@ func Load() (Config, error) {
 	_ = godotenv.Load()
 
 	cfg := Config{
-		ServerPort: 3000, // safe default
+		ServerPort:      3000, // safe default
+		AccessTokenTTL:  DefaultAccessTokenTTL,
+		RefreshTokenTTL: DefaultRefreshTokenTTL,
+		JWTIssuer:       DefaultJWTIssuer,
+		JWTAudience:     DefaultJWTAudience,
+		FreshAuthMaxAge: DefaultFreshAuthMaxAge,
+
+		PasswordResetTTL: DefaultPasswordResetTTL,
+		SMTPPort:         DefaultSMTPPort,
+		MailFrom:         DefaultMailFrom,
 	}
 
 	if v := os.Getenv("SERVER_PORT"); v != "" {
@@ -39,6 +114,116 @@ func Load() (Config, error) {
 		cfg.JWTSecret = v
 	}
 
+	if v := os.Getenv("JWT_KEYS_FILE"); v != "" {
+		cfg.JWTKeysFile = v
+	}
+
+	if v := os.Getenv("POLICY_FILE"); v != "" {
+		cfg.PolicyFile = v
+	}
+
+	if v := os.Getenv("GRPC_PORT"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid GRPC_PORT: %w", err)
+		}
+		cfg.GRPCPort = p
+	}
+
+	if v := os.Getenv("FORWARD_AUTH_FILE"); v != "" {
+		cfg.ForwardFile = v
+	}
+
+	if v := os.Getenv("ACCESS_TOKEN_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid ACCESS_TOKEN_TTL: %w", err)
+		}
+		cfg.AccessTokenTTL = d
+	}
+
+	if v := os.Getenv("REFRESH_TOKEN_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid REFRESH_TOKEN_TTL: %w", err)
+		}
+		cfg.RefreshTokenTTL = d
+	}
+
+	if v := os.Getenv("JWT_ISSUER"); v != "" {
+		cfg.JWTIssuer = v
+	}
+
+	if v := os.Getenv("JWT_AUDIENCE"); v != "" {
+		cfg.JWTAudience = v
+	}
+
+	if v := os.Getenv("JWT_LEEWAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid JWT_LEEWAY: %w", err)
+		}
+		cfg.JWTLeeway = d
+	}
+
+	if v := os.Getenv("FRESH_AUTH_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid FRESH_AUTH_MAX_AGE: %w", err)
+		}
+		// A zero max age would demand reauthentication on every request.
+		if d <= 0 {
+			return Config{}, errors.New("config: invalid FRESH_AUTH_MAX_AGE: must be positive")
+		}
+		cfg.FreshAuthMaxAge = d
+	}
+
+	if v := os.Getenv("PASSWORD_RESET_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid PASSWORD_RESET_TTL: %w", err)
+		}
+		cfg.PasswordResetTTL = d
+	}
+
+	if v := os.Getenv("PASSWORD_RESET_URL"); v != "" {
+		cfg.PasswordResetURL = v
+	}
+
+	if v := os.Getenv("SMTP_HOST"); v != "" {
+		cfg.SMTPHost = v
+	}
+
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid SMTP_PORT: %w", err)
+		}
+		cfg.SMTPPort = p
+	}
+
+	if v := os.Getenv("SMTP_USERNAME"); v != "" {
+		cfg.SMTPUsername = v
+	}
+
+	if v := os.Getenv("SMTP_PASSWORD"); v != "" {
+		cfg.SMTPPassword = v
+	}
+
+	if v := os.Getenv("MAIL_FROM"); v != "" {
+		cfg.MailFrom = v
+	}
+
+	if v := os.Getenv("MAIL_OUTBOX_DIR"); v != "" {
+		cfg.MailOutboxDir = v
+	}
+
+	if v := os.Getenv("AUTH_TRANSPORTS"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			cfg.AuthTransports = append(cfg.AuthTransports, strings.ToLower(strings.TrimSpace(t)))
+		}
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return Config{}, err
 	}
@@ -50,11 +235,44 @@ func (c Config) Validate() error {
 	if c.ServerPort <= 0 || c.ServerPort > 65535 {
 		return errors.New("config: invalid SERVER_PORT")
 	}
+	if c.GRPCPort < 0 || c.GRPCPort > 65535 {
+		return errors.New("config: invalid GRPC_PORT")
+	}
+	if c.AccessTokenTTL < 0 {
+		return errors.New("config: invalid ACCESS_TOKEN_TTL")
+	}
+	if c.RefreshTokenTTL < 0 || (c.RefreshTokenTTL > 0 && c.RefreshTokenTTL <= c.AccessTokenTTL) {
+		return errors.New("config: invalid REFRESH_TOKEN_TTL")
+	}
+	if c.JWTLeeway < 0 {
+		return errors.New("config: invalid JWT_LEEWAY")
+	}
+	if c.FreshAuthMaxAge < 0 {
+		return errors.New("config: invalid FRESH_AUTH_MAX_AGE")
+	}
+	if c.PasswordResetTTL < 0 {
+		return errors.New("config: invalid PASSWORD_RESET_TTL")
+	}
+	if c.PasswordResetURL != "" {
+		if u, err := url.Parse(c.PasswordResetURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("config: invalid PASSWORD_RESET_URL")
+		}
+	}
+	if c.SMTPHost != "" && (c.SMTPPort <= 0 || c.SMTPPort > 65535) {
+		return errors.New("config: invalid SMTP_PORT")
+	}
+	seen := make(map[string]bool, len(c.AuthTransports))
+	for _, t := range c.AuthTransports {
+		if (t != "cookie" && t != "bearer") || seen[t] {
+			return errors.New("config: invalid AUTH_TRANSPORTS")
+		}
+		seen[t] = true
+	}
 	if c.DatabaseURL == "" {
 		return errors.New("config: missing DATABASE_URL")
 	}
-	if c.JWTSecret == "" {
-		return errors.New("config: missing JWT_SECRET")
+	if c.JWTSecret == "" && c.JWTKeysFile == "" {
+		return errors.New("config: missing JWT_SECRET or JWT_KEYS_FILE")
 	}
 	return nil
 }
@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/corradoisidoro/sentinel-rbac/internal/config"
 	"github.com/stretchr/testify/require"
@@ -20,6 +21,124 @@ func TestLoad_Success(t *testing.T) {
 	require.Equal(t, "super-secret", cfg.JWTSecret)
 }
 
+func TestLoad_TokenTTL(t *testing.T) {
+	t.Setenv("DATABASE_URL", "test.db")
+	t.Setenv("JWT_SECRET", "secret")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, config.DefaultAccessTokenTTL, cfg.AccessTokenTTL)
+	require.Equal(t, config.DefaultRefreshTokenTTL, cfg.RefreshTokenTTL)
+
+	t.Setenv("ACCESS_TOKEN_TTL", "5m")
+	t.Setenv("REFRESH_TOKEN_TTL", "168h")
+
+	cfg, err = config.Load()
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Minute, cfg.AccessTokenTTL)
+	require.Equal(t, 7*24*time.Hour, cfg.RefreshTokenTTL)
+
+	t.Setenv("ACCESS_TOKEN_TTL", "soon")
+	_, err = config.Load()
+	require.Error(t, err)
+}
+
+func TestLoad_TokenClaims(t *testing.T) {
+	t.Setenv("DATABASE_URL", "test.db")
+	t.Setenv("JWT_SECRET", "secret")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, config.DefaultJWTIssuer, cfg.JWTIssuer)
+	require.Equal(t, config.DefaultJWTAudience, cfg.JWTAudience)
+	require.Zero(t, cfg.JWTLeeway)
+
+	t.Setenv("JWT_ISSUER", "https://auth.example.com")
+	t.Setenv("JWT_AUDIENCE", "billing")
+	t.Setenv("JWT_LEEWAY", "30s")
+
+	cfg, err = config.Load()
+	require.NoError(t, err)
+	require.Equal(t, "https://auth.example.com", cfg.JWTIssuer)
+	require.Equal(t, "billing", cfg.JWTAudience)
+	require.Equal(t, 30*time.Second, cfg.JWTLeeway)
+
+	t.Setenv("JWT_LEEWAY", "-1s")
+	_, err = config.Load()
+	require.Error(t, err)
+}
+
+func TestLoad_FreshAuthMaxAge(t *testing.T) {
+	t.Setenv("DATABASE_URL", "test.db")
+	t.Setenv("JWT_SECRET", "secret")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, config.DefaultFreshAuthMaxAge, cfg.FreshAuthMaxAge)
+
+	t.Setenv("FRESH_AUTH_MAX_AGE", "2m")
+
+	cfg, err = config.Load()
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, cfg.FreshAuthMaxAge)
+
+	for _, v := range []string{"0", "0s", "-1m"} {
+		t.Setenv("FRESH_AUTH_MAX_AGE", v)
+		_, err = config.Load()
+		require.Error(t, err, v)
+	}
+}
+
+func TestLoad_PasswordReset(t *testing.T) {
+	t.Setenv("DATABASE_URL", "test.db")
+	t.Setenv("JWT_SECRET", "secret")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, config.DefaultPasswordResetTTL, cfg.PasswordResetTTL)
+	require.Equal(t, config.DefaultSMTPPort, cfg.SMTPPort)
+	require.Equal(t, config.DefaultMailFrom, cfg.MailFrom)
+	require.Empty(t, cfg.SMTPHost)
+
+	t.Setenv("PASSWORD_RESET_TTL", "1h")
+	t.Setenv("PASSWORD_RESET_URL", "https://app.example.com/reset-password")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_PORT", "465")
+	t.Setenv("SMTP_USERNAME", "mailer")
+	t.Setenv("SMTP_PASSWORD", "hunter2")
+	t.Setenv("MAIL_FROM", "accounts@example.com")
+
+	cfg, err = config.Load()
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, cfg.PasswordResetTTL)
+	require.Equal(t, "https://app.example.com/reset-password", cfg.PasswordResetURL)
+	require.Equal(t, "smtp.example.com", cfg.SMTPHost)
+	require.Equal(t, 465, cfg.SMTPPort)
+	require.Equal(t, "mailer", cfg.SMTPUsername)
+	require.Equal(t, "hunter2", cfg.SMTPPassword)
+	require.Equal(t, "accounts@example.com", cfg.MailFrom)
+
+	t.Setenv("PASSWORD_RESET_URL", "/reset-password")
+	_, err = config.Load()
+	require.Error(t, err)
+
+	t.Setenv("PASSWORD_RESET_URL", "")
+	t.Setenv("SMTP_PORT", "70000")
+	_, err = config.Load()
+	require.Error(t, err)
+}
+
+func TestLoad_AuthTransports(t *testing.T) {
+	t.Setenv("DATABASE_URL", "test.db")
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("AUTH_TRANSPORTS", "Bearer, cookie")
+
+	cfg, err := config.Load()
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"bearer", "cookie"}, cfg.AuthTransports)
+}
+
 func TestLoad_DefaultPort(t *testing.T) {
 	t.Setenv("DATABASE_URL", "test.db")
 	t.Setenv("JWT_SECRET", "secret")
@@ -100,6 +219,46 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			valid: false,
 		},
+		{
+			name: "refresh ttl shorter than access ttl",
+			cfg: config.Config{
+				ServerPort:      3000,
+				DatabaseURL:     "db",
+				JWTSecret:       "secret",
+				AccessTokenTTL:  time.Hour,
+				RefreshTokenTTL: time.Minute,
+			},
+			valid: false,
+		},
+		{
+			name: "auth transports",
+			cfg: config.Config{
+				ServerPort:     3000,
+				DatabaseURL:    "db",
+				JWTSecret:      "secret",
+				AuthTransports: []string{"bearer", "cookie"},
+			},
+			valid: true,
+		},
+		{
+			name: "unknown auth transport",
+			cfg: config.Config{
+				ServerPort:     3000,
+				DatabaseURL:    "db",
+				JWTSecret:      "secret",
+				AuthTransports: []string{"bearer", "query"},
+			},
+			valid: false,
+		},
+		{
+			name: "keys file without jwt secret",
+			cfg: config.Config{
+				ServerPort:  3000,
+				DatabaseURL: "db",
+				JWTKeysFile: "keys.yaml",
+			},
+			valid: true,
+		},
 		{
 			name: "missing jwt secret",
 			cfg: config.Config{
@@ -11,19 +11,38 @@ var (
 	ErrInvalidInput      = errors.New("invalid input")
 	ErrInternal          = errors.New("internal server error")
 	ErrInvalidRole       = errors.New("invalid role")
+	ErrResourceNotFound  = errors.New("resource not found")
+	ErrSessionNotFound   = errors.New("session not found")
 
 	// --- Auth & JWT Errors ---
 	ErrInvalidPassword         = errors.New("invalid email or password")
 	ErrNoTokenProvided         = errors.New("no token provided")
 	ErrTokenExpiredOrInvalid   = errors.New("invalid or expired token")
+	ErrTokenRevoked            = errors.New("token has been revoked")
 	ErrUnexpectedSigningMethod = errors.New("unexpected signing method")
 	ErrInvalidTokenSubject     = errors.New("invalid token subject")
 	ErrInvalidClaims           = errors.New("invalid token claims")
+	ErrInvalidTokenIssuer      = errors.New("invalid token issuer")
+	ErrInvalidTokenAudience    = errors.New("invalid token audience")
 	ErrFailedToGenerateToken   = errors.New("failed to generate token")
+	ErrInvalidRefreshToken     = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused      = errors.New("refresh token reuse detected")
+	ErrReauthRequired          = errors.New("reauthentication required")
+	ErrInvalidResetToken       = errors.New("invalid or expired reset token")
+
+	// --- MFA Errors ---
+	ErrMFARequired       = errors.New("mfa required")
+	ErrInvalidMFACode    = errors.New("invalid mfa code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+	ErrMFANotEnrolled    = errors.New("mfa enrollment not started")
+	ErrMFALocked         = errors.New("too many failed mfa attempts, try again later")
 
 	// --- RBAC Errors ---
 	ErrPermissionDenied = errors.New("permission denied")
 	ErrUnauthorized     = errors.New("unauthorized access")
+	ErrRoleNotFound     = errors.New("role not found")
+	ErrRoleCycle        = errors.New("role hierarchy cycle detected")
 
 	// --- Handler Errors ---
 	ErrFailedToParseRequestBody = errors.New("failed to parse request body")
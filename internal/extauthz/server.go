@@ -0,0 +1,130 @@
+// Package extauthz implements the Envoy external authorization gRPC API
+// (envoy.service.auth.v3.Authorization) on top of the same token validation,
+// route rules and RBAC evaluator as the HTTP forward-auth endpoint, so mesh
+// traffic can be authorized without an HTTP hop.
+package extauthz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/middleware"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+
+	auth  *middleware.AuthMiddleware
+	rules []middleware.ForwardRule
+}
+
+func NewServer(auth *middleware.AuthMiddleware, rules []middleware.ForwardRule) *Server {
+	return &Server{auth: auth, rules: rules}
+}
+
+// Check authorizes the HTTP request described by req. Allowed requests are
+// forwarded upstream with the X-Auth-User-* identity headers; denied ones are
+// answered by Envoy with the status and JSON error body the HTTP API uses.
+func (s *Server) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	httpReq := req.GetAttributes().GetRequest().GetHttp()
+
+	header := make(http.Header, len(httpReq.GetHeaders()))
+	for k, v := range httpReq.GetHeaders() {
+		header.Set(k, v)
+	}
+
+	result := s.auth.CheckForward(ctx, s.rules, middleware.ForwardRequest{
+		Method: httpReq.GetMethod(),
+		Host:   httpReq.GetHost(),
+		URI:    httpReq.GetPath(),
+		Header: header,
+	})
+
+	if result.Status == http.StatusOK {
+		return allowed(result), nil
+	}
+
+	return denied(result), nil
+}
+
+func allowed(result middleware.ForwardResult) *authv3.CheckResponse {
+	identity := result.IdentityHeaders()
+
+	headers := make([]*corev3.HeaderValueOption, 0, len(identity))
+	for _, k := range []string{middleware.HeaderAuthUserID, middleware.HeaderAuthUserEmail, middleware.HeaderAuthUserRoles} {
+		v, ok := identity[k]
+		if !ok {
+			continue
+		}
+		headers = append(headers, &corev3.HeaderValueOption{
+			Header:       &corev3.HeaderValue{Key: k, Value: v},
+			AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		})
+	}
+
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{
+			OkResponse: &authv3.OkHttpResponse{
+				Headers:         headers,
+				HeadersToRemove: spoofableHeaders(identity),
+			},
+		},
+	}
+}
+
+func denied(result middleware.ForwardResult) *authv3.CheckResponse {
+	body := map[string]string{"error": result.Err.Error()}
+	if result.Reason != "" {
+		body["reason"] = result.Reason
+	}
+	payload, _ := json.Marshal(body)
+
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(grpcCode(result.Status)), Message: result.Err.Error()},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status: &typev3.HttpStatus{Code: typev3.StatusCode(result.Status)},
+				Headers: []*corev3.HeaderValueOption{{
+					Header:       &corev3.HeaderValue{Key: "Content-Type", Value: "application/json"},
+					AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+				}},
+				Body: string(payload),
+			},
+		},
+	}
+}
+
+// spoofableHeaders lists the identity headers to strip from requests on
+// public routes, where none are set, so upstreams never see client-supplied
+// values. Authenticated requests have them overwritten instead.
+func spoofableHeaders(identity map[string]string) []string {
+	if identity != nil {
+		return nil
+	}
+	names := []string{middleware.HeaderAuthUserID, middleware.HeaderAuthUserEmail, middleware.HeaderAuthUserRoles}
+	for i, n := range names {
+		names[i] = strings.ToLower(n)
+	}
+	return names
+}
+
+func grpcCode(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,136 @@
+package extauthz_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/extauthz"
+	"github.com/corradoisidoro/sentinel-rbac/internal/middleware"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	serviceMocks "github.com/corradoisidoro/sentinel-rbac/internal/service/mocks"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"gorm.io/gorm"
+)
+
+func newServer(t *testing.T) (*extauthz.Server, *serviceMocks.AuthzServiceMock, string) {
+	t.Helper()
+
+	user := &models.User{Model: gorm.Model{ID: 1}, Email: "alice@example.com", Role: "user"}
+
+	repo := new(mocks.UserRepositoryMock)
+	repo.On("FindById", mock.Anything, uint(1)).Return(user, nil)
+
+	authz := new(serviceMocks.AuthzServiceMock)
+	authz.On("EffectiveRoles", mock.Anything, user).Return([]models.Role{{Name: "user"}}, nil)
+
+	m := middleware.NewAuthMiddleware([]byte("secret"), repo, middleware.WithAuthz(authz))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": float64(1),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	tokenString, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	rules := []middleware.ForwardRule{
+		{Path: "/health", Public: true},
+		{Path: "/invoices/**", Permissions: []string{"invoices:read"}},
+	}
+
+	return extauthz.NewServer(m, rules), authz, tokenString
+}
+
+func checkRequest(method, path string, headers map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method:  method,
+					Path:    path,
+					Host:    "api.internal",
+					Headers: headers,
+				},
+			},
+		},
+	}
+}
+
+func TestCheck_Allowed(t *testing.T) {
+	s, authz, token := newServer(t)
+	authz.On("Authorize", mock.Anything, mock.Anything, service.RequireAll, []string{"invoices:read"}).
+		Return(service.Decision{Allowed: true}, nil)
+
+	resp, err := s.Check(context.Background(), checkRequest("GET", "/invoices/7", map[string]string{
+		"authorization": "Bearer " + token,
+	}))
+
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+
+	headers := map[string]string{}
+	for _, h := range resp.GetOkResponse().GetHeaders() {
+		headers[h.GetHeader().GetKey()] = h.GetHeader().GetValue()
+	}
+	require.Equal(t, map[string]string{
+		middleware.HeaderAuthUserID:    "1",
+		middleware.HeaderAuthUserEmail: "alice@example.com",
+		middleware.HeaderAuthUserRoles: "user",
+	}, headers)
+}
+
+func TestCheck_CookieToken(t *testing.T) {
+	s, authz, token := newServer(t)
+	authz.On("Authorize", mock.Anything, mock.Anything, service.RequireAll, []string{"invoices:read"}).
+		Return(service.Decision{Allowed: true}, nil)
+
+	resp, err := s.Check(context.Background(), checkRequest("GET", "/invoices", map[string]string{
+		"cookie": "theme=dark; Authorization=" + token,
+	}))
+
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+}
+
+func TestCheck_Denied(t *testing.T) {
+	s, authz, token := newServer(t)
+	authz.On("Authorize", mock.Anything, mock.Anything, service.RequireAll, []string{"invoices:read"}).
+		Return(service.Decision{Reason: `no grant for "invoices:read"`}, nil)
+
+	resp, err := s.Check(context.Background(), checkRequest("GET", "/invoices/7", map[string]string{
+		"authorization": "Bearer " + token,
+	}))
+
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.PermissionDenied), resp.GetStatus().GetCode())
+	require.EqualValues(t, 403, resp.GetDeniedResponse().GetStatus().GetCode())
+	require.Contains(t, resp.GetDeniedResponse().GetBody(), "no grant")
+}
+
+func TestCheck_Unauthenticated(t *testing.T) {
+	s, _, _ := newServer(t)
+
+	resp, err := s.Check(context.Background(), checkRequest("GET", "/invoices", nil))
+
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.Unauthenticated), resp.GetStatus().GetCode())
+	require.EqualValues(t, 401, resp.GetDeniedResponse().GetStatus().GetCode())
+}
+
+func TestCheck_PublicStripsIdentityHeaders(t *testing.T) {
+	s, _, _ := newServer(t)
+
+	resp, err := s.Check(context.Background(), checkRequest("GET", "/health", map[string]string{
+		"x-auth-user-id": "1",
+	}))
+
+	require.NoError(t, err)
+	require.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+	require.Contains(t, resp.GetOkResponse().GetHeadersToRemove(), "x-auth-user-id")
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"net/http"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthzHandler struct {
+	authz service.AuthzService
+	users service.UserService
+}
+
+type subjectRequest struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+}
+
+type resourceRequest struct {
+	Type string `json:"type" binding:"required"`
+	ID   string `json:"id" binding:"required"`
+}
+
+type explainRequest struct {
+	Subject  subjectRequest   `json:"subject"`
+	Action   string           `json:"action"`
+	Actions  []string         `json:"actions"`
+	Mode     string           `json:"mode" binding:"omitempty,oneof=all any"`
+	Resource *resourceRequest `json:"resource"`
+}
+
+// maxBatchChecks bounds the size of a single check request.
+const maxBatchChecks = 200
+
+type checkItem struct {
+	Action   string           `json:"action" binding:"required"`
+	Resource *resourceRequest `json:"resource,omitempty"`
+}
+
+type checkRequest struct {
+	Checks []checkItem `json:"checks" binding:"required,min=1,dive"`
+}
+
+type checkResult struct {
+	checkItem
+	Allowed bool `json:"allowed"`
+}
+
+type checkResponse struct {
+	Results []checkResult `json:"results"`
+}
+
+type grantResponse struct {
+	Permission string `json:"permission"`
+	Effect     string `json:"effect"`
+	Role       string `json:"role,omitempty"`
+	User       bool   `json:"user,omitempty"`
+}
+
+type decisionResponse struct {
+	Permission string         `json:"permission,omitempty"`
+	Allowed    bool           `json:"allowed"`
+	Reason     string         `json:"reason"`
+	Grant      *grantResponse `json:"grant,omitempty"`
+}
+
+type roleSourceResponse struct {
+	Role   string `json:"role"`
+	Source string `json:"source"`
+	Via    string `json:"via,omitempty"`
+}
+
+type explainResponse struct {
+	Subject gin.H `json:"subject"`
+	decisionResponse
+	Checks []decisionResponse   `json:"checks"`
+	Roles  []roleSourceResponse `json:"roles"`
+	Grants []grantResponse      `json:"grants"`
+	Denies []grantResponse      `json:"denies"`
+}
+
+func NewAuthzHandler(authz service.AuthzService, users service.UserService) *AuthzHandler {
+	return &AuthzHandler{authz: authz, users: users}
+}
+
+// Explain godoc
+// @Summary Explain an authorization decision
+// @Description Evaluates one or more permissions for a user, optionally on a resource, and returns the decision with the roles, grants and deny rules behind it. Admin only.
+// @Tags Authz
+// @Accept json
+// @Produce json
+// @Param request body explainRequest true "Subject, action(s) and optional resource"
+// @Success 200 {object} explainResponse "Decision and its explanation"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /authz/explain [post]
+func (h *AuthzHandler) Explain(c *gin.Context) {
+	var req explainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+		return
+	}
+
+	perms := req.Actions
+	if req.Action != "" {
+		perms = append([]string{req.Action}, perms...)
+	}
+
+	if len(perms) == 0 || (req.Subject.ID == 0 && req.Subject.Email == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrInvalidInput.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var (
+		user *models.User
+		err  error
+	)
+	if req.Subject.ID != 0 {
+		user, err = h.users.FindUser(ctx, req.Subject.ID)
+	} else {
+		user, err = h.users.FindUserByEmail(ctx, req.Subject.Email)
+	}
+	if err != nil {
+		writeAuthzError(c, err)
+		return
+	}
+
+	mode := service.RequireAll
+	if req.Mode == "any" {
+		mode = service.RequireAny
+	}
+
+	var resource *service.Resource
+	if req.Resource != nil {
+		resource = &service.Resource{Type: req.Resource.Type, ID: req.Resource.ID}
+	}
+
+	exp, err := h.authz.Explain(ctx, user, resource, mode, perms...)
+	if err != nil {
+		writeAuthzError(c, err)
+		return
+	}
+
+	resp := explainResponse{
+		Subject: gin.H{
+			"id":    user.ID,
+			"email": user.Email,
+			"role":  user.Role,
+		},
+		decisionResponse: toDecisionResponse(exp.Decision),
+		Checks:           make([]decisionResponse, 0, len(exp.Checks)),
+		Roles:            make([]roleSourceResponse, 0, len(exp.Roles)),
+		Grants:           []grantResponse{},
+		Denies:           []grantResponse{},
+	}
+
+	for _, d := range exp.Checks {
+		resp.Checks = append(resp.Checks, toDecisionResponse(d))
+	}
+
+	for _, r := range exp.Roles {
+		resp.Roles = append(resp.Roles, roleSourceResponse{Role: r.Role.Name, Source: r.Source, Via: r.Via})
+	}
+
+	for _, g := range exp.Grants {
+		if g.Effect != models.EffectAllow {
+			resp.Denies = append(resp.Denies, toGrantResponse(g))
+		} else {
+			resp.Grants = append(resp.Grants, toGrantResponse(g))
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Check godoc
+// @Summary Check permissions in bulk
+// @Description Evaluates a batch of (action, resource) pairs for the authenticated user and returns allow/deny for each, in request order.
+// @Tags Authz
+// @Accept json
+// @Produce json
+// @Param request body checkRequest true "Checks to evaluate"
+// @Success 200 {object} checkResponse "One result per check"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /authz/check [post]
+func (h *AuthzHandler) Check(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrUnauthorized.Error()})
+		return
+	}
+
+	var req checkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+		return
+	}
+
+	if len(req.Checks) > maxBatchChecks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrInvalidInput.Error()})
+		return
+	}
+
+	checks := make([]service.Check, 0, len(req.Checks))
+	for _, item := range req.Checks {
+		check := service.Check{Permission: item.Action}
+		if item.Resource != nil {
+			check.Resource = &service.Resource{Type: item.Resource.Type, ID: item.Resource.ID}
+		}
+		checks = append(checks, check)
+	}
+
+	decisions, err := h.authz.AuthorizeBatch(c.Request.Context(), user, checks)
+	if err != nil {
+		writeAuthzError(c, err)
+		return
+	}
+
+	resp := checkResponse{Results: make([]checkResult, 0, len(decisions))}
+	for i, d := range decisions {
+		resp.Results = append(resp.Results, checkResult{checkItem: req.Checks[i], Allowed: d.Allowed})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func toDecisionResponse(d service.Decision) decisionResponse {
+	resp := decisionResponse{
+		Permission: d.Permission,
+		Allowed:    d.Allowed,
+		Reason:     d.Reason,
+	}
+	if d.Grant != nil {
+		g := toGrantResponse(*d.Grant)
+		resp.Grant = &g
+	}
+	return resp
+}
+
+func toGrantResponse(g models.PermissionGrant) grantResponse {
+	return grantResponse{
+		Permission: g.Permission,
+		Effect:     g.Effect,
+		Role:       g.RoleName,
+		User:       g.RoleName == "",
+	}
+}
+
+func writeAuthzError(c *gin.Context, err error) {
+	switch err {
+	case appErr.ErrInvalidInput:
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrInvalidInput.Error()})
+	case appErr.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": appErr.ErrUserNotFound.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+	}
+}
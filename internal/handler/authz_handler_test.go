@@ -0,0 +1,162 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/handler"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	serviceMocks "github.com/corradoisidoro/sentinel-rbac/internal/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupAuthzRouter(h *handler.AuthzHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/explain", h.Explain)
+	return r
+}
+
+func postJSON(router *gin.Engine, path string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestExplainHandler_Success(t *testing.T) {
+	authz := new(serviceMocks.AuthzServiceMock)
+	users := new(serviceMocks.UserServiceMock)
+	router := setupAuthzRouter(handler.NewAuthzHandler(authz, users))
+
+	alice := &models.User{Model: gorm.Model{ID: 7}, Email: "alice@example.com"}
+	deny := models.PermissionGrant{Permission: "users:delete", Effect: models.EffectDeny, RoleID: 3, RoleName: "contractor"}
+
+	users.On("FindUserByEmail", mock.Anything, "alice@example.com").Return(alice, nil)
+	authz.On("Explain", mock.Anything, alice, &service.Resource{Type: "project", ID: "42"}, service.RequireAll, []string{"users:delete"}).
+		Return(service.Explanation{
+			Decision: service.Decision{Permission: "users:delete", Grant: &deny, Reason: "denied"},
+			Checks:   []service.Decision{{Permission: "users:delete", Grant: &deny, Reason: "denied"}},
+			Roles: []service.RoleSource{
+				{Role: models.Role{Name: "admin"}, Source: service.RoleSourceAssigned},
+				{Role: models.Role{Name: "contractor"}, Source: service.RoleSourceInherited, Via: "admin"},
+			},
+			Grants: []models.PermissionGrant{
+				{Permission: "users:*", Effect: models.EffectAllow, RoleID: 1, RoleName: "admin"},
+				deny,
+			},
+		}, nil)
+
+	resp := postJSON(router, "/explain", gin.H{
+		"subject":  gin.H{"email": "alice@example.com"},
+		"action":   "users:delete",
+		"resource": gin.H{"type": "project", "id": "42"},
+	})
+
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Allowed bool   `json:"allowed"`
+		Reason  string `json:"reason"`
+		Roles   []struct {
+			Role   string `json:"role"`
+			Source string `json:"source"`
+			Via    string `json:"via"`
+		} `json:"roles"`
+		Grants []map[string]any `json:"grants"`
+		Denies []map[string]any `json:"denies"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+
+	assert.False(t, body.Allowed)
+	assert.Equal(t, "denied", body.Reason)
+	require.Len(t, body.Roles, 2)
+	assert.Equal(t, "admin", body.Roles[1].Via)
+	assert.Len(t, body.Grants, 1)
+	require.Len(t, body.Denies, 1)
+	assert.Equal(t, "contractor", body.Denies[0]["role"])
+	authz.AssertExpectations(t)
+}
+
+func TestExplainHandler_UnknownSubject(t *testing.T) {
+	authz := new(serviceMocks.AuthzServiceMock)
+	users := new(serviceMocks.UserServiceMock)
+	router := setupAuthzRouter(handler.NewAuthzHandler(authz, users))
+
+	users.On("FindUser", mock.Anything, uint(99)).Return(nil, appErr.ErrUserNotFound)
+
+	resp := postJSON(router, "/explain", gin.H{
+		"subject": gin.H{"id": 99},
+		"action":  "users:read",
+	})
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	authz.AssertNotCalled(t, "Explain")
+}
+
+func TestExplainHandler_MissingAction(t *testing.T) {
+	router := setupAuthzRouter(handler.NewAuthzHandler(new(serviceMocks.AuthzServiceMock), new(serviceMocks.UserServiceMock)))
+
+	resp := postJSON(router, "/explain", gin.H{"subject": gin.H{"id": 1}})
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), appErr.ErrInvalidInput.Error())
+}
+
+func TestCheckHandler_Success(t *testing.T) {
+	authz := new(serviceMocks.AuthzServiceMock)
+	alice := &models.User{Model: gorm.Model{ID: 7}, Email: "alice@example.com"}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/check", func(c *gin.Context) {
+		c.Set("user", alice)
+	}, handler.NewAuthzHandler(authz, new(serviceMocks.UserServiceMock)).Check)
+
+	authz.On("AuthorizeBatch", mock.Anything, alice, []service.Check{
+		{Permission: "invoices:read"},
+		{Permission: "projects:write", Resource: &service.Resource{Type: "project", ID: "42"}},
+	}).Return([]service.Decision{{Allowed: true}, {Allowed: false}}, nil)
+
+	resp := postJSON(router, "/check", gin.H{"checks": []gin.H{
+		{"action": "invoices:read"},
+		{"action": "projects:write", "resource": gin.H{"type": "project", "id": "42"}},
+	}})
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"results":[
+		{"action":"invoices:read","allowed":true},
+		{"action":"projects:write","resource":{"type":"project","id":"42"},"allowed":false}
+	]}`, resp.Body.String())
+}
+
+func TestCheckHandler_RequiresUser(t *testing.T) {
+	router := gin.New()
+	router.POST("/check", handler.NewAuthzHandler(new(serviceMocks.AuthzServiceMock), new(serviceMocks.UserServiceMock)).Check)
+
+	resp := postJSON(router, "/check", gin.H{"checks": []gin.H{{"action": "invoices:read"}}})
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestCheckHandler_EmptyBatch(t *testing.T) {
+	router := gin.New()
+	router.POST("/check", func(c *gin.Context) {
+		c.Set("user", &models.User{Model: gorm.Model{ID: 1}})
+	}, handler.NewAuthzHandler(new(serviceMocks.AuthzServiceMock), new(serviceMocks.UserServiceMock)).Check)
+
+	resp := postJSON(router, "/check", gin.H{"checks": []gin.H{}})
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
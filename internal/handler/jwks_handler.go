@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/gin-gonic/gin"
+)
+
+// KeySource publishes the public keys access tokens are verified with.
+// keyring.Keyring implements it.
+type KeySource interface {
+	JWKS() authz.JWKS
+}
+
+type JWKSHandler struct {
+	keys KeySource
+}
+
+// jwksMaxAge is how long verifiers may cache the key set. A new key must be
+// published at least this long before it signs tokens.
+const jwksMaxAge = "300"
+
+func NewJWKSHandler(keys KeySource) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS godoc
+// @Summary Public signing keys
+// @Description Returns the public keys that verify access tokens, as a JSON Web Key Set, so other services can validate tokens locally. HMAC keys are not listed.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} authz.JWKS "JSON Web Key Set"
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age="+jwksMaxAge)
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,42 @@
+package handler_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/handler"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticKeys authz.JWKS
+
+func (k staticKeys) JWKS() authz.JWKS { return authz.JWKS(k) }
+
+func TestJWKSHandler(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	jwk, err := authz.NewJWK("k1", "EdDSA", public)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/.well-known/jwks.json", handler.NewJWKSHandler(staticKeys{Keys: []authz.JWK{jwk}}).JWKS)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Cache-Control"), "max-age")
+
+	keys, err := authz.ParseJWKS(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, authz.PublicKeys{"k1": public}, keys)
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	service service.MFAService
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func NewMFAHandler(service service.MFAService) *MFAHandler {
+	return &MFAHandler{service: service}
+}
+
+// EnrollTOTP godoc
+// @Summary Start TOTP enrollment
+// @Description Generates a TOTP secret for the authenticated user and returns it with an otpauth URI to show as a QR code. MFA is enabled only once a code confirms the enrollment.
+// @Tags MFA
+// @Produce json
+// @Success 200 {object} map[string]string "Secret and otpauth URI"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "MFA already enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/mfa/totp [post]
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrUnauthorized.Error()})
+		return
+	}
+
+	setup, err := h.service.Enroll(c.Request.Context(), user)
+	if err != nil {
+		switch err {
+		case appErr.ErrMFAAlreadyEnabled:
+			c.JSON(http.StatusConflict, gin.H{"error": appErr.ErrMFAAlreadyEnabled.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      setup.Secret,
+		"otpauth_uri": setup.URI,
+	})
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP enrollment
+// @Description Enables MFA with a code from the authenticator app and returns one-time recovery codes. The recovery codes are shown only once.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body confirmTOTPRequest true "Code from the authenticator app"
+// @Success 200 {object} map[string]interface{} "MFA enabled, recovery codes"
+// @Failure 400 {object} map[string]string "Invalid code or no pending enrollment"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "MFA already enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrUnauthorized.Error()})
+		return
+	}
+
+	var req confirmTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+		return
+	}
+
+	codes, err := h.service.Confirm(c.Request.Context(), user, req.Code)
+	if err != nil {
+		switch err {
+		case appErr.ErrInvalidMFACode, appErr.ErrMFANotEnrolled:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case appErr.ErrMFAAlreadyEnabled:
+			c.JSON(http.StatusConflict, gin.H{"error": appErr.ErrMFAAlreadyEnabled.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "mfa enabled",
+		"recovery_codes": codes,
+	})
+}
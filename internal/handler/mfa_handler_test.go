@@ -0,0 +1,110 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/handler"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	serviceMocks "github.com/corradoisidoro/sentinel-rbac/internal/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func setupMFARouter(h *handler.MFAHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	withUser := func(next gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("user", &models.User{Model: gorm.Model{ID: 1}, Email: "test@example.com"})
+			next(c)
+		}
+	}
+	r.POST("/mfa/totp", withUser(h.EnrollTOTP))
+	r.POST("/mfa/totp/confirm", withUser(h.ConfirmTOTP))
+
+	return r
+}
+
+func TestEnrollTOTPHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"success", nil, http.StatusOK},
+		{"already enabled", appErr.ErrMFAAlreadyEnabled, http.StatusConflict},
+		{"internal", appErr.ErrInternal, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(serviceMocks.MFAServiceMock)
+			router := setupMFARouter(handler.NewMFAHandler(svc))
+
+			if tt.err == nil {
+				svc.On("Enroll", mock.Anything, mock.Anything).Return(&service.TOTPSetup{
+					Secret: "JBSWY3DPEHPK3PXP",
+					URI:    "otpauth://totp/sentinel:test@example.com?secret=JBSWY3DPEHPK3PXP",
+				}, nil)
+			} else {
+				svc.On("Enroll", mock.Anything, mock.Anything).Return(nil, tt.err)
+			}
+
+			req, _ := http.NewRequest(http.MethodPost, "/mfa/totp", nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.err == nil {
+				assert.Contains(t, resp.Body.String(), "JBSWY3DPEHPK3PXP")
+				assert.Contains(t, resp.Body.String(), "otpauth_uri")
+			}
+		})
+	}
+}
+
+func TestConfirmTOTPHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"success", nil, http.StatusOK},
+		{"wrong code", appErr.ErrInvalidMFACode, http.StatusBadRequest},
+		{"not enrolled", appErr.ErrMFANotEnrolled, http.StatusBadRequest},
+		{"already enabled", appErr.ErrMFAAlreadyEnabled, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(serviceMocks.MFAServiceMock)
+			router := setupMFARouter(handler.NewMFAHandler(svc))
+
+			if tt.err == nil {
+				svc.On("Confirm", mock.Anything, mock.Anything, "123456").Return([]string{"abcd-efgh"}, nil)
+			} else {
+				svc.On("Confirm", mock.Anything, mock.Anything, "123456").Return(nil, tt.err)
+			}
+
+			body, _ := json.Marshal(gin.H{"code": "123456"})
+			req, _ := http.NewRequest(http.MethodPost, "/mfa/totp/confirm", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.err == nil {
+				assert.Contains(t, resp.Body.String(), "abcd-efgh")
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// forgotPasswordMessage is the response to every valid forgot-password
+// request, whether or not the email belongs to an account.
+const forgotPasswordMessage = "if the email is registered, a password reset link has been sent"
+
+type PasswordResetHandler struct {
+	service service.PasswordResetService
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+func NewPasswordResetHandler(service service.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{service: service}
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Emails a single-use password reset link to the account with the given email. The response is the same whether or not the account exists.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body forgotPasswordRequest true "Account email"
+// @Success 202 {object} map[string]string "Reset link sent if the account exists"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/password/forgot [post]
+func (h *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+		return
+	}
+
+	if err := h.service.Forgot(c.Request.Context(), req.Email); err != nil {
+		switch err {
+		case appErr.ErrInvalidInput:
+			c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrInvalidInput.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": forgotPasswordMessage})
+}
+
+// ResetPassword godoc
+// @Summary Reset a forgotten password
+// @Description Sets a new password with the token from a password reset email and logs out every session of the account. Each token works once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body resetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string "Password reset"
+// @Failure 400 {object} map[string]string "Invalid request, or invalid or expired token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/password/reset [post]
+func (h *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+		return
+	}
+
+	if err := h.service.Reset(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		switch err {
+		case appErr.ErrInvalidInput, appErr.ErrInvalidResetToken:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	c.SetCookie("Authorization", "", -1, "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "password reset, please log in again",
+	})
+}
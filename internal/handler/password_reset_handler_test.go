@@ -0,0 +1,79 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/handler"
+	serviceMocks "github.com/corradoisidoro/sentinel-rbac/internal/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupPasswordResetRouter(h *handler.PasswordResetHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/password/forgot", h.ForgotPassword)
+	r.POST("/password/reset", h.ResetPassword)
+	return r
+}
+
+func TestForgotPasswordHandler_SameResponseForAnyEmail(t *testing.T) {
+	svc := new(serviceMocks.PasswordResetServiceMock)
+	router := setupPasswordResetRouter(handler.NewPasswordResetHandler(svc))
+
+	// The service reports success for unknown emails too.
+	svc.On("Forgot", mock.Anything, "alice@example.com").Return(nil)
+	svc.On("Forgot", mock.Anything, "nobody@example.com").Return(nil)
+
+	known := postJSON(router, "/password/forgot", gin.H{"email": "alice@example.com"})
+	unknown := postJSON(router, "/password/forgot", gin.H{"email": "nobody@example.com"})
+
+	assert.Equal(t, http.StatusAccepted, known.Code)
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+}
+
+func TestForgotPasswordHandler_InvalidEmail(t *testing.T) {
+	svc := new(serviceMocks.PasswordResetServiceMock)
+	router := setupPasswordResetRouter(handler.NewPasswordResetHandler(svc))
+
+	resp := postJSON(router, "/password/forgot", gin.H{"email": "not-an-email"})
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	svc.AssertNotCalled(t, "Forgot", mock.Anything, mock.Anything)
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"success", nil, http.StatusOK},
+		{"invalid token", appErr.ErrInvalidResetToken, http.StatusBadRequest},
+		{"internal", appErr.ErrInternal, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(serviceMocks.PasswordResetServiceMock)
+			router := setupPasswordResetRouter(handler.NewPasswordResetHandler(svc))
+
+			svc.On("Reset", mock.Anything, "reset-token", "new-password").Return(tt.err)
+
+			resp := postJSON(router, "/password/reset", gin.H{"token": "reset-token", "new_password": "new-password"})
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.err == nil {
+				cookies := resp.Result().Cookies()
+				assert.Len(t, cookies, 1)
+				assert.Equal(t, -1, cookies[0].MaxAge)
+			} else {
+				assert.Contains(t, resp.Body.String(), tt.err.Error())
+			}
+		})
+	}
+}
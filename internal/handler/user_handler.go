@@ -2,10 +2,14 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/middleware"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
 	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,6 +28,39 @@ type loginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type verifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type reauthenticateRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+type sessionResponse struct {
+	ID         uint      `json:"id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
 func NewUserHandler(service service.UserService) *UserHandler {
 	return &UserHandler{service: service}
 }
@@ -72,7 +109,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticates a user and returns a JWT token.
+// @Description Authenticates a user and returns a JWT token. Users with MFA enabled get an mfa_token instead, to exchange at /auth/mfa/verify together with a code.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -80,6 +117,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "Login successful"
 // @Failure 400 {object} map[string]string "Invalid request"
 // @Failure 401 {object} map[string]string "Invalid credentials"
+// @Failure 429 {object} map[string]string "Locked out after too many wrong MFA codes"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /auth/login [post]
 func (h *UserHandler) Login(c *gin.Context) {
@@ -90,25 +128,150 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	tokenString, err := h.service.Login(c.Request.Context(), req.Email, req.Password)
+	client := service.ClientInfo{
+		IPAddress: middleware.ClientIP(c),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	pair, challenge, err := h.service.Login(c.Request.Context(), req.Email, req.Password, client)
 	if err != nil {
 		switch err {
 		case appErr.ErrUserNotFound, appErr.ErrInvalidPassword:
 			c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrInvalidPassword.Error()})
+		case appErr.ErrMFALocked:
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
 		}
 		return
 	}
 
-	const thirtyDays = 30 * 24 * time.Hour
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("Authorization", tokenString, int(thirtyDays.Seconds()), "/", "", false, true)
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      appErr.ErrMFARequired.Error(),
+			"mfa_required": true,
+			"mfa_token":    challenge.Token,
+			"expires_in":   int(time.Until(challenge.ExpiresAt).Seconds()),
+		})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "logged in successfully",
-		"token":   tokenString,
-	})
+	writeTokenPair(c, "logged in successfully", pair)
+}
+
+// VerifyMFA godoc
+// @Summary Complete an MFA login
+// @Description Exchanges the mfa_token returned by login and a TOTP or recovery code for a JWT token and refresh token. A challenge ends after a few wrong codes, and too many wrong codes across challenges lock the user out for a while.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body verifyMFARequest true "MFA token and code"
+// @Success 200 {object} map[string]interface{} "Login successful"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Invalid code or expired MFA token"
+// @Failure 429 {object} map[string]string "Locked out after too many wrong codes"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/mfa/verify [post]
+func (h *UserHandler) VerifyMFA(c *gin.Context) {
+	var req verifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+		return
+	}
+
+	pair, err := h.service.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		switch err {
+		case appErr.ErrInvalidMFAToken, appErr.ErrInvalidMFACode:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case appErr.ErrMFALocked:
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	writeTokenPair(c, "logged in successfully", pair)
+}
+
+// Reauthenticate godoc
+// @Summary Reauthenticate the current session
+// @Description Confirms the authenticated user's identity again for routes requiring a recent login. Users with MFA enabled send a TOTP or recovery code, others their password. Returns a new access token with a fresh auth_time; the refresh token is unchanged.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body reauthenticateRequest true "Password or MFA code"
+// @Success 200 {object} map[string]interface{} "Session reauthenticated"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Wrong password or code, or MFA code required"
+// @Failure 429 {object} map[string]string "Locked out after too many wrong codes"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/reauthenticate [post]
+func (h *UserHandler) Reauthenticate(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrUnauthorized.Error()})
+		return
+	}
+
+	var req reauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+		return
+	}
+
+	pair, err := h.service.Reauthenticate(c.Request.Context(), user.ID, currentSessionID(c), req.Password, req.Code)
+	if err != nil {
+		switch err {
+		case appErr.ErrInvalidInput:
+			c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrInvalidInput.Error()})
+		case appErr.ErrInvalidPassword, appErr.ErrInvalidMFACode, appErr.ErrMFARequired, appErr.ErrSessionNotFound:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case appErr.ErrMFALocked:
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	writeTokenPair(c, "reauthenticated successfully", pair)
+}
+
+// Refresh godoc
+// @Summary Refresh the access token
+// @Description Exchanges a refresh token for a new access and refresh token pair. Each refresh token can be used once; replaying a used token revokes every token issued from the same login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body refreshRequest true "Refresh token"
+// @Success 200 {object} map[string]interface{} "Tokens refreshed"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Invalid, expired or reused refresh token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/refresh [post]
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+		return
+	}
+
+	pair, err := h.service.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		switch err {
+		case appErr.ErrInvalidInput:
+			c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrInvalidInput.Error()})
+		case appErr.ErrInvalidRefreshToken, appErr.ErrRefreshTokenReused:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	writeTokenPair(c, "token refreshed", pair)
 }
 
 // Profile godoc
@@ -126,18 +289,225 @@ func (h *UserHandler) Profile(c *gin.Context) {
 
 // Logout godoc
 // @Summary Logout user
-// @Description Clears the authentication cookie.
+// @Description Ends the session: revokes its access and refresh tokens and clears the authentication cookie. When a refresh token is sent, it and every token rotated from it are revoked too.
 // @Tags Auth
+// @Accept json
 // @Produce json
+// @Param request body logoutRequest false "Refresh token to revoke"
 // @Success 200 {object} map[string]string "Logged out"
+// @Failure 500 {object} map[string]string "Internal server error"
 // @Router /auth/logout [post]
 func (h *UserHandler) Logout(c *gin.Context) {
+	var req logoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+			return
+		}
+	}
+
+	access, _ := currentClaims(c)
+	if access != nil || req.RefreshToken != "" {
+		// An unknown refresh token is already unusable, so only storage errors fail.
+		err := h.service.Logout(c.Request.Context(), access, req.RefreshToken)
+		if err != nil && err != appErr.ErrInvalidRefreshToken {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+			return
+		}
+	}
+
 	c.SetCookie("Authorization", "", -1, "/", "", false, true)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "logged out successfully",
 	})
 }
 
+// ChangePassword godoc
+// @Summary Change password
+// @Description Changes the authenticated user's password and revokes all of their access and refresh tokens, logging out every session.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body changePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string "Password changed"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Wrong current password"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/password [put]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrUnauthorized.Error()})
+		return
+	}
+
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrFailedToParseRequestBody.Error()})
+		return
+	}
+
+	err := h.service.ChangePassword(c.Request.Context(), user.ID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		switch err {
+		case appErr.ErrInvalidInput:
+			c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrInvalidInput.Error()})
+		case appErr.ErrInvalidPassword:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrInvalidPassword.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	c.SetCookie("Authorization", "", -1, "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "password changed, please log in again",
+	})
+}
+
+// RevokeTokens godoc
+// @Summary Revoke a user's tokens
+// @Description Revokes every access and refresh token issued to the user, logging out all of their sessions. Admin only.
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string "Tokens revoked"
+// @Failure 400 {object} map[string]string "Invalid user ID"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/{id}/revoke-tokens [post]
+func (h *UserHandler) RevokeTokens(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrInvalidInput.Error()})
+		return
+	}
+
+	if err := h.service.RevokeTokens(c.Request.Context(), uint(id)); err != nil {
+		switch err {
+		case appErr.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": appErr.ErrUserNotFound.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "tokens revoked",
+	})
+}
+
+// Sessions godoc
+// @Summary List active sessions
+// @Description Lists the authenticated user's active sessions, one per login, most recently used first. The session of the current request is marked current.
+// @Tags Users
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Active sessions"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/sessions [get]
+func (h *UserHandler) Sessions(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrUnauthorized.Error()})
+		return
+	}
+
+	sessions, err := h.service.Sessions(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		return
+	}
+
+	current := currentSessionID(c)
+	resp := make([]sessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, sessionResponse{
+			ID:         s.ID,
+			IPAddress:  s.IPAddress,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    current != 0 && s.ID == current,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": resp})
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Logs out one of the authenticated user's sessions, revoking its access and refresh tokens.
+// @Tags Users
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]string "Session revoked"
+// @Failure 400 {object} map[string]string "Invalid session ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Session not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/sessions/{id} [delete]
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrUnauthorized.Error()})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": appErr.ErrInvalidInput.Error()})
+		return
+	}
+
+	if err := h.service.RevokeSession(c.Request.Context(), user.ID, uint(id)); err != nil {
+		switch err {
+		case appErr.ErrSessionNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": appErr.ErrSessionNotFound.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		}
+		return
+	}
+
+	if uint(id) == currentSessionID(c) {
+		c.SetCookie("Authorization", "", -1, "/", "", false, true)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "session revoked",
+	})
+}
+
+// RevokeOtherSessions godoc
+// @Summary Revoke all other sessions
+// @Description Logs out every session of the authenticated user except the one the request was made with.
+// @Tags Users
+// @Produce json
+// @Success 200 {object} map[string]string "Other sessions revoked"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/sessions [delete]
+func (h *UserHandler) RevokeOtherSessions(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": appErr.ErrUnauthorized.Error()})
+		return
+	}
+
+	if err := h.service.RevokeOtherSessions(c.Request.Context(), user.ID, currentSessionID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": appErr.ErrInternal.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "other sessions revoked",
+	})
+}
+
 // Admin godoc
 // @Summary Admin-only endpoint
 // @Description Accessible only to users with the admin role.
@@ -151,3 +521,44 @@ func (h *UserHandler) Admin(c *gin.Context) {
 		"message": "Welcome to the admin dashboard",
 	})
 }
+
+// writeTokenPair sets the access token cookie and returns both tokens.
+func writeTokenPair(c *gin.Context, message string, pair *service.TokenPair) {
+	expiresIn := int(time.Until(pair.AccessExpiresAt).Seconds())
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("Authorization", pair.AccessToken, expiresIn, "/", "", false, true)
+
+	body := gin.H{
+		"message":    message,
+		"token":      pair.AccessToken,
+		"expires_in": expiresIn,
+	}
+	if pair.RefreshToken != "" {
+		body["refresh_token"] = pair.RefreshToken
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+func currentUser(c *gin.Context) (*models.User, bool) {
+	usr, ok := c.Get("user")
+	user, _ := usr.(*models.User)
+	return user, ok && user != nil
+}
+
+// currentSessionID returns the session of the request's access token, or 0
+// for tokens issued before sessions were recorded.
+func currentSessionID(c *gin.Context) uint {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return 0
+	}
+	return claims.SessionID
+}
+
+// currentClaims returns the access token claims RequireAuth stored.
+func currentClaims(c *gin.Context) (*authz.Claims, bool) {
+	val, ok := c.Get("claims")
+	claims, _ := val.(*authz.Claims)
+	return claims, ok && claims != nil
+}
@@ -6,11 +6,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
 	"github.com/corradoisidoro/sentinel-rbac/internal/handler"
 	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
 	serviceMocks "github.com/corradoisidoro/sentinel-rbac/internal/service/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -22,6 +25,7 @@ func setupRouter(h *handler.UserHandler) *gin.Engine {
 	r := gin.New()
 	r.POST("/register", h.Register)
 	r.POST("/login", h.Login)
+	r.POST("/refresh", h.Refresh)
 	r.POST("/logout", h.Logout)
 
 	// Fake auth middleware for tests
@@ -37,6 +41,14 @@ func setupRouter(h *handler.UserHandler) *gin.Engine {
 	return r
 }
 
+func tokenPair(access, refresh string) *service.TokenPair {
+	return &service.TokenPair{
+		AccessToken:     access,
+		AccessExpiresAt: time.Now().Add(15 * time.Minute),
+		RefreshToken:    refresh,
+	}
+}
+
 func TestRegisterHandler_Success(t *testing.T) {
 	service := new(serviceMocks.UserServiceMock)
 	h := handler.NewUserHandler(service)
@@ -121,7 +133,8 @@ func TestLoginHandler_Success(t *testing.T) {
 		mock.Anything,
 		"test@example.com",
 		"password123",
-	).Return("jwt-token", nil)
+		mock.Anything,
+	).Return(tokenPair("jwt-token", "refresh-token"), nil, nil)
 
 	body, _ := json.Marshal(gin.H{
 		"email":    "test@example.com",
@@ -136,6 +149,7 @@ func TestLoginHandler_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, resp.Code)
 	assert.Contains(t, resp.Body.String(), "jwt-token")
+	assert.Contains(t, resp.Body.String(), "refresh-token")
 
 	// ✅ Cookie is set
 	cookies := resp.Result().Cookies()
@@ -154,7 +168,8 @@ func TestLoginHandler_UserNotFound(t *testing.T) {
 		mock.Anything,
 		"test@example.com",
 		"password123",
-	).Return("", appErr.ErrUserNotFound)
+		mock.Anything,
+	).Return(nil, nil, appErr.ErrUserNotFound)
 
 	body, _ := json.Marshal(gin.H{
 		"email":    "test@example.com",
@@ -180,7 +195,8 @@ func TestLoginHandler_InvalidPassword(t *testing.T) {
 		mock.Anything,
 		"test@example.com",
 		"wrongpassword",
-	).Return("", appErr.ErrInvalidPassword)
+		mock.Anything,
+	).Return(nil, nil, appErr.ErrInvalidPassword)
 
 	body, _ := json.Marshal(gin.H{
 		"email":    "test@example.com",
@@ -196,6 +212,140 @@ func TestLoginHandler_InvalidPassword(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, resp.Code)
 }
 
+func TestLoginHandler_MFAChallenge(t *testing.T) {
+	svc := new(serviceMocks.UserServiceMock)
+	h := handler.NewUserHandler(svc)
+	router := setupRouter(h)
+
+	svc.On(
+		"Login",
+		mock.Anything,
+		"test@example.com",
+		"password123",
+		mock.Anything,
+	).Return(nil, &service.MFAChallenge{Token: "mfa-token", ExpiresAt: time.Now().Add(5 * time.Minute)}, nil)
+
+	body, _ := json.Marshal(gin.H{
+		"email":    "test@example.com",
+		"password": "password123",
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var got map[string]any
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+	assert.Equal(t, true, got["mfa_required"])
+	assert.Equal(t, "mfa-token", got["mfa_token"])
+	assert.NotContains(t, got, "token")
+	assert.Empty(t, resp.Result().Cookies(), "no session before the code is verified")
+}
+
+func TestVerifyMFAHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"success", nil, http.StatusOK},
+		{"wrong code", appErr.ErrInvalidMFACode, http.StatusUnauthorized},
+		{"expired token", appErr.ErrInvalidMFAToken, http.StatusUnauthorized},
+		{"locked out", appErr.ErrMFALocked, http.StatusTooManyRequests},
+		{"internal", appErr.ErrInternal, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(serviceMocks.UserServiceMock)
+			h := handler.NewUserHandler(svc)
+
+			if tt.err == nil {
+				svc.On("VerifyMFA", mock.Anything, "mfa-token", "123456").Return(tokenPair("jwt-token", "refresh-token"), nil)
+			} else {
+				svc.On("VerifyMFA", mock.Anything, "mfa-token", "123456").Return(nil, tt.err)
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.POST("/mfa/verify", h.VerifyMFA)
+
+			body, _ := json.Marshal(gin.H{"mfa_token": "mfa-token", "code": "123456"})
+			req, _ := http.NewRequest(http.MethodPost, "/mfa/verify", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.err == nil {
+				assert.Contains(t, resp.Body.String(), "jwt-token")
+				cookies := resp.Result().Cookies()
+				assert.Len(t, cookies, 1)
+				assert.Equal(t, "jwt-token", cookies[0].Value)
+			}
+		})
+	}
+}
+
+func TestReauthenticateHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"success", nil, http.StatusOK},
+		{"wrong password", appErr.ErrInvalidPassword, http.StatusUnauthorized},
+		{"code required", appErr.ErrMFARequired, http.StatusUnauthorized},
+		{"wrong code", appErr.ErrInvalidMFACode, http.StatusUnauthorized},
+		{"locked out", appErr.ErrMFALocked, http.StatusTooManyRequests},
+		{"nothing sent", appErr.ErrInvalidInput, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(serviceMocks.UserServiceMock)
+			h := handler.NewUserHandler(svc)
+
+			if tt.err == nil {
+				svc.On("Reauthenticate", mock.Anything, uint(1), uint(3), "password123", "").
+					Return(&service.TokenPair{AccessToken: "jwt-token", AccessExpiresAt: time.Now().Add(15 * time.Minute)}, nil)
+			} else {
+				svc.On("Reauthenticate", mock.Anything, uint(1), uint(3), "password123", "").Return(nil, tt.err)
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.POST("/reauthenticate", func(c *gin.Context) {
+				c.Set("user", &models.User{Model: gorm.Model{ID: 1}})
+				c.Set("claims", &authz.Claims{Subject: 1, SessionID: 3})
+				h.Reauthenticate(c)
+			})
+
+			body, _ := json.Marshal(gin.H{"password": "password123"})
+			req, _ := http.NewRequest(http.MethodPost, "/reauthenticate", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.err == nil {
+				var got map[string]any
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				assert.Equal(t, "jwt-token", got["token"])
+				assert.NotContains(t, got, "refresh_token")
+
+				cookies := resp.Result().Cookies()
+				assert.Len(t, cookies, 1)
+				assert.Equal(t, "jwt-token", cookies[0].Value)
+			}
+		})
+	}
+}
+
 func TestLoginHandler_InvalidJSON(t *testing.T) {
 	service := new(serviceMocks.UserServiceMock)
 	h := handler.NewUserHandler(service)
@@ -244,3 +394,270 @@ func TestLogoutHandler_Success(t *testing.T) {
 	assert.Equal(t, "", cookies[0].Value)
 	assert.Equal(t, -1, cookies[0].MaxAge)
 }
+
+func TestRefreshHandler_Success(t *testing.T) {
+	service := new(serviceMocks.UserServiceMock)
+	h := handler.NewUserHandler(service)
+	router := setupRouter(h)
+
+	service.On("Refresh", mock.Anything, "old-refresh").Return(tokenPair("new-access", "new-refresh"), nil)
+
+	body, _ := json.Marshal(gin.H{"refresh_token": "old-refresh"})
+	req, _ := http.NewRequest(http.MethodPost, "/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "new-refresh")
+
+	cookies := resp.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "new-access", cookies[0].Value)
+}
+
+func TestRefreshHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"invalid", appErr.ErrInvalidRefreshToken, http.StatusUnauthorized},
+		{"reused", appErr.ErrRefreshTokenReused, http.StatusUnauthorized},
+		{"internal", appErr.ErrInternal, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(serviceMocks.UserServiceMock)
+			h := handler.NewUserHandler(service)
+			router := setupRouter(h)
+
+			service.On("Refresh", mock.Anything, "token").Return(nil, tt.err)
+
+			body, _ := json.Marshal(gin.H{"refresh_token": "token"})
+			req, _ := http.NewRequest(http.MethodPost, "/refresh", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			assert.Empty(t, resp.Result().Cookies())
+		})
+	}
+}
+
+func TestLogoutHandler_RevokesRefreshToken(t *testing.T) {
+	service := new(serviceMocks.UserServiceMock)
+	h := handler.NewUserHandler(service)
+	router := setupRouter(h)
+
+	service.On("Logout", mock.Anything, (*authz.Claims)(nil), "refresh-token").Return(nil)
+
+	body, _ := json.Marshal(gin.H{"refresh_token": "refresh-token"})
+	req, _ := http.NewRequest(http.MethodPost, "/logout", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	service.AssertExpectations(t)
+}
+
+func TestLogoutHandler_RevokesAccessToken(t *testing.T) {
+	service := new(serviceMocks.UserServiceMock)
+	h := handler.NewUserHandler(service)
+
+	claims := &authz.Claims{ID: "jti", Subject: 1}
+	service.On("Logout", mock.Anything, claims, "").Return(nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/logout", func(c *gin.Context) {
+		c.Set("claims", claims)
+		h.Logout(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/logout", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	service.AssertExpectations(t)
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"success", nil, http.StatusOK},
+		{"wrong current password", appErr.ErrInvalidPassword, http.StatusUnauthorized},
+		{"internal", appErr.ErrInternal, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(serviceMocks.UserServiceMock)
+			h := handler.NewUserHandler(service)
+
+			service.On("ChangePassword", mock.Anything, uint(1), "password123", "new-password").Return(tt.err)
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.PUT("/password", func(c *gin.Context) {
+				c.Set("user", &models.User{Model: gorm.Model{ID: 1}})
+				h.ChangePassword(c)
+			})
+
+			body, _ := json.Marshal(gin.H{"current_password": "password123", "new_password": "new-password"})
+			req, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.err == nil {
+				cookies := resp.Result().Cookies()
+				assert.Len(t, cookies, 1)
+				assert.Equal(t, -1, cookies[0].MaxAge)
+			}
+		})
+	}
+}
+
+func TestRevokeTokensHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		err    error
+		status int
+	}{
+		{"success", "7", nil, http.StatusOK},
+		{"unknown user", "7", appErr.ErrUserNotFound, http.StatusNotFound},
+		{"invalid id", "abc", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(serviceMocks.UserServiceMock)
+			h := handler.NewUserHandler(service)
+
+			service.On("RevokeTokens", mock.Anything, uint(7)).Return(tt.err)
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.POST("/users/:id/revoke-tokens", h.RevokeTokens)
+
+			req, _ := http.NewRequest(http.MethodPost, "/users/"+tt.id+"/revoke-tokens", nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+		})
+	}
+}
+
+func TestSessionsHandler(t *testing.T) {
+	service := new(serviceMocks.UserServiceMock)
+	h := handler.NewUserHandler(service)
+
+	service.On("Sessions", mock.Anything, uint(1)).Return([]models.Session{
+		{Model: gorm.Model{ID: 3}, UserID: 1, IPAddress: "203.0.113.7", UserAgent: "curl/8.0"},
+		{Model: gorm.Model{ID: 4}, UserID: 1},
+	}, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/sessions", func(c *gin.Context) {
+		c.Set("user", &models.User{Model: gorm.Model{ID: 1}})
+		c.Set("claims", &authz.Claims{Subject: 1, SessionID: 3})
+		h.Sessions(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/sessions", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Sessions []struct {
+			ID        uint   `json:"id"`
+			IPAddress string `json:"ip_address"`
+			UserAgent string `json:"user_agent"`
+			Current   bool   `json:"current"`
+		} `json:"sessions"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Len(t, body.Sessions, 2)
+	assert.Equal(t, "203.0.113.7", body.Sessions[0].IPAddress)
+	assert.Equal(t, "curl/8.0", body.Sessions[0].UserAgent)
+	assert.True(t, body.Sessions[0].Current)
+	assert.False(t, body.Sessions[1].Current)
+}
+
+func TestRevokeSessionHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		err     error
+		status  int
+		cleared bool
+	}{
+		{"other session", "4", nil, http.StatusOK, false},
+		{"current session", "3", nil, http.StatusOK, true},
+		{"unknown session", "4", appErr.ErrSessionNotFound, http.StatusNotFound, false},
+		{"invalid id", "abc", nil, http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(serviceMocks.UserServiceMock)
+			h := handler.NewUserHandler(service)
+
+			service.On("RevokeSession", mock.Anything, uint(1), mock.Anything).Return(tt.err)
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.DELETE("/sessions/:id", func(c *gin.Context) {
+				c.Set("user", &models.User{Model: gorm.Model{ID: 1}})
+				c.Set("claims", &authz.Claims{Subject: 1, SessionID: 3})
+				h.RevokeSession(c)
+			})
+
+			req, _ := http.NewRequest(http.MethodDelete, "/sessions/"+tt.id, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			assert.Equal(t, tt.cleared, len(resp.Result().Cookies()) == 1, "cookie cleared")
+		})
+	}
+}
+
+func TestRevokeOtherSessionsHandler(t *testing.T) {
+	service := new(serviceMocks.UserServiceMock)
+	h := handler.NewUserHandler(service)
+
+	service.On("RevokeOtherSessions", mock.Anything, uint(1), uint(3)).Return(nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.DELETE("/sessions", func(c *gin.Context) {
+		c.Set("user", &models.User{Model: gorm.Model{ID: 1}})
+		c.Set("claims", &authz.Claims{Subject: 1, SessionID: 3})
+		h.RevokeOtherSessions(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodDelete, "/sessions", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	service.AssertExpectations(t)
+}
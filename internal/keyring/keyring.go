@@ -0,0 +1,282 @@
+// Package keyring holds the keys access tokens are signed and verified with.
+// One key signs new tokens; every key verifies the tokens carrying its ID in
+// the "kid" header until it is retired, so keys can be rotated without
+// invalidating tokens that are still in flight. Keys are HMAC secrets or
+// RSA, ECDSA and Ed25519 private keys read from PEM files; the public halves
+// of the latter are published as a JWKS.
+package keyring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/golang-jwt/jwt"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultKeyID is the ID of the key built from JWT_SECRET. It also verifies
+// tokens without a "kid" header, issued before keys were rotated.
+const DefaultKeyID = "default"
+
+var (
+	ErrUnknownKey = errors.New("keyring: unknown key")
+	ErrRetiredKey = errors.New("keyring: key retired")
+)
+
+// Key is a signing key: either an HMAC Secret or a PrivateKeyFile in PEM
+// (PKCS #8, PKCS #1 or SEC 1). Algorithm defaults to HS256 for secrets and
+// to RS256, ES256/ES384/ES512 or EdDSA by private key type. A key with
+// RetireAt set stops verifying tokens at that time; it should be no earlier
+// than the expiry of the last token it signed.
+type Key struct {
+	ID             string     `json:"id" yaml:"id"`
+	Algorithm      string     `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	Secret         string     `json:"secret,omitempty" yaml:"secret,omitempty"`
+	PrivateKeyFile string     `json:"private_key_file,omitempty" yaml:"private_key_file,omitempty"`
+	RetireAt       *time.Time `json:"retire_at,omitempty" yaml:"retire_at,omitempty"`
+}
+
+func (k Key) retired(now time.Time) bool {
+	return k.RetireAt != nil && !now.Before(*k.RetireAt)
+}
+
+// entry is a Key with its signing method and parsed key material.
+type entry struct {
+	Key
+	method jwt.SigningMethod
+	sign   any
+	verify any
+}
+
+type Keyring struct {
+	active *entry
+	keys   map[string]*entry
+	order  []string
+	now    func() time.Time
+}
+
+// New returns a keyring signing with the key whose ID is active. Private
+// key files are read relative to the working directory.
+func New(active string, keys ...Key) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*entry, len(keys)), now: time.Now}
+
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("keyring: key without id")
+		}
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("keyring: key %q declared twice", key.ID)
+		}
+
+		e, err := parseKey(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[key.ID] = e
+		k.order = append(k.order, key.ID)
+	}
+
+	activeKey, ok := k.keys[active]
+	if !ok {
+		return nil, fmt.Errorf("keyring: active key %q not found", active)
+	}
+	if activeKey.RetireAt != nil {
+		return nil, fmt.Errorf("keyring: active key %q is scheduled for retirement", active)
+	}
+	k.active = activeKey
+
+	return k, nil
+}
+
+// Load builds the keyring from secret (JWT_SECRET) and the keys file, either
+// of which may be empty. secret becomes the DefaultKeyID key, which signs
+// unless the file names another active key. Private key files are read
+// relative to the keys file.
+func Load(file, secret string) (*Keyring, error) {
+	var keys []Key
+	active := ""
+
+	if secret != "" {
+		keys = append(keys, Key{ID: DefaultKeyID, Secret: secret})
+		active = DefaultKeyID
+	}
+
+	if file != "" {
+		f, err := ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range f.Keys {
+			if key.PrivateKeyFile != "" && !filepath.IsAbs(key.PrivateKeyFile) {
+				key.PrivateKeyFile = filepath.Join(filepath.Dir(file), key.PrivateKeyFile)
+			}
+			keys = append(keys, key)
+		}
+		if f.Active != "" {
+			active = f.Active
+		}
+	}
+
+	return New(active, keys...)
+}
+
+// ActiveKeyID returns the ID of the key new tokens are signed with.
+func (k *Keyring) ActiveKeyID() string {
+	return k.active.ID
+}
+
+// Sign signs claims with the active key, naming it in the "kid" header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.sign)
+}
+
+// VerificationKey implements authz.KeySet. Tokens without a "kid" are
+// verified with the DefaultKeyID key.
+func (k *Keyring) VerificationKey(kid string) (any, error) {
+	if kid == "" {
+		kid = DefaultKeyID
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if key.retired(k.now()) {
+		return nil, ErrRetiredKey
+	}
+	return key.verify, nil
+}
+
+// JWKS returns the public keys of the asymmetric keys that still verify
+// tokens. HMAC secrets are never published.
+func (k *Keyring) JWKS() authz.JWKS {
+	set := authz.JWKS{Keys: []authz.JWK{}}
+	now := k.now()
+
+	for _, id := range k.order {
+		key := k.keys[id]
+		if key.Secret != "" || key.retired(now) {
+			continue
+		}
+		// parseKey only accepts keys NewJWK can encode.
+		jwk, _ := authz.NewJWK(key.ID, key.method.Alg(), key.verify)
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// File is the JWT_KEYS_FILE format:
+//
+//	active: 2026-10
+//	keys:
+//	  - id: 2026-10
+//	    secret: ...
+//	  - id: 2026-04
+//	    secret: ...
+//	    retire_at: 2026-10-16T12:15:00Z
+type File struct {
+	Active string `json:"active,omitempty" yaml:"active,omitempty"`
+	Keys   []Key  `json:"keys" yaml:"keys"`
+}
+
+// ReadFile reads a keys file in YAML or JSON.
+func ReadFile(file string) (*File, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("keys file %s: %w", file, err)
+	}
+	return &f, nil
+}
+
+// WriteFile writes f to file, as JSON for a .json file and YAML otherwise.
+// The file holds secrets, so it is only readable by its owner.
+func WriteFile(file string, f *File) error {
+	var (
+		data []byte
+		err  error
+	)
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		data, err = json.MarshalIndent(f, "", "  ")
+	} else {
+		data, err = yaml.Marshal(f)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o600)
+}
+
+// Find returns the key with the given ID.
+func (f *File) Find(id string) (*Key, bool) {
+	for i := range f.Keys {
+		if f.Keys[i].ID == id {
+			return &f.Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// Add adds a key. DefaultKeyID is reserved for JWT_SECRET.
+func (f *File) Add(key Key) error {
+	if key.ID == "" || (key.Secret == "") == (key.PrivateKeyFile == "") {
+		return errors.New("keyring: key needs an id and either a secret or a private key file")
+	}
+	if key.ID == DefaultKeyID {
+		return fmt.Errorf("keyring: key id %q is reserved for JWT_SECRET", DefaultKeyID)
+	}
+	if _, ok := f.Find(key.ID); ok {
+		return fmt.Errorf("keyring: key %q already exists", key.ID)
+	}
+	f.Keys = append(f.Keys, key)
+	return nil
+}
+
+// Activate makes id the signing key. The previously active key keeps
+// verifying tokens until retireAt.
+func (f *File) Activate(id string, retireAt time.Time) error {
+	key, ok := f.Find(id)
+	if !ok {
+		return fmt.Errorf("keyring: key %q not found", id)
+	}
+	if id == f.Active {
+		return nil
+	}
+
+	if prev, ok := f.Find(f.Active); ok {
+		prev.RetireAt = &retireAt
+	}
+	key.RetireAt = nil
+	f.Active = id
+	return nil
+}
+
+// Prune removes keys retired by now and returns their IDs.
+func (f *File) Prune(now time.Time) []string {
+	var removed []string
+	kept := f.Keys[:0]
+
+	for _, key := range f.Keys {
+		if key.retired(now) {
+			removed = append(removed, key.ID)
+			continue
+		}
+		kept = append(kept, key)
+	}
+
+	f.Keys = kept
+	return removed
+}
@@ -0,0 +1,205 @@
+package keyring_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/keyring"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": float64(1), "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestNew_Validation(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	_, err := keyring.New("a", keyring.Key{ID: "a", Secret: "x"}, keyring.Key{ID: "a", Secret: "y"})
+	assert.Error(t, err, "duplicate id")
+
+	_, err = keyring.New("a", keyring.Key{ID: "a"})
+	assert.Error(t, err, "empty secret")
+
+	_, err = keyring.New("b", keyring.Key{ID: "a", Secret: "x"})
+	assert.Error(t, err, "unknown active key")
+
+	_, err = keyring.New("a", keyring.Key{ID: "a", Secret: "x", RetireAt: &past})
+	assert.Error(t, err, "retired active key")
+}
+
+func TestKeyring_SignAndVerify(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	keys, err := keyring.New("new",
+		keyring.Key{ID: "new", Secret: "new-secret"},
+		keyring.Key{ID: "old", Secret: "old-secret", RetireAt: &future},
+		keyring.Key{ID: "gone", Secret: "gone-secret", RetireAt: &past},
+	)
+	require.NoError(t, err)
+
+	tokenString, err := keys.Sign(claims())
+	require.NoError(t, err)
+
+	validator := authz.NewValidator(keys)
+
+	c, err := validator.Validate(tokenString)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), c.Subject)
+
+	sign := func(kid, secret string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+		token.Header["kid"] = kid
+		s, err := token.SignedString([]byte(secret))
+		require.NoError(t, err)
+		return s
+	}
+
+	_, err = validator.Validate(sign("old", "old-secret"))
+	assert.NoError(t, err, "retiring key still verifies")
+
+	_, err = validator.Validate(sign("gone", "gone-secret"))
+	assert.Error(t, err, "retired key no longer verifies")
+
+	_, err = validator.Validate(sign("unknown", "new-secret"))
+	assert.Error(t, err)
+}
+
+func TestLoad_SecretVerifiesTokensWithoutKeyID(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, keyring.WriteFile(file, &keyring.File{
+		Active: "k1",
+		Keys:   []keyring.Key{{ID: "k1", Secret: "k1-secret"}},
+	}))
+
+	keys, err := keyring.Load(file, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "k1", keys.ActiveKeyID())
+
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("legacy"))
+	require.NoError(t, err)
+
+	_, err = authz.NewValidator(keys).Validate(legacy)
+	assert.NoError(t, err)
+
+	keys, err = keyring.Load("", "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, keyring.DefaultKeyID, keys.ActiveKeyID())
+
+	_, err = keyring.Load("", "")
+	assert.Error(t, err)
+}
+
+func TestFile_Rotation(t *testing.T) {
+	for _, name := range []string{"keys.yaml", "keys.json"} {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), name)
+			now := time.Now()
+
+			f := &keyring.File{}
+			require.NoError(t, f.Add(keyring.Key{ID: "k1", Secret: "s1"}))
+			require.NoError(t, f.Activate("k1", now))
+			require.NoError(t, f.Add(keyring.Key{ID: "k2", Secret: "s2"}))
+			assert.Error(t, f.Add(keyring.Key{ID: "k2", Secret: "s3"}))
+			assert.Error(t, f.Add(keyring.Key{ID: keyring.DefaultKeyID, Secret: "s3"}))
+
+			require.NoError(t, f.Activate("k2", now.Add(15*time.Minute)))
+			require.NoError(t, keyring.WriteFile(file, f))
+
+			f, err := keyring.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, "k2", f.Active)
+
+			k1, ok := f.Find("k1")
+			require.True(t, ok)
+			require.NotNil(t, k1.RetireAt)
+			assert.WithinDuration(t, now.Add(15*time.Minute), *k1.RetireAt, time.Second)
+
+			assert.Empty(t, f.Prune(now))
+			assert.Equal(t, []string{"k1"}, f.Prune(now.Add(time.Hour)))
+			assert.Len(t, f.Keys, 1)
+		})
+	}
+}
+
+func writePrivateKey(t *testing.T, dir, name, alg string) string {
+	t.Helper()
+
+	data, err := keyring.GeneratePrivateKey(alg)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+	return file
+}
+
+func TestKeyring_Asymmetric(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, alg := range []string{"RS256", "ES256", "ES384", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			keys, err := keyring.New("k1", keyring.Key{ID: "k1", PrivateKeyFile: writePrivateKey(t, dir, alg+".pem", alg)})
+			require.NoError(t, err)
+
+			tokenString, err := keys.Sign(claims())
+			require.NoError(t, err)
+
+			token, _ := jwt.Parse(tokenString, nil)
+			assert.Equal(t, alg, token.Header["alg"])
+
+			// Verifiers only need the published JWKS.
+			set := keys.JWKS()
+			require.Len(t, set.Keys, 1)
+			assert.Equal(t, alg, set.Keys[0].Alg)
+
+			public, err := set.PublicKeys()
+			require.NoError(t, err)
+
+			c, err := authz.NewValidator(public).Validate(tokenString)
+			require.NoError(t, err)
+			assert.Equal(t, uint(1), c.Subject)
+		})
+	}
+}
+
+func TestKeyring_AsymmetricValidation(t *testing.T) {
+	dir := t.TempDir()
+	file := writePrivateKey(t, dir, "ec.pem", "ES256")
+
+	_, err := keyring.New("k1", keyring.Key{ID: "k1", PrivateKeyFile: file, Algorithm: "RS256"})
+	assert.Error(t, err, "algorithm does not fit the key")
+
+	_, err = keyring.New("k1", keyring.Key{ID: "k1", PrivateKeyFile: file, Algorithm: "ES384"})
+	assert.Error(t, err, "curve does not fit the algorithm")
+
+	_, err = keyring.New("k1", keyring.Key{ID: "k1", Secret: "x", PrivateKeyFile: file})
+	assert.Error(t, err, "secret and private key")
+
+	_, err = keyring.New("k1", keyring.Key{ID: "k1", Secret: "x", Algorithm: "ES256"})
+	assert.Error(t, err, "asymmetric algorithm with a secret")
+}
+
+func TestLoad_PrivateKeyRelativeToKeysFile(t *testing.T) {
+	dir := t.TempDir()
+	writePrivateKey(t, dir, "k1.pem", "EdDSA")
+
+	file := filepath.Join(dir, "keys.yaml")
+	require.NoError(t, keyring.WriteFile(file, &keyring.File{
+		Active: "k1",
+		Keys:   []keyring.Key{{ID: "k1", PrivateKeyFile: "k1.pem"}},
+	}))
+
+	keys, err := keyring.Load(file, "legacy")
+	require.NoError(t, err)
+
+	// JWT_SECRET still verifies, but is not published.
+	set := keys.JWKS()
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, "k1", set.Keys[0].Kid)
+}
@@ -0,0 +1,152 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// parseKey resolves the signing method of key and loads its key material.
+func parseKey(key Key) (*entry, error) {
+	if (key.Secret == "") == (key.PrivateKeyFile == "") {
+		return nil, fmt.Errorf("keyring: key %q needs either a secret or a private key file", key.ID)
+	}
+
+	if key.Secret != "" {
+		alg := key.Algorithm
+		if alg == "" {
+			alg = jwt.SigningMethodHS256.Alg()
+		}
+		method, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC)
+		if !ok {
+			return nil, fmt.Errorf("keyring: key %q: algorithm %q needs a private key file", key.ID, alg)
+		}
+		secret := []byte(key.Secret)
+		return &entry{Key: key, method: method, sign: secret, verify: secret}, nil
+	}
+
+	private, err := readPrivateKey(key.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: key %q: %w", key.ID, err)
+	}
+
+	alg := key.Algorithm
+	if alg == "" {
+		alg = defaultAlgorithm(private)
+	}
+
+	method := jwt.GetSigningMethod(alg)
+	public := private.(crypto.Signer).Public()
+	if method == nil || !methodFits(method, private) {
+		return nil, fmt.Errorf("keyring: key %q: algorithm %q does not fit a %T", key.ID, alg, private)
+	}
+
+	return &entry{Key: key, method: method, sign: private, verify: public}, nil
+}
+
+// readPrivateKey reads an RSA, ECDSA or Ed25519 private key from a PEM file.
+func readPrivateKey(file string) (crypto.PrivateKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", file)
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		switch key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+			return key, nil
+		}
+		return nil, fmt.Errorf("%s: unsupported key type %T", file, key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%s: not a PKCS #8, PKCS #1 or SEC 1 private key", file)
+}
+
+func defaultAlgorithm(key crypto.PrivateKey) string {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return "RS256"
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P384():
+			return "ES384"
+		case elliptic.P521():
+			return "ES512"
+		}
+		return "ES256"
+	case ed25519.PrivateKey:
+		return "EdDSA"
+	}
+	return ""
+}
+
+func methodFits(method jwt.SigningMethod, key crypto.PrivateKey) bool {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+	case *ecdsa.PrivateKey:
+		m, ok := method.(*jwt.SigningMethodECDSA)
+		return ok && m.CurveBits == k.Curve.Params().BitSize
+	case ed25519.PrivateKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
+
+// GeneratePrivateKey returns a new private key for alg (RS256, ES256, ES384,
+// ES512 or EdDSA) as PKCS #8 PEM.
+func GeneratePrivateKey(alg string) ([]byte, error) {
+	var (
+		key crypto.PrivateKey
+		err error
+	)
+
+	switch alg {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		key, err = rsa.GenerateKey(rand.Reader, 3072)
+	case "ES256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ES512":
+		key, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, errors.New("keyring: unsupported algorithm " + alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
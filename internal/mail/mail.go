@@ -0,0 +1,130 @@
+// Package mail delivers account emails such as password reset links.
+//
+// Mailer is implemented by SMTPMailer for production and by Outbox and
+// FileOutbox, which keep messages in memory or write them to a directory,
+// for tests and local development.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidMessage is returned for messages without a recipient or with
+// line breaks in a header field.
+var ErrInvalidMessage = errors.New("mail: invalid message")
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Discard is a Mailer dropping every message, for deployments without
+// email.
+var Discard Mailer = discard{}
+
+type discard struct{}
+
+func (discard) Send(context.Context, Message) error { return nil }
+
+func (m Message) validate() error {
+	if m.To == "" || strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return ErrInvalidMessage
+	}
+	return nil
+}
+
+// format renders the message as RFC 5322 text with CRLF line endings.
+func (m Message) format(from string, date time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// Outbox is a Mailer keeping sent messages in memory.
+type Outbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewOutbox returns an empty Outbox.
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+func (o *Outbox) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
+
+// FileOutbox is a Mailer writing each message to its own .eml file in a
+// directory, to be opened with a mail client during development.
+type FileOutbox struct {
+	dir  string
+	from string
+	now  func() time.Time
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewFileOutbox returns a FileOutbox writing to dir, creating it if needed.
+func NewFileOutbox(dir, from string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("mail: create outbox: %w", err)
+	}
+	return &FileOutbox{dir: dir, from: from, now: time.Now}, nil
+}
+
+func (o *FileOutbox) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	o.seq++
+	name := fmt.Sprintf("%s-%04d.eml", now.UTC().Format("20060102T150405"), o.seq)
+
+	if err := os.WriteFile(filepath.Join(o.dir, name), msg.format(o.from, now), 0o600); err != nil {
+		return fmt.Errorf("mail: write outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package mail_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/mail"
+	"github.com/stretchr/testify/require"
+)
+
+var message = mail.Message{
+	To:      "alice@example.com",
+	Subject: "Reset your password",
+	Body:    "Open this link:\nhttps://example.com/reset?token=abc",
+}
+
+func TestOutbox(t *testing.T) {
+	outbox := mail.NewOutbox()
+
+	require.NoError(t, outbox.Send(context.Background(), message))
+	require.Equal(t, []mail.Message{message}, outbox.Messages())
+}
+
+func TestFileOutbox(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	outbox, err := mail.NewFileOutbox(dir, "sentinel@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, outbox.Send(context.Background(), message))
+	require.NoError(t, outbox.Send(context.Background(), message))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "From: sentinel@example.com\r\n")
+	require.Contains(t, string(raw), "To: alice@example.com\r\n")
+	require.Contains(t, string(raw), "\r\n\r\nOpen this link:\r\nhttps://example.com/reset?token=abc\r\n")
+}
+
+func TestInvalidMessage(t *testing.T) {
+	tests := []mail.Message{
+		{Subject: "no recipient"},
+		{To: "alice@example.com", Subject: "injected\r\nBcc: mallory@example.com"},
+		{To: "alice@example.com\nBcc: mallory@example.com"},
+	}
+
+	for _, msg := range tests {
+		require.ErrorIs(t, mail.NewOutbox().Send(context.Background(), msg), mail.ErrInvalidMessage)
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	received := make(chan []string, 1)
+	go serveSMTP(lis, received)
+
+	port := lis.Addr().(*net.TCPAddr).Port
+	mailer := mail.NewSMTPMailer(mail.SMTPConfig{Host: "127.0.0.1", Port: port, From: "sentinel@example.com"})
+
+	require.NoError(t, mailer.Send(context.Background(), message))
+
+	session := strings.Join(<-received, "\n")
+	require.Contains(t, session, "MAIL FROM:<sentinel@example.com>")
+	require.Contains(t, session, "RCPT TO:<alice@example.com>")
+	require.Contains(t, session, "Subject: Reset your password")
+	require.Contains(t, session, "https://example.com/reset?token=abc")
+}
+
+func TestSMTPMailer_Unreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+
+	mailer := mail.NewSMTPMailer(mail.SMTPConfig{Host: "127.0.0.1", Port: port, From: "sentinel@example.com"})
+	require.Error(t, mailer.Send(context.Background(), message))
+}
+
+// serveSMTP accepts one connection, speaks just enough SMTP to accept a
+// message and sends every line the client wrote to received.
+func serveSMTP(lis net.Listener, received chan<- []string) {
+	conn, err := lis.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(code int, text string) {
+		conn.Write([]byte(strconv.Itoa(code) + " " + text + "\r\n"))
+	}
+
+	var lines []string
+	reply(220, "localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			received <- lines
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		lines = append(lines, line)
+
+		switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+		case "EHLO", "HELO", "MAIL", "RCPT":
+			reply(250, "OK")
+		case "DATA":
+			reply(354, "go ahead")
+			for {
+				data, err := r.ReadString('\n')
+				if err != nil {
+					received <- lines
+					return
+				}
+				data = strings.TrimRight(data, "\r\n")
+				if data == "." {
+					break
+				}
+				lines = append(lines, data)
+			}
+			reply(250, "queued")
+		case "QUIT":
+			reply(221, "bye")
+			received <- lines
+			return
+		default:
+			reply(502, "not implemented")
+		}
+	}
+}
@@ -1,94 +1,347 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
 	"net/http"
 
 	"github.com/corradoisidoro/sentinel-rbac/internal/models"
 
 	"github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/policy"
 	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 )
 
 type AuthMiddleware struct {
-	secret []byte
-	repo   repository.UserRepository
+	validator   authz.TokenValidator
+	keys        authz.KeySet
+	validation  []authz.ValidatorOption
+	transports  []TokenTransport
+	revocations service.RevocationStore
+	activity    service.SessionActivity
+	repo        repository.UserRepository
+	authz       service.AuthzService
+	policies    *policy.Engine
+	attributes  repository.UserAttributeRepository
 }
 
-func NewAuthMiddleware(secret []byte, repo repository.UserRepository) *AuthMiddleware {
-	return &AuthMiddleware{
-		secret: secret,
-		repo:   repo,
+// AuthOption configures optional AuthMiddleware dependencies.
+type AuthOption func(*AuthMiddleware)
+
+// WithAuthz enables permission checks backed by the given AuthzService.
+func WithAuthz(authz service.AuthzService) AuthOption {
+	return func(m *AuthMiddleware) {
+		m.authz = authz
+	}
+}
+
+// WithRevocation rejects access tokens revoked in store.
+func WithRevocation(store service.RevocationStore) AuthOption {
+	return func(m *AuthMiddleware) {
+		m.revocations = store
+	}
+}
+
+// WithSessionActivity records the use of each authenticated access token's
+// session in activity.
+func WithSessionActivity(activity service.SessionActivity) AuthOption {
+	return func(m *AuthMiddleware) {
+		m.activity = activity
+	}
+}
+
+// WithKeys verifies access tokens against keys, selected by their "kid"
+// header, instead of the single secret given to NewAuthMiddleware.
+func WithKeys(keys authz.KeySet) AuthOption {
+	return func(m *AuthMiddleware) {
+		m.keys = keys
+	}
+}
+
+// WithTokenValidation applies stricter checks to the registered claims of
+// access tokens, such as their issuer, audience and clock-skew leeway.
+func WithTokenValidation(opts ...authz.ValidatorOption) AuthOption {
+	return func(m *AuthMiddleware) {
+		m.validation = append(m.validation, opts...)
+	}
+}
+
+func NewAuthMiddleware(secret []byte, repo repository.UserRepository, opts ...AuthOption) *AuthMiddleware {
+	m := &AuthMiddleware{
+		transports: DefaultTransports,
+		repo:       repo,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.keys != nil {
+		m.validator = authz.NewValidator(m.keys, m.validation...)
+	} else {
+		m.validator = authz.NewHMACValidator(secret, m.validation...)
 	}
+	return m
 }
 
+// RequireAuth authenticates the request with the token from the configured
+// transports (see WithTransports) and stores the user and claims.
 func (m *AuthMiddleware) RequireAuth(c *gin.Context) {
-	tokenString, err := c.Cookie("Authorization")
+	m.requireAuth(c, m.transports)
+}
+
+// RequireAuthFrom is RequireAuth accepting the token only from transports,
+// in order of precedence, e.g. bearer headers alone on a group serving
+// other services.
+func (m *AuthMiddleware) RequireAuthFrom(transports ...TokenTransport) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.requireAuth(c, transports)
+	}
+}
+
+func (m *AuthMiddleware) requireAuth(c *gin.Context, transports []TokenTransport) {
+	tokenString := extractToken(c.Request.Header, transports)
+	if tokenString == "" {
+		unauthorized(c, transports, errors.ErrNoTokenProvided)
+		return
+	}
+
+	user, claims, err := m.authenticate(c.Request.Context(), tokenString)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrNoTokenProvided.Error()})
+		unauthorized(c, transports, err)
 		return
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	c.Set("user", user)
+	c.Set("claims", claims)
+	c.Next()
+}
+
+// Authenticate validates tokenString and loads the user it was issued to.
+// Errors are the sentinel messages RequireAuth reports with a 401. It lets
+// other transports, such as the gRPC ext_authz server, share token checks.
+func (m *AuthMiddleware) Authenticate(ctx context.Context, tokenString string) (*models.User, error) {
+	user, _, err := m.authenticate(ctx, tokenString)
+	return user, err
+}
+
+func (m *AuthMiddleware) authenticate(ctx context.Context, tokenString string) (*models.User, *authz.Claims, error) {
+	claims, err := m.validator.Validate(tokenString)
+	switch err {
+	case nil:
+	case authz.ErrInvalidClaims:
+		return nil, nil, errors.ErrInvalidClaims
+	case authz.ErrInvalidSubject:
+		return nil, nil, errors.ErrInvalidTokenSubject
+	case authz.ErrInvalidIssuer:
+		return nil, nil, errors.ErrInvalidTokenIssuer
+	case authz.ErrInvalidAudience:
+		return nil, nil, errors.ErrInvalidTokenAudience
+	default:
+		return nil, nil, errors.ErrTokenExpiredOrInvalid
+	}
+
+	if m.revocations != nil && m.revocations.IsRevoked(claims.Subject, claims.SessionID, claims.ID, claims.IssuedAt) {
+		return nil, nil, errors.ErrTokenRevoked
+	}
 
-		}
-		return m.secret, nil
-	})
+	user, err := m.repo.FindById(ctx, claims.Subject)
+	if err != nil || user == nil {
+		return nil, nil, errors.ErrUserNotFound
+	}
 
-	if err != nil || !token.Valid {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrTokenExpiredOrInvalid.Error()})
-		return
+	if m.activity != nil && claims.SessionID != 0 {
+		m.activity.Touch(ctx, claims.SessionID)
+	}
+
+	return user, claims, nil
+}
+
+// AuthorizeRole allows the request if the authenticated user holds one of the
+// given roles, either directly or by inheriting it through the role hierarchy.
+// Users holding a role that requires MFA are refused unless the access token
+// shows a second factor in its "amr" claim; they can step up through
+// /api/auth/reauthenticate.
+func (m *AuthMiddleware) AuthorizeRole(roles ...string) gin.HandlerFunc {
+	roleSet := make(map[string]bool)
+	for _, r := range roles {
+		roleSet[r] = true
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		// jwt-go/v5 handles 'exp' validation automatically in Parse, but we need to extract 'sub'
-		sub, ok := claims["sub"].(float64)
+	return func(c *gin.Context) {
+		usr, ok := currentUser(c)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrInvalidTokenSubject.Error()})
+			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		user, err := m.repo.FindById(c.Request.Context(), uint(sub))
+		allowed, err := m.hasAnyRole(c, usr, roleSet)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrUserNotFound.Error()})
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": errors.ErrInternal.Error()})
+			return
+		}
+
+		if !allowed {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		required, err := m.requiresMFA(c, usr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": errors.ErrInternal.Error()})
+			return
+		}
+
+		if required {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errors.ErrMFARequired.Error()})
 			return
 		}
 
-		c.Set("user", user)
 		c.Next()
-	} else {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrInvalidClaims.Error()})
 	}
 }
 
-func (m *AuthMiddleware) AuthorizeRole(roles ...string) gin.HandlerFunc {
-	roleSet := make(map[string]bool)
-	for _, r := range roles {
-		roleSet[r] = true
+// requiresMFA reports whether the user holds a role that requires MFA while
+// the session authenticated without a second factor. Roles are only known
+// with an AuthzService.
+func (m *AuthMiddleware) requiresMFA(c *gin.Context, usr *models.User) (bool, error) {
+	if m.authz == nil || sessionHasMFA(c) {
+		return false, nil
+	}
+
+	effective, err := m.authz.EffectiveRoles(c.Request.Context(), usr)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range effective {
+		if r.MFARequired {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// sessionHasMFA reports whether the claims stored by RequireAuth record a
+// second factor. Enabling MFA does not upgrade sessions started without it.
+func sessionHasMFA(c *gin.Context) bool {
+	val, _ := c.Get("claims")
+	claims, ok := val.(*authz.Claims)
+	if !ok || claims == nil {
+		return false
+	}
+
+	for _, method := range claims.AuthMethods {
+		if method == service.AuthMethodOTP || method == service.AuthMethodMFA {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyRole reports whether the user holds one of the roles in roleSet,
+// resolving inherited roles when an AuthzService is configured.
+func (m *AuthMiddleware) hasAnyRole(c *gin.Context, usr *models.User, roleSet map[string]bool) (bool, error) {
+	if roleSet[usr.Role] {
+		return true, nil
+	}
+
+	if m.authz == nil {
+		return false, nil
+	}
+
+	effective, err := m.authz.EffectiveRoles(c.Request.Context(), usr)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range effective {
+		if roleSet[r.Name] {
+			return true, nil
+		}
 	}
 
+	return false, nil
+}
+
+// RequirePermission allows the request only if the authenticated user holds
+// every listed permission. It is an alias of RequireAllPermissions.
+func (m *AuthMiddleware) RequirePermission(perms ...string) gin.HandlerFunc {
+	return m.RequireAllPermissions(perms...)
+}
+
+// RequireAllPermissions allows the request only if the authenticated user
+// holds every listed permission.
+func (m *AuthMiddleware) RequireAllPermissions(perms ...string) gin.HandlerFunc {
+	return m.requirePermissions(service.RequireAll, perms)
+}
+
+// RequireAnyPermission allows the request if the authenticated user holds at
+// least one of the listed permissions.
+func (m *AuthMiddleware) RequireAnyPermission(perms ...string) gin.HandlerFunc {
+	return m.requirePermissions(service.RequireAny, perms)
+}
+
+// RequirePermissionOn allows the request only if the authenticated user holds
+// every listed permission on the resource whose ID is taken from the route
+// parameter param (e.g. "projectID" for "/projects/:projectID"). Roles bound
+// to the user on that resource are considered in addition to global roles.
+func (m *AuthMiddleware) RequirePermissionOn(resourceType, param string, perms ...string) gin.HandlerFunc {
+	return m.authorizeWith(func(c *gin.Context, usr *models.User) (service.Decision, error) {
+		resource := service.Resource{Type: resourceType, ID: c.Param(param)}
+		return m.authz.AuthorizeOn(c.Request.Context(), usr, resource, service.RequireAll, perms...)
+	})
+}
+
+func (m *AuthMiddleware) requirePermissions(mode service.PermissionMode, perms []string) gin.HandlerFunc {
+	return m.authorizeWith(func(c *gin.Context, usr *models.User) (service.Decision, error) {
+		return m.authz.Authorize(c.Request.Context(), usr, mode, perms...)
+	})
+}
+
+func (m *AuthMiddleware) authorizeWith(decide func(c *gin.Context, usr *models.User) (service.Decision, error)) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		val, exists := c.Get("user")
-		if !exists {
-			c.AbortWithStatus(http.StatusUnauthorized)
+		usr, ok := currentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrUnauthorized.Error()})
 			return
 		}
 
-		usr, ok := val.(*models.User)
-		if !ok {
-			c.AbortWithStatus(http.StatusUnauthorized)
+		if m.authz == nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": errors.ErrInternal.Error()})
 			return
 		}
 
-		if !roleSet[usr.Role] {
-			c.AbortWithStatus(http.StatusForbidden)
+		decision, err := decide(c, usr)
+		if err == errors.ErrInvalidInput {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errors.ErrInvalidInput.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": errors.ErrInternal.Error()})
+			return
+		}
+
+		if !decision.Allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":  errors.ErrPermissionDenied.Error(),
+				"reason": decision.Reason,
+			})
 			return
 		}
 
 		c.Next()
 	}
 }
+
+func currentUser(c *gin.Context) (*models.User, bool) {
+	val, exists := c.Get("user")
+	if !exists {
+		return nil, false
+	}
+
+	usr, ok := val.(*models.User)
+	return usr, ok && usr != nil
+}
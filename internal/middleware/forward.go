@@ -0,0 +1,306 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/policy"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// Identity headers set on a successful forward-auth response, for the proxy
+// to copy onto the upstream request.
+const (
+	HeaderAuthUserID    = "X-Auth-User-Id"
+	HeaderAuthUserEmail = "X-Auth-User-Email"
+	HeaderAuthUserRoles = "X-Auth-User-Roles"
+)
+
+// ForwardRule maps requests to a proxied service onto the permissions they
+// require. Path is matched segment by segment: "*" matches exactly one
+// segment and a trailing "**" matches the rest of the path, including
+// nothing. Empty Host and Methods match anything. A rule without
+// permissions only requires authentication, unless it is Public.
+type ForwardRule struct {
+	Host        string   `json:"host,omitempty" yaml:"host,omitempty"`
+	Methods     []string `json:"methods,omitempty" yaml:"methods,omitempty"`
+	Path        string   `json:"path" yaml:"path"`
+	Permissions []string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	Any         bool     `json:"any,omitempty" yaml:"any,omitempty"`
+	Public      bool     `json:"public,omitempty" yaml:"public,omitempty"`
+}
+
+// LoadForwardRules reads forward-auth rules from a YAML or JSON file with a
+// top-level "routes" list:
+//
+//	routes:
+//	  - path: /invoices/**
+//	    methods: [GET]
+//	    permissions: [invoices:read]
+func LoadForwardRules(file string) ([]ForwardRule, error) {
+	format, err := policy.FormatFromPath(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Routes []ForwardRule `json:"routes" yaml:"routes"`
+	}
+
+	if format == policy.FormatJSON {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("forward rules %s: %w", file, err)
+	}
+
+	for i, r := range doc.Routes {
+		if !strings.HasPrefix(r.Path, "/") {
+			return nil, fmt.Errorf("forward rules %s: route %d: path must start with /", file, i)
+		}
+	}
+
+	return doc.Routes, nil
+}
+
+// ForwardRequest is a request to a proxied service that a proxy asks us to
+// authorize.
+type ForwardRequest struct {
+	Method string
+	Host   string
+	URI    string
+	Header http.Header
+}
+
+// ForwardResult is the outcome of CheckForward. Status is the HTTP status to
+// answer the proxy with; on 200 User and Roles identify the caller (both are
+// empty for public rules), otherwise Err holds the sentinel error and Reason
+// explains a 403.
+type ForwardResult struct {
+	Status int
+	Err    error
+	Reason string
+	User   *models.User
+	Roles  []string
+}
+
+// CheckForward authenticates req with the token from the configured
+// transports and authorizes it against the first rule matching its method, host
+// and path. Requests no rule matches are denied.
+func (m *AuthMiddleware) CheckForward(ctx context.Context, rules []ForwardRule, req ForwardRequest) ForwardResult {
+	target, err := url.ParseRequestURI(req.URI)
+	if req.Method == "" || req.URI == "" || err != nil {
+		return ForwardResult{Status: http.StatusBadRequest, Err: errors.ErrInvalidInput}
+	}
+	reqPath := path.Clean(target.Path)
+
+	rule, ok := matchForwardRule(rules, req.Method, req.Host, reqPath)
+	if !ok {
+		return ForwardResult{
+			Status: http.StatusForbidden,
+			Err:    errors.ErrPermissionDenied,
+			Reason: fmt.Sprintf("no forward rule for %s %s", req.Method, reqPath),
+		}
+	}
+
+	if rule.Public {
+		return ForwardResult{Status: http.StatusOK}
+	}
+
+	tokenString := extractToken(req.Header, m.transports)
+	if tokenString == "" {
+		return ForwardResult{Status: http.StatusUnauthorized, Err: errors.ErrNoTokenProvided}
+	}
+
+	usr, err := m.Authenticate(ctx, tokenString)
+	if err != nil {
+		return ForwardResult{Status: http.StatusUnauthorized, Err: err}
+	}
+
+	if len(rule.Permissions) > 0 {
+		if m.authz == nil {
+			return ForwardResult{Status: http.StatusInternalServerError, Err: errors.ErrInternal}
+		}
+
+		mode := service.RequireAll
+		if rule.Any {
+			mode = service.RequireAny
+		}
+
+		decision, err := m.authz.Authorize(ctx, usr, mode, rule.Permissions...)
+		if err != nil {
+			return ForwardResult{Status: http.StatusInternalServerError, Err: errors.ErrInternal}
+		}
+
+		if !decision.Allowed {
+			return ForwardResult{
+				Status: http.StatusForbidden,
+				Err:    errors.ErrPermissionDenied,
+				Reason: decision.Reason,
+			}
+		}
+	}
+
+	roles, err := m.roleNames(ctx, usr)
+	if err != nil {
+		return ForwardResult{Status: http.StatusInternalServerError, Err: errors.ErrInternal}
+	}
+
+	return ForwardResult{Status: http.StatusOK, User: usr, Roles: roles}
+}
+
+// IdentityHeaders returns the X-Auth-User-* headers describing an allowed
+// caller, or nil for public rules.
+func (r ForwardResult) IdentityHeaders() map[string]string {
+	if r.User == nil {
+		return nil
+	}
+	return map[string]string{
+		HeaderAuthUserID:    strconv.FormatUint(uint64(r.User.ID), 10),
+		HeaderAuthUserEmail: r.User.Email,
+		HeaderAuthUserRoles: strings.Join(r.Roles, ","),
+	}
+}
+
+// ForwardAuth serves forward-auth subrequests from reverse proxies such as
+// nginx (auth_request) and Traefik (ForwardAuth). The original request is
+// read from X-Forwarded-Method/-Uri/-Host or X-Original-Method/-URI/-Host
+// and checked with CheckForward. The response is 200 with identity headers,
+// 400 when the method or URI header is missing, 401 or 403. The method of the
+// subrequest itself is never used: proxies send it as GET whatever the
+// original request was.
+//
+// The endpoint trusts those headers, so it must only be reachable by the
+// proxy.
+func (m *AuthMiddleware) ForwardAuth(rules []ForwardRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := m.CheckForward(c.Request.Context(), rules, ForwardRequest{
+			Method: firstHeader(c, "X-Forwarded-Method", "X-Original-Method"),
+			Host:   firstHeader(c, "X-Forwarded-Host", "X-Original-Host"),
+			URI:    firstHeader(c, "X-Forwarded-Uri", "X-Original-URI"),
+			Header: c.Request.Header,
+		})
+
+		if result.Status != http.StatusOK {
+			body := gin.H{"error": result.Err.Error()}
+			if result.Reason != "" {
+				body["reason"] = result.Reason
+			}
+			c.AbortWithStatusJSON(result.Status, body)
+			return
+		}
+
+		for k, v := range result.IdentityHeaders() {
+			c.Header(k, v)
+		}
+		c.Status(http.StatusOK)
+	}
+}
+
+// roleNames returns the user's effective role names, or just the legacy
+// User.Role when no AuthzService is configured.
+func (m *AuthMiddleware) roleNames(ctx context.Context, usr *models.User) ([]string, error) {
+	if m.authz == nil {
+		if usr.Role == "" {
+			return nil, nil
+		}
+		return []string{usr.Role}, nil
+	}
+
+	roles, err := m.authz.EffectiveRoles(ctx, usr)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(roles)+1)
+	if usr.Role != "" {
+		names = append(names, usr.Role)
+	}
+	for _, r := range roles {
+		if r.Name != usr.Role {
+			names = append(names, r.Name)
+		}
+	}
+	return names, nil
+}
+
+func matchForwardRule(rules []ForwardRule, method, host, reqPath string) (ForwardRule, bool) {
+	for _, r := range rules {
+		if r.Host != "" && !matchHost(r.Host, host) {
+			continue
+		}
+		if len(r.Methods) > 0 && !containsFold(r.Methods, method) {
+			continue
+		}
+		if matchForwardPath(r.Path, reqPath) {
+			return r, true
+		}
+	}
+	return ForwardRule{}, false
+}
+
+func matchForwardPath(pattern, reqPath string) bool {
+	patternSegs := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegs := strings.Split(strings.Trim(reqPath, "/"), "/")
+
+	for i, seg := range patternSegs {
+		if seg == "**" && i == len(patternSegs)-1 {
+			return true
+		}
+		if i >= len(pathSegs) {
+			return false
+		}
+		if seg != "*" && seg != pathSegs[i] {
+			return false
+		}
+	}
+
+	return len(patternSegs) == len(pathSegs)
+}
+
+func matchHost(pattern, host string) bool {
+	if strings.EqualFold(pattern, host) {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return strings.EqualFold(pattern, h)
+	}
+	return false
+}
+
+func containsFold(values []string, v string) bool {
+	for _, s := range values {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstHeader(c *gin.Context, names ...string) string {
+	for _, name := range names {
+		if v := c.GetHeader(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/middleware"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	serviceMocks "github.com/corradoisidoro/sentinel-rbac/internal/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var forwardRules = []middleware.ForwardRule{
+	{Path: "/health", Public: true},
+	{Path: "/invoices/**", Methods: []string{"GET"}, Permissions: []string{"invoices:read"}},
+	{Path: "/profile"},
+}
+
+func newForwardRouter(t *testing.T) (*gin.Engine, *serviceMocks.AuthzServiceMock, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	user := &models.User{Model: gorm.Model{ID: 1}, Email: "alice@example.com", Role: "user"}
+
+	repo := new(mocks.UserRepositoryMock)
+	repo.On("FindById", mock.Anything, uint(1)).Return(user, nil)
+
+	authz := new(serviceMocks.AuthzServiceMock)
+	authz.On("EffectiveRoles", mock.Anything, user).
+		Return([]models.Role{{Name: "user"}, {Name: "billing"}}, nil)
+
+	m := middleware.NewAuthMiddleware([]byte("secret"), repo, middleware.WithAuthz(authz))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": float64(1),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	tokenString, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.GET("/forward", m.ForwardAuth(forwardRules))
+
+	return r, authz, tokenString
+}
+
+func forwardRequest(method, uri, bearer string) *http.Request {
+	req := httptest.NewRequest("GET", "/forward", nil)
+	req.Header.Set("X-Forwarded-Method", method)
+	req.Header.Set("X-Forwarded-Uri", uri)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	return req
+}
+
+func TestForwardAuth_AllowedSetsIdentityHeaders(t *testing.T) {
+	r, authz, token := newForwardRouter(t)
+	authz.On("Authorize", mock.Anything, mock.Anything, service.RequireAll, []string{"invoices:read"}).
+		Return(service.Decision{Allowed: true}, nil)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, forwardRequest("GET", "/invoices/42?expand=lines", token))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "1", w.Header().Get(middleware.HeaderAuthUserID))
+	require.Equal(t, "alice@example.com", w.Header().Get(middleware.HeaderAuthUserEmail))
+	require.Equal(t, "user,billing", w.Header().Get(middleware.HeaderAuthUserRoles))
+}
+
+func TestForwardAuth_CookieAndOriginalHeaders(t *testing.T) {
+	r, _, token := newForwardRouter(t)
+
+	req := httptest.NewRequest("GET", "/forward", nil)
+	req.Header.Set("X-Original-Method", "POST")
+	req.Header.Set("X-Original-URI", "/profile")
+	req.AddCookie(&http.Cookie{Name: "Authorization", Value: token})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestForwardAuth_Denied(t *testing.T) {
+	r, authz, token := newForwardRouter(t)
+	authz.On("Authorize", mock.Anything, mock.Anything, service.RequireAll, []string{"invoices:read"}).
+		Return(service.Decision{Reason: `no grant for "invoices:read"`}, nil)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, forwardRequest("GET", "/invoices", token))
+
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), "no grant")
+}
+
+func TestForwardAuth_Unauthenticated(t *testing.T) {
+	r, _, _ := newForwardRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, forwardRequest("GET", "/profile", ""))
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestForwardAuth_PublicRoute(t *testing.T) {
+	r, _, _ := newForwardRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, forwardRequest("GET", "/health", ""))
+
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestForwardAuth_NoMatchingRule(t *testing.T) {
+	r, _, token := newForwardRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		uri    string
+	}{
+		{"unknown path", "GET", "/admin"},
+		{"method not listed", "DELETE", "/invoices/42"},
+		{"dot segments are cleaned", "GET", "/health/../admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, forwardRequest(tt.method, tt.uri, token))
+
+			require.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
+func TestForwardAuth_MissingURI(t *testing.T) {
+	r, _, token := newForwardRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, forwardRequest("GET", "", token))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestForwardAuth_MissingMethod(t *testing.T) {
+	r, _, token := newForwardRouter(t)
+
+	// The subrequest is a GET; without the forwarded method a write must not
+	// be checked against the GET rule.
+	req := forwardRequest("GET", "/invoices/42", token)
+	req.Header.Del("X-Forwarded-Method")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLoadForwardRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - path: /invoices/**
+    methods: [GET]
+    permissions: [invoices:read]
+`), 0o600))
+
+	rules, err := middleware.LoadForwardRules(path)
+
+	require.NoError(t, err)
+	require.Equal(t, []middleware.ForwardRule{
+		{Path: "/invoices/**", Methods: []string{"GET"}, Permissions: []string{"invoices:read"}},
+	}, rules)
+}
@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/middleware"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newOwnershipRouter(user *models.User, resolver middleware.OwnerResolver, roles ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	repo := new(mocks.UserRepositoryMock)
+	m := middleware.NewAuthMiddleware([]byte("secret"), repo)
+
+	r := gin.New()
+	r.DELETE("/documents/:id",
+		func(c *gin.Context) {
+			if user != nil {
+				c.Set("user", user)
+			}
+		},
+		m.AuthorizeOwnerOr(resolver, roles...),
+		func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		},
+	)
+
+	return r
+}
+
+func documentOwner(owners map[string]uint) middleware.OwnerResolver {
+	return middleware.OwnerResolverFunc(func(c *gin.Context) (uint, error) {
+		owner, ok := owners[c.Param("id")]
+		if !ok {
+			return 0, appErr.ErrResourceNotFound
+		}
+		return owner, nil
+	})
+}
+
+func TestAuthorizeOwnerOr(t *testing.T) {
+	owners := map[string]uint{"10": 1, "11": 2}
+
+	tests := []struct {
+		name string
+		user *models.User
+		path string
+		want int
+	}{
+		{"owner allowed", &models.User{Model: gorm.Model{ID: 1}, Role: "user"}, "/documents/10", http.StatusOK},
+		{"admin allowed", &models.User{Model: gorm.Model{ID: 3}, Role: "admin"}, "/documents/10", http.StatusOK},
+		{"other user forbidden", &models.User{Model: gorm.Model{ID: 2}, Role: "user"}, "/documents/10", http.StatusForbidden},
+		{"missing resource forbidden", &models.User{Model: gorm.Model{ID: 1}, Role: "user"}, "/documents/99", http.StatusForbidden},
+		{"admin skips lookup", &models.User{Model: gorm.Model{ID: 3}, Role: "admin"}, "/documents/99", http.StatusOK},
+		{"no user in context", nil, "/documents/10", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newOwnershipRouter(tt.user, documentOwner(owners), "admin")
+
+			req := httptest.NewRequest("DELETE", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestAuthorizeOwnerOr_UserParamOwner(t *testing.T) {
+	user := &models.User{Model: gorm.Model{ID: 1}, Role: "user"}
+
+	r := newOwnershipRouter(user, middleware.UserParamOwner("id"), "admin")
+
+	req := httptest.NewRequest("DELETE", "/documents/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("DELETE", "/documents/abc", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
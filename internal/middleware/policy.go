@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/policy"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// ResourceLoader loads the attributes of the resource targeted by a request,
+// e.g. {"type": "expense", "id": "7", "amount": 420, "department": "sales"}.
+// Implementations return errors.ErrResourceNotFound when it does not exist.
+type ResourceLoader interface {
+	LoadResource(c *gin.Context) (policy.Attributes, error)
+}
+
+// ResourceLoaderFunc adapts a plain function to the ResourceLoader interface.
+type ResourceLoaderFunc func(c *gin.Context) (policy.Attributes, error)
+
+func (f ResourceLoaderFunc) LoadResource(c *gin.Context) (policy.Attributes, error) {
+	return f(c)
+}
+
+// WithPolicies enables RequirePolicy, evaluating requests with engine and
+// reading custom subject attributes from attributes.
+func WithPolicies(engine *policy.Engine, attributes repository.UserAttributeRepository) AuthOption {
+	return func(m *AuthMiddleware) {
+		m.policies = engine
+		m.attributes = attributes
+	}
+}
+
+// RequirePolicy allows the request only if the ABAC policies allow action on
+// the resource returned by loader. The loaded attributes are stored under the
+// "resource" context key for the handler to reuse. Missing resources are
+// refused with 403, like denied ones, so IDs can't be probed.
+func (m *AuthMiddleware) RequirePolicy(action string, loader ResourceLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		usr, ok := currentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrUnauthorized.Error()})
+			return
+		}
+
+		if m.policies == nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": errors.ErrInternal.Error()})
+			return
+		}
+
+		resource, err := loader.LoadResource(c)
+		switch err {
+		case nil:
+		case errors.ErrInvalidInput:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errors.ErrInvalidInput.Error()})
+			return
+		case errors.ErrResourceNotFound:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errors.ErrPermissionDenied.Error()})
+			return
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": errors.ErrInternal.Error()})
+			return
+		}
+
+		subject, err := m.subjectAttributes(c, usr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": errors.ErrInternal.Error()})
+			return
+		}
+
+		decision := m.policies.Evaluate(policy.Request{
+			Subject:     subject,
+			Action:      action,
+			Resource:    resource,
+			Environment: EnvironmentAttributes(c),
+		})
+
+		if !decision.Allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":  errors.ErrPermissionDenied.Error(),
+				"reason": decision.Reason,
+			})
+			return
+		}
+
+		c.Set("resource", resource)
+		c.Next()
+	}
+}
+
+// subjectAttributes collects the user's built-in and custom attributes, plus
+// "roles" with the names of their effective roles when an AuthzService is
+// configured.
+func (m *AuthMiddleware) subjectAttributes(c *gin.Context, usr *models.User) (policy.Attributes, error) {
+	var custom []models.UserAttribute
+	if m.attributes != nil {
+		var err error
+		custom, err = m.attributes.FindByUserId(c.Request.Context(), usr.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attrs := policy.SubjectAttributes(usr, custom)
+
+	if m.authz != nil {
+		roles, err := m.authz.EffectiveRoles(c.Request.Context(), usr)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(roles))
+		for _, r := range roles {
+			names = append(names, r.Name)
+		}
+		attrs["roles"] = names
+	}
+
+	return attrs, nil
+}
+
+// EnvironmentAttributes describes the request context available to policies
+// as "environment.<key>": time (UTC), hour, weekday, client_ip, method, path.
+func EnvironmentAttributes(c *gin.Context) policy.Attributes {
+	now := time.Now().UTC()
+
+	return policy.Attributes{
+		"time":      now,
+		"hour":      now.Hour(),
+		"weekday":   now.Weekday().String(),
+		"client_ip": ClientIP(c),
+		"method":    c.Request.Method,
+		"path":      c.Request.URL.Path,
+	}
+}
@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/middleware"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/policy"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestRequirePolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine, err := policy.NewEngine(policy.Policy{
+		Name:      "managers-approve-expenses",
+		Effect:    "allow",
+		Actions:   []string{"expenses:approve"},
+		Resources: []string{"expense"},
+		Conditions: []policy.Condition{
+			{Attribute: "subject.role", Operator: "eq", Value: "manager"},
+			{Attribute: "resource.amount", Operator: "lt", Value: 10000},
+			{Attribute: "resource.department", Operator: "eq", Ref: "subject.department"},
+		},
+	})
+	require.NoError(t, err)
+
+	expenses := map[string]policy.Attributes{
+		"1": {"type": "expense", "id": "1", "amount": 500, "department": "sales"},
+		"2": {"type": "expense", "id": "2", "amount": 50000, "department": "sales"},
+	}
+	loader := middleware.ResourceLoaderFunc(func(c *gin.Context) (policy.Attributes, error) {
+		attrs, ok := expenses[c.Param("id")]
+		if !ok {
+			return nil, appErr.ErrResourceNotFound
+		}
+		return attrs, nil
+	})
+
+	user := &models.User{Model: gorm.Model{ID: 1}, Role: "manager"}
+
+	repo := new(mocks.UserRepositoryMock)
+	attributes := new(mocks.UserAttributeRepositoryMock)
+	attributes.On("FindByUserId", mock.Anything, uint(1)).
+		Return([]models.UserAttribute{{UserID: 1, Key: "department", Value: "sales"}}, nil)
+
+	m := middleware.NewAuthMiddleware([]byte("secret"), repo, middleware.WithPolicies(engine, attributes))
+
+	r := gin.New()
+	r.POST("/expenses/:id/approve",
+		func(c *gin.Context) {
+			c.Set("user", user)
+		},
+		m.RequirePolicy("expenses:approve", loader),
+		func(c *gin.Context) {
+			require.Equal(t, expenses[c.Param("id")], c.MustGet("resource"))
+			c.Status(http.StatusOK)
+		},
+	)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/expenses/1/approve", http.StatusOK},
+		{"/expenses/2/approve", http.StatusForbidden},
+		{"/expenses/3/approve", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, tt.want, w.Code, tt.path)
+	}
+}
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"math"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -19,6 +21,12 @@ type RateLimiterConfig struct {
 
 	RouteRPS   int
 	RouteBurst int
+
+	// ProxyPaths are routes (gin full paths) called by a trusted reverse
+	// proxy on behalf of many clients, such as forward auth. Every request
+	// arrives from the proxy's IP, so they skip the per-IP and per-route
+	// limits and only count against the global one.
+	ProxyPaths []string
 }
 
 type ipLimiter struct {
@@ -37,6 +45,11 @@ func NewRateLimiter(cfg RateLimiterConfig) gin.HandlerFunc {
 	ipLimiters := make(map[string]*ipLimiter)
 	routeLimiters := make(map[string]*rate.Limiter)
 
+	proxyPaths := make(map[string]bool, len(cfg.ProxyPaths))
+	for _, p := range cfg.ProxyPaths {
+		proxyPaths[p] = true
+	}
+
 	var mu sync.Mutex
 
 	// Cleanup goroutine
@@ -57,15 +70,18 @@ func NewRateLimiter(cfg RateLimiterConfig) gin.HandlerFunc {
 	}()
 
 	return func(c *gin.Context) {
-		if !globalLimiter.Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "global rate limit exceeded",
-			})
+		if ok, wait := reserve(globalLimiter); !ok {
+			tooManyRequests(c, "global rate limit exceeded", wait)
+			return
+		}
+
+		if proxyPaths[c.FullPath()] {
+			c.Next()
 			return
 		}
 
 		now := time.Now()
-		ip := clientIP(c)
+		ip := ClientIP(c)
 
 		mu.Lock()
 		ipLim, ok := ipLimiters[ip]
@@ -79,10 +95,8 @@ func NewRateLimiter(cfg RateLimiterConfig) gin.HandlerFunc {
 		ipLim.lastSeen = now
 		mu.Unlock()
 
-		if !ipLim.limiter.Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "too many requests from this IP",
-			})
+		if ok, wait := reserve(ipLim.limiter); !ok {
+			tooManyRequests(c, "too many requests from this IP", wait)
 			return
 		}
 
@@ -100,10 +114,8 @@ func NewRateLimiter(cfg RateLimiterConfig) gin.HandlerFunc {
 		}
 		mu.Unlock()
 
-		if !routeLim.Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "route rate limit exceeded",
-			})
+		if ok, wait := reserve(routeLim); !ok {
+			tooManyRequests(c, "route rate limit exceeded", wait)
 			return
 		}
 
@@ -111,7 +123,37 @@ func NewRateLimiter(cfg RateLimiterConfig) gin.HandlerFunc {
 	}
 }
 
-func clientIP(c *gin.Context) string {
+// reserve takes a token from lim if one is available now. Otherwise it
+// takes nothing and reports how long until one will be.
+func reserve(lim *rate.Limiter) (bool, time.Duration) {
+	now := time.Now()
+	r := lim.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Second
+	}
+
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return true, 0
+	}
+
+	r.CancelAt(now)
+	return false, delay
+}
+
+// tooManyRequests aborts with 429 and a Retry-After of wait, rounded up to
+// whole seconds.
+func tooManyRequests(c *gin.Context, msg string, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": msg})
+}
+
+// ClientIP returns the normalized address of the client, or "unknown".
+func ClientIP(c *gin.Context) string {
 	ip := c.ClientIP()
 	if parsed := net.ParseIP(ip); parsed != nil {
 		return parsed.String()
@@ -3,6 +3,7 @@ package middleware_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -48,6 +49,7 @@ func TestGlobalRateLimit(t *testing.T) {
 	// Second request immediately should be rate-limited
 	res2 := performRequest(r, "1.1.1.1", "/test")
 	assert.Equal(t, 429, res2.Code)
+	assert.Equal(t, "1", res2.Header().Get("Retry-After"))
 }
 
 func TestIPRateLimit(t *testing.T) {
@@ -141,3 +143,56 @@ func TestLimiterReset(t *testing.T) {
 	res3 := performRequest(r, "6.6.6.6", "/test")
 	assert.Equal(t, 200, res3.Code)
 }
+
+func TestProxyPathsSkipIPAndRouteLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.NewRateLimiter(middleware.RateLimiterConfig{
+		GlobalRPS:   1,
+		GlobalBurst: 5,
+		IPRPS:       1,
+		IPBurst:     1,
+		RouteRPS:    1,
+		RouteBurst:  1,
+		ProxyPaths:  []string{"/forward"},
+	}))
+	r.GET("/forward", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// Every subrequest comes from the proxy.
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, 200, performRequest(r, "10.0.0.1", "/forward").Code)
+	}
+
+	// The global limit still applies.
+	assert.Equal(t, 429, performRequest(r, "10.0.0.1", "/forward").Code)
+}
+
+func TestRetryAfterIsHonored(t *testing.T) {
+	r := newTestRouter(middleware.RateLimiterConfig{
+		GlobalRPS:   100,
+		GlobalBurst: 100,
+		IPRPS:       2,
+		IPBurst:     1,
+		RouteRPS:    100,
+		RouteBurst:  100,
+	})
+
+	assert.Equal(t, 200, performRequest(r, "3.3.3.3", "/test").Code)
+
+	// Rejected requests don't use up tokens, so one retry after
+	// Retry-After succeeds however often the client was refused.
+	var res *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		res = performRequest(r, "3.3.3.3", "/test")
+		assert.Equal(t, 429, res.Code)
+	}
+
+	retryAfter, err := strconv.Atoi(res.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, retryAfter, "half a second is rounded up")
+
+	time.Sleep(time.Duration(retryAfter) * time.Second)
+	assert.Equal(t, 200, performRequest(r, "3.3.3.3", "/test").Code)
+}
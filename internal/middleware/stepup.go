@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFreshAuth allows the request only if the user authenticated within
+// maxAge, according to the "auth_time" claim of the access token, so that a
+// long-lived session alone is not enough for sensitive actions. It must run
+// after RequireAuth.
+//
+// Stale sessions get a 401 "reauthentication required" with max_age and a
+// Bearer challenge with error="insufficient_user_authentication" (RFC 9470).
+// Unlike other 401s, the session is still valid: the client should prompt
+// for the password or an MFA code and post it to /api/auth/reauthenticate.
+func (m *AuthMiddleware) RequireFreshAuth(maxAge time.Duration) gin.HandlerFunc {
+	seconds := int(maxAge.Seconds())
+
+	return func(c *gin.Context) {
+		val, _ := c.Get("claims")
+		claims, ok := val.(*authz.Claims)
+		if !ok || claims == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrUnauthorized.Error()})
+			return
+		}
+
+		if claims.AuthTime.IsZero() || time.Since(claims.AuthTime) > maxAge {
+			c.Header("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="sentinel-rbac", error="insufficient_user_authentication", error_description="A more recent authentication is required", max_age=%d`,
+				seconds,
+			))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   errors.ErrReauthRequired.Error(),
+				"max_age": seconds,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
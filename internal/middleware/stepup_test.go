@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/middleware"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireFreshAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		claims *authz.Claims
+		status int
+		stepUp bool
+	}{
+		{"recent", &authz.Claims{Subject: 1, AuthTime: time.Now().Add(-time.Minute)}, http.StatusOK, false},
+		{"stale", &authz.Claims{Subject: 1, AuthTime: time.Now().Add(-time.Hour)}, http.StatusUnauthorized, true},
+		{"no auth_time", &authz.Claims{Subject: 1}, http.StatusUnauthorized, true},
+		{"not authenticated", nil, http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := middleware.NewAuthMiddleware([]byte("secret"), new(mocks.UserRepositoryMock))
+
+			r := gin.New()
+			r.POST("/sensitive",
+				func(c *gin.Context) {
+					if tt.claims != nil {
+						c.Set("claims", tt.claims)
+					}
+				},
+				m.RequireFreshAuth(5*time.Minute),
+				func(c *gin.Context) {
+					c.Status(http.StatusOK)
+				},
+			)
+
+			req := httptest.NewRequest(http.MethodPost, "/sensitive", nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.status, w.Code)
+			if tt.stepUp {
+				require.JSONEq(t, `{"error":"reauthentication required","max_age":300}`, w.Body.String())
+				require.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_user_authentication"`)
+				require.Contains(t, w.Header().Get("WWW-Authenticate"), `max_age=300`)
+			} else {
+				require.Empty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
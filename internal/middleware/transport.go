@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenTransport is a way for a request to carry its access token.
+type TokenTransport string
+
+const (
+	// TransportCookie reads the Authorization cookie set by Login.
+	TransportCookie TokenTransport = "cookie"
+	// TransportBearer reads an "Authorization: Bearer <token>" header, as
+	// sent by CLI tools and other services.
+	TransportBearer TokenTransport = "bearer"
+)
+
+// DefaultTransports prefers the cookie and falls back to a bearer header.
+var DefaultTransports = []TokenTransport{TransportCookie, TransportBearer}
+
+// WithTransports sets where RequireAuth and forward auth look for the token,
+// in order of precedence. The first transport carrying a token is used even
+// if the token turns out to be invalid; the others are not consulted.
+func WithTransports(transports ...TokenTransport) AuthOption {
+	return func(m *AuthMiddleware) {
+		m.transports = transports
+	}
+}
+
+// extractToken returns the token from the first of transports present in h.
+func extractToken(h http.Header, transports []TokenTransport) string {
+	for _, t := range transports {
+		switch t {
+		case TransportCookie:
+			req := http.Request{Header: h}
+			if cookie, err := req.Cookie("Authorization"); err == nil && cookie.Value != "" {
+				return cookie.Value
+			}
+		case TransportBearer:
+			header := h.Get("Authorization")
+			if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+				if token := strings.TrimSpace(header[7:]); token != "" {
+					return token
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// unauthorized aborts with a 401, challenging for a bearer token when the
+// route accepts one.
+func unauthorized(c *gin.Context, transports []TokenTransport, err error) {
+	for _, t := range transports {
+		if t == TransportBearer {
+			c.Header("WWW-Authenticate", `Bearer realm="sentinel-rbac"`)
+			break
+		}
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+}
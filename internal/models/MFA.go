@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TOTPEnrollment is a user's authenticator app. It is pending until a code
+// confirms it; LastStep is the time step of the last accepted code, so a code
+// cannot be used twice.
+type TOTPEnrollment struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;uniqueIndex"`
+	Secret      string `gorm:"not null"`
+	ConfirmedAt *time.Time
+	LastStep    int64
+}
+
+// RecoveryCode is a one-time code that stands in for a TOTP code when the
+// authenticator is lost. Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// MFAChallenge is the second step of a login by a user with MFA enabled: an
+// opaque token, stored hashed, that is exchanged together with a valid code
+// for a session. It keeps the client details of the login for that session.
+type MFAChallenge struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	TokenHash string `gorm:"not null;uniqueIndex"`
+	IPAddress string
+	UserAgent string
+	Attempts  int
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// MFALockout counts a user's wrong second-factor codes and reauthentication
+// passwords across challenges and sessions. Reaching the limit locks both
+// until LockedUntil and starts the count over; a successful check clears it.
+type MFALockout struct {
+	UserID      uint `gorm:"primarykey;autoIncrement:false"`
+	Failures    int  `gorm:"not null;default:0"`
+	LockedUntil *time.Time
+	UpdatedAt   time.Time
+}
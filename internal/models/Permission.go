@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Grant effects. A deny grant always overrides any matching allow grant.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Permission is a fine-grained action string such as "invoices:read".
+type Permission struct {
+	gorm.Model
+	Name        string `gorm:"unique;not null"`
+	Description string
+}
+
+// RolePermission grants or denies a permission to a role (many-to-many).
+type RolePermission struct {
+	RoleID       uint   `gorm:"primaryKey"`
+	PermissionID uint   `gorm:"primaryKey"`
+	Effect       string `gorm:"not null;default:allow"`
+	CreatedAt    time.Time
+}
+
+// UserPermission grants or denies a permission to an individual user,
+// independently of their roles.
+type UserPermission struct {
+	UserID       uint   `gorm:"primaryKey"`
+	PermissionID uint   `gorm:"primaryKey"`
+	Effect       string `gorm:"not null;default:allow"`
+	CreatedAt    time.Time
+}
+
+// PermissionGrant is a resolved grant as seen by the authorizer: the
+// permission pattern, its effect and where it came from. RoleID and RoleName
+// are empty for grants attached directly to a user.
+type PermissionGrant struct {
+	Permission string
+	Effect     string
+	RoleID     uint
+	RoleName   string
+	UserID     uint
+}
@@ -0,0 +1,11 @@
+package models
+
+import "gorm.io/gorm"
+
+// Policy stores an attribute-based access control policy. Definition holds
+// the JSON encoding of the policy (effect, actions, resources, conditions).
+type Policy struct {
+	gorm.Model
+	Name       string `gorm:"unique;not null"`
+	Definition string `gorm:"type:text;not null"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RevokedToken blocks access tokens before they expire. An entry with a JTI
+// revokes that single token and one with a SessionID every token of that
+// session; an entry with neither revokes every token issued to UserID before
+// RevokedAt. Entries can be deleted once ExpiresAt has
+// passed, as every token they block has expired by then.
+type RevokedToken struct {
+	ID        uint      `gorm:"primarykey"`
+	JTI       string    `gorm:"index"`
+	SessionID uint      `gorm:"index"`
+	UserID    uint      `gorm:"not null;index"`
+	RevokedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
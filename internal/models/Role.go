@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Role struct {
+	gorm.Model
+	Name        string `gorm:"unique;not null"`
+	Description string
+	// MFARequired denies AuthorizeRole to holders of the role until they
+	// enable MFA.
+	MFARequired bool `gorm:"not null;default:false"`
+}
+
+// UserRole assigns a role to a user (many-to-many).
+type UserRole struct {
+	UserID    uint `gorm:"primaryKey"`
+	RoleID    uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+// RoleInheritance makes RoleID inherit every grant of ParentID, so that e.g.
+// "admin" can inherit from "editor", which in turn inherits from "viewer".
+type RoleInheritance struct {
+	RoleID    uint `gorm:"primaryKey"`
+	ParentID  uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
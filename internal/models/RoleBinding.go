@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// RoleBinding grants a role to a user on a single resource only, e.g. alice
+// is "editor" on project 42.
+type RoleBinding struct {
+	gorm.Model
+	UserID       uint   `gorm:"not null;uniqueIndex:idx_role_binding"`
+	RoleID       uint   `gorm:"not null;uniqueIndex:idx_role_binding"`
+	ResourceType string `gorm:"not null;uniqueIndex:idx_role_binding;index:idx_role_binding_resource"`
+	ResourceID   string `gorm:"not null;uniqueIndex:idx_role_binding;index:idx_role_binding_resource"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a login on one device: the refresh token family it started,
+// where it was made from and when its tokens were last used. Access tokens
+// name their session in the "sid" claim. LastUsedAt is updated on refresh
+// and, at most once a minute, when an access token authenticates a request.
+//
+// AuthTime and AuthMethods record when and how the user last proved their
+// identity in the session, at login or reauthentication. Refreshed access
+// tokens carry them on as the "auth_time" and "amr" claims.
+type Session struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;index"`
+	FamilyID    string `gorm:"not null;uniqueIndex"`
+	IPAddress   string
+	UserAgent   string
+	AuthTime    *time.Time
+	AuthMethods string
+	LastUsedAt  time.Time `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	RevokedAt   *time.Time
+}
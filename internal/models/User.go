@@ -7,4 +7,7 @@ type User struct {
 	Email    string `gorm:"unique;not null"`
 	Password string `gorm:"not null"`
 	Role     string `gorm:"not null;default:user"`
+	// MFAEnabled is set once a TOTP enrollment is confirmed; login then
+	// requires a code.
+	MFAEnabled bool `gorm:"not null;default:false"`
 }
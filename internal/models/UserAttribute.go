@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// UserAttribute is a custom key/value attribute of a user, such as
+// "department", made available to policies as "subject.<key>".
+type UserAttribute struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;uniqueIndex:idx_user_attribute"`
+	Key    string `gorm:"not null;uniqueIndex:idx_user_attribute"`
+	Value  string
+}
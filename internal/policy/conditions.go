@@ -0,0 +1,203 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type operator func(actual, expected any) bool
+
+// operators lists every supported condition operator:
+//
+//	eq, ne            equality (numbers are compared numerically)
+//	lt, lte, gt, gte  ordering of numbers, times or strings
+//	in, not_in        membership of actual in the expected list
+//	contains          actual list (or string) contains expected
+//	starts_with       string prefix
+//	cidr              actual IP inside the expected CIDR (or list of CIDRs)
+//	between           actual within the inclusive [min, max] expected pair
+//	exists            attribute is present (expected is ignored)
+var operators = map[string]operator{
+	"eq":          func(a, e any) bool { return equal(a, e) },
+	"ne":          func(a, e any) bool { return a != nil && !equal(a, e) },
+	"lt":          func(a, e any) bool { c, ok := compare(a, e); return ok && c < 0 },
+	"lte":         func(a, e any) bool { c, ok := compare(a, e); return ok && c <= 0 },
+	"gt":          func(a, e any) bool { c, ok := compare(a, e); return ok && c > 0 },
+	"gte":         func(a, e any) bool { c, ok := compare(a, e); return ok && c >= 0 },
+	"in":          func(a, e any) bool { return inList(a, e) },
+	"not_in":      func(a, e any) bool { return a != nil && !inList(a, e) },
+	"contains":    contains,
+	"starts_with": func(a, e any) bool { return a != nil && strings.HasPrefix(toString(a), toString(e)) },
+	"cidr":        inCIDR,
+	"between":     between,
+	"exists":      func(a, _ any) bool { return a != nil },
+}
+
+func equal(a, e any) bool {
+	if a == nil || e == nil {
+		return a == nil && e == nil
+	}
+	if c, ok := compare(a, e); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, e)
+}
+
+// compare orders two values as numbers, times or strings, in that order of
+// preference. Numeric strings are compared as numbers when the other side is
+// a number, so attributes stored as text still compare naturally.
+func compare(a, e any) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(e); ok {
+			return cmp(x, y), true
+		}
+	}
+
+	if x, ok := toTime(a); ok {
+		if y, ok := toTime(e); ok {
+			return x.Compare(y), true
+		}
+	}
+
+	x, okA := a.(string)
+	y, okE := e.(string)
+	if okA && okE {
+		return strings.Compare(x, y), true
+	}
+
+	return 0, false
+}
+
+func cmp(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func inList(a, e any) bool {
+	for _, item := range toList(e) {
+		if equal(a, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(a, e any) bool {
+	if s, ok := a.(string); ok {
+		return strings.Contains(s, toString(e))
+	}
+	return inList(e, a)
+}
+
+func inCIDR(a, e any) bool {
+	ip := net.ParseIP(toString(a))
+	if ip == nil {
+		return false
+	}
+
+	for _, item := range toList(e) {
+		_, network, err := net.ParseCIDR(toString(item))
+		if err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func between(a, e any) bool {
+	bounds := toList(e)
+	if len(bounds) != 2 {
+		return false
+	}
+
+	lo, ok := compare(a, bounds[0])
+	if !ok {
+		return false
+	}
+	hi, ok := compare(a, bounds[1])
+	return ok && lo >= 0 && hi <= 0
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339, t)
+		return parsed, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+func toList(v any) []any {
+	if v == nil {
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []any{v}
+	}
+
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
+
+func toString(v any) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	default:
+		return fmt.Sprint(v)
+	}
+}
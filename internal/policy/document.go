@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported policy file formats.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Document is the declarative access model kept in version control. Every
+// section is optional:
+//
+//	permissions:
+//	  - {name: "billing:invoices:read", description: "Read invoices"}
+//	roles:
+//	  - name: admin
+//	    mfa_required: true
+//	    inherits: [editor]
+//	    allow: ["billing:*"]
+//	    deny: ["billing:payouts:approve"]
+//	users:
+//	  - email: alice@example.com
+//	    roles: [viewer]
+//	    deny: ["users:delete"]
+//	    attributes: {department: sales}
+//	bindings:
+//	  - {user: alice@example.com, role: editor, resource_type: project, resource_id: "42"}
+//	policies:
+//	  - name: managers-approve-expenses
+//	    effect: allow
+//	    actions: ["expenses:approve"]
+//	    resources: ["expense"]
+//	    conditions:
+//	      - {attribute: subject.role, operator: eq, value: manager}
+//	      - {attribute: resource.amount, operator: lt, value: 10000}
+//	      - {attribute: resource.department, operator: eq, ref: subject.department}
+type Document struct {
+	Permissions []PermissionSpec `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	Roles       []RoleSpec       `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Users       []UserSpec       `json:"users,omitempty" yaml:"users,omitempty"`
+	Bindings    []BindingSpec    `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+	Policies    []Policy         `json:"policies,omitempty" yaml:"policies,omitempty"`
+}
+
+type PermissionSpec struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// RoleSpec declares a role, the roles it inherits from and its grants.
+// MFARequired makes AuthorizeRole refuse holders of the role without MFA.
+type RoleSpec struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	MFARequired bool     `json:"mfa_required,omitempty" yaml:"mfa_required,omitempty"`
+	Inherits    []string `json:"inherits,omitempty" yaml:"inherits,omitempty"`
+	Allow       []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	Deny        []string `json:"deny,omitempty" yaml:"deny,omitempty"`
+}
+
+// UserSpec declares the global roles, direct grants and custom attributes of
+// an existing user, identified by email.
+type UserSpec struct {
+	Email      string            `json:"email" yaml:"email"`
+	Roles      []string          `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Allow      []string          `json:"allow,omitempty" yaml:"allow,omitempty"`
+	Deny       []string          `json:"deny,omitempty" yaml:"deny,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// BindingSpec declares a resource-scoped role binding.
+type BindingSpec struct {
+	User         string `json:"user" yaml:"user"`
+	Role         string `json:"role" yaml:"role"`
+	ResourceType string `json:"resource_type" yaml:"resource_type"`
+	ResourceID   string `json:"resource_id" yaml:"resource_id"`
+}
+
+// FormatFromPath infers the file format from the extension of path.
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("policy: unsupported file type %q", filepath.Ext(path))
+	}
+}
+
+// ReadFile reads a Document from a JSON (.json) or YAML (.yaml, .yml) file.
+func ReadFile(path string) (*Document, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policy: read %s: %w", path, err)
+	}
+
+	doc, err := Decode(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("policy: parse %s: %w", path, err)
+	}
+
+	return doc, nil
+}
+
+func Decode(data []byte, format string) (*Document, error) {
+	var doc Document
+	var err error
+
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(data, &doc)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("policy: unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+func Encode(doc *Document, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		return yaml.Marshal(doc)
+	default:
+		return nil, fmt.Errorf("policy: unsupported format %q", format)
+	}
+}
@@ -0,0 +1,163 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+)
+
+// Engine evaluates requests against a set of policies. It is safe for
+// concurrent use; Load atomically replaces the active policy set.
+//
+// Evaluation is deny-overrides: a matching deny policy always wins, any
+// matching allow policy grants access, and a request matched by no policy
+// is denied.
+type Engine struct {
+	mu       sync.RWMutex
+	policies []Policy
+}
+
+func NewEngine(policies ...Policy) (*Engine, error) {
+	e := &Engine{}
+	if err := e.Load(policies); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Load validates and installs the given policies, replacing the current set.
+// On error the current set is left untouched.
+func (e *Engine) Load(policies []Policy) error {
+	seen := make(map[string]bool, len(policies))
+	for _, p := range policies {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("policy %q: duplicate name", p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	loaded := append([]Policy(nil), policies...)
+
+	e.mu.Lock()
+	e.policies = loaded
+	e.mu.Unlock()
+
+	return nil
+}
+
+// Policies returns a copy of the active policy set.
+func (e *Engine) Policies() []Policy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]Policy(nil), e.policies...)
+}
+
+func (e *Engine) Evaluate(req Request) Decision {
+	e.mu.RLock()
+	policies := e.policies
+	e.mu.RUnlock()
+
+	var allow *Policy
+
+	for i := range policies {
+		p := &policies[i]
+		if !p.applies(req) {
+			continue
+		}
+
+		if p.Effect == models.EffectDeny {
+			return Decision{
+				Policy: p.Name,
+				Reason: fmt.Sprintf("%q denied by policy %q", req.Action, p.Name),
+			}
+		}
+		if allow == nil {
+			allow = p
+		}
+	}
+
+	if allow == nil {
+		return Decision{Reason: fmt.Sprintf("no policy allows %q", req.Action)}
+	}
+
+	return Decision{
+		Allowed: true,
+		Policy:  allow.Name,
+		Reason:  fmt.Sprintf("%q allowed by policy %q", req.Action, allow.Name),
+	}
+}
+
+func (p *Policy) applies(req Request) bool {
+	if !p.matchesAction(req.Action) || !p.matchesResource(req.Resource) {
+		return false
+	}
+
+	for _, c := range p.Conditions {
+		if !c.holds(req) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Policy) matchesAction(action string) bool {
+	for _, a := range p.Actions {
+		if service.MatchPermission(a, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) matchesResource(resource Attributes) bool {
+	if len(p.Resources) == 0 {
+		return true
+	}
+
+	resourceType, _ := resource["type"].(string)
+	for _, r := range p.Resources {
+		if r == "*" || r == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) holds(req Request) bool {
+	actual := req.lookup(c.Attribute)
+
+	expected := c.Value
+	if c.Ref != "" {
+		expected = req.lookup(c.Ref)
+		if expected == nil {
+			return false
+		}
+	}
+
+	return operators[c.Operator](actual, expected)
+}
+
+func (req Request) lookup(path string) any {
+	if path == ActionAttribute {
+		return req.Action
+	}
+
+	var attrs Attributes
+	switch {
+	case strings.HasPrefix(path, SubjectPrefix):
+		attrs, path = req.Subject, strings.TrimPrefix(path, SubjectPrefix)
+	case strings.HasPrefix(path, ResourcePrefix):
+		attrs, path = req.Resource, strings.TrimPrefix(path, ResourcePrefix)
+	case strings.HasPrefix(path, EnvironmentPrefix):
+		attrs, path = req.Environment, strings.TrimPrefix(path, EnvironmentPrefix)
+	}
+
+	return attrs[path]
+}
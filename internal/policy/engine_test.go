@@ -0,0 +1,179 @@
+package policy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/policy"
+	"github.com/stretchr/testify/require"
+)
+
+var expensePolicies = []policy.Policy{
+	{
+		Name:      "managers-approve-expenses",
+		Effect:    "allow",
+		Actions:   []string{"expenses:approve"},
+		Resources: []string{"expense"},
+		Conditions: []policy.Condition{
+			{Attribute: "subject.role", Operator: "eq", Value: "manager"},
+			{Attribute: "resource.amount", Operator: "lt", Value: 10000},
+			{Attribute: "resource.department", Operator: "eq", Ref: "subject.department"},
+		},
+	},
+	{
+		Name:    "no-approvals-from-shared-network",
+		Effect:  "deny",
+		Actions: []string{"expenses:*"},
+		Conditions: []policy.Condition{
+			{Attribute: "environment.client_ip", Operator: "cidr", Value: []any{"10.0.0.0/8", "192.168.0.0/16"}},
+		},
+	},
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	engine, err := policy.NewEngine(expensePolicies...)
+	require.NoError(t, err)
+
+	manager := policy.Attributes{"role": "manager", "department": "sales"}
+	public := policy.Attributes{"client_ip": "203.0.113.7"}
+
+	tests := []struct {
+		name    string
+		req     policy.Request
+		allowed bool
+		policy  string
+	}{
+		{
+			name: "manager approves small expense in own department",
+			req: policy.Request{
+				Subject:     manager,
+				Action:      "expenses:approve",
+				Resource:    policy.Attributes{"type": "expense", "amount": 420.5, "department": "sales"},
+				Environment: public,
+			},
+			allowed: true,
+			policy:  "managers-approve-expenses",
+		},
+		{
+			name: "amount over limit",
+			req: policy.Request{
+				Subject:     manager,
+				Action:      "expenses:approve",
+				Resource:    policy.Attributes{"type": "expense", "amount": 15000, "department": "sales"},
+				Environment: public,
+			},
+		},
+		{
+			name: "other department",
+			req: policy.Request{
+				Subject:     manager,
+				Action:      "expenses:approve",
+				Resource:    policy.Attributes{"type": "expense", "amount": 100, "department": "finance"},
+				Environment: public,
+			},
+		},
+		{
+			name: "not a manager",
+			req: policy.Request{
+				Subject:     policy.Attributes{"role": "user", "department": "sales"},
+				Action:      "expenses:approve",
+				Resource:    policy.Attributes{"type": "expense", "amount": 100, "department": "sales"},
+				Environment: public,
+			},
+		},
+		{
+			name: "numeric string attribute",
+			req: policy.Request{
+				Subject:     manager,
+				Action:      "expenses:approve",
+				Resource:    policy.Attributes{"type": "expense", "amount": "9999", "department": "sales"},
+				Environment: public,
+			},
+			allowed: true,
+			policy:  "managers-approve-expenses",
+		},
+		{
+			name: "deny overrides allow",
+			req: policy.Request{
+				Subject:     manager,
+				Action:      "expenses:approve",
+				Resource:    policy.Attributes{"type": "expense", "amount": 100, "department": "sales"},
+				Environment: policy.Attributes{"client_ip": "10.1.2.3"},
+			},
+			policy: "no-approvals-from-shared-network",
+		},
+		{
+			name: "wrong resource type",
+			req: policy.Request{
+				Subject:     manager,
+				Action:      "expenses:approve",
+				Resource:    policy.Attributes{"type": "invoice", "amount": 100, "department": "sales"},
+				Environment: public,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := engine.Evaluate(tt.req)
+			require.Equal(t, tt.allowed, d.Allowed, d.Reason)
+			require.Equal(t, tt.policy, d.Policy)
+		})
+	}
+}
+
+func TestEngine_Operators(t *testing.T) {
+	noon := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		cond policy.Condition
+		env  policy.Attributes
+		want bool
+	}{
+		{"between hours", policy.Condition{Attribute: "environment.hour", Operator: "between", Value: []any{9, 17}}, policy.Attributes{"hour": 12}, true},
+		{"outside hours", policy.Condition{Attribute: "environment.hour", Operator: "between", Value: []any{9, 17}}, policy.Attributes{"hour": 20}, false},
+		{"time after", policy.Condition{Attribute: "environment.time", Operator: "gte", Value: "2026-01-01T00:00:00Z"}, policy.Attributes{"time": noon}, true},
+		{"in list", policy.Condition{Attribute: "environment.weekday", Operator: "in", Value: []any{"Monday", "Tuesday"}}, policy.Attributes{"weekday": "Monday"}, true},
+		{"not in list", policy.Condition{Attribute: "environment.weekday", Operator: "not_in", Value: []any{"Saturday", "Sunday"}}, policy.Attributes{"weekday": "Sunday"}, false},
+		{"contains list", policy.Condition{Attribute: "environment.tags", Operator: "contains", Value: "beta"}, policy.Attributes{"tags": []string{"beta", "eu"}}, true},
+		{"starts with", policy.Condition{Attribute: "environment.path", Operator: "starts_with", Value: "/api/"}, policy.Attributes{"path": "/api/users"}, true},
+		{"ne missing attribute", policy.Condition{Attribute: "environment.missing", Operator: "ne", Value: "x"}, policy.Attributes{}, false},
+		{"exists", policy.Condition{Attribute: "environment.path", Operator: "exists"}, policy.Attributes{"path": "/"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := policy.NewEngine(policy.Policy{
+				Name:       "p",
+				Effect:     "allow",
+				Actions:    []string{"*"},
+				Conditions: []policy.Condition{tt.cond},
+			})
+			require.NoError(t, err)
+
+			d := engine.Evaluate(policy.Request{Action: "reports:read", Environment: tt.env})
+			require.Equal(t, tt.want, d.Allowed)
+		})
+	}
+}
+
+func TestEngine_LoadRejectsInvalidPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy policy.Policy
+	}{
+		{"missing name", policy.Policy{Effect: "allow", Actions: []string{"*"}}},
+		{"bad effect", policy.Policy{Name: "p", Effect: "maybe", Actions: []string{"*"}}},
+		{"no actions", policy.Policy{Name: "p", Effect: "allow"}},
+		{"unknown operator", policy.Policy{Name: "p", Effect: "allow", Actions: []string{"*"}, Conditions: []policy.Condition{{Attribute: "subject.role", Operator: "like"}}}},
+		{"unknown attribute namespace", policy.Policy{Name: "p", Effect: "allow", Actions: []string{"*"}, Conditions: []policy.Condition{{Attribute: "user.role", Operator: "eq"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := policy.NewEngine(tt.policy)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+)
+
+// LoadFile reads the ABAC policies from a JSON (.json) or YAML (.yaml, .yml)
+// policy file. See Document for the file layout.
+func LoadFile(path string) ([]Policy, error) {
+	doc, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Policies, nil
+}
+
+// FromModel decodes a policy stored in the database.
+func FromModel(m models.Policy) (Policy, error) {
+	var p Policy
+	if err := json.Unmarshal([]byte(m.Definition), &p); err != nil {
+		return Policy{}, fmt.Errorf("policy %q: decode definition: %w", m.Name, err)
+	}
+	p.Name = m.Name
+	return p, nil
+}
+
+// ToModel encodes a policy for storage in the database.
+func ToModel(p Policy) (models.Policy, error) {
+	def, err := json.Marshal(p)
+	if err != nil {
+		return models.Policy{}, fmt.Errorf("policy %q: encode definition: %w", p.Name, err)
+	}
+	return models.Policy{Name: p.Name, Definition: string(def)}, nil
+}
+
+// LoadRepository reads every policy stored in the database.
+func LoadRepository(ctx context.Context, repo repository.PolicyRepository) ([]Policy, error) {
+	stored, err := repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("policy: list: %w", err)
+	}
+
+	policies := make([]Policy, 0, len(stored))
+	for _, m := range stored {
+		p, err := FromModel(m)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	return policies, nil
+}
+
+// SubjectAttributes builds the subject attributes of a user: "id", "email"
+// and "role" from the user record, plus every custom attribute by key.
+// Custom attributes never shadow the built-in ones.
+func SubjectAttributes(user *models.User, custom []models.UserAttribute) Attributes {
+	attrs := make(Attributes, len(custom)+3)
+	for _, a := range custom {
+		attrs[a.Key] = a.Value
+	}
+
+	attrs["id"] = user.ID
+	attrs["email"] = user.Email
+	attrs["role"] = user.Role
+
+	return attrs
+}
@@ -0,0 +1,62 @@
+package policy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFile_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+policies:
+  - name: managers-approve-expenses
+    effect: allow
+    actions: ["expenses:approve"]
+    resources: ["expense"]
+    conditions:
+      - {attribute: resource.amount, operator: lt, value: 10000}
+`), 0o600))
+
+	policies, err := policy.LoadFile(path)
+
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	require.Equal(t, "managers-approve-expenses", policies[0].Name)
+	require.Equal(t, "lt", policies[0].Conditions[0].Operator)
+}
+
+func TestLoadFile_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"policies":[{"name":"p","effect":"deny","actions":["users:delete"]}]}`), 0o600))
+
+	policies, err := policy.LoadFile(path)
+
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	require.Equal(t, "deny", policies[0].Effect)
+}
+
+func TestLoadFile_UnsupportedExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.txt")
+	require.NoError(t, os.WriteFile(path, []byte("policies: []"), 0o600))
+
+	_, err := policy.LoadFile(path)
+
+	require.Error(t, err)
+}
+
+func TestModelRoundTrip(t *testing.T) {
+	p := policy.Policy{Name: "p", Effect: "allow", Actions: []string{"reports:read"}}
+
+	m, err := policy.ToModel(p)
+	require.NoError(t, err)
+
+	got, err := policy.FromModel(models.Policy{Name: m.Name, Definition: m.Definition})
+	require.NoError(t, err)
+	require.Equal(t, p, got)
+}
@@ -0,0 +1,104 @@
+// Package policy implements attribute-based access control (ABAC): policies
+// whose conditions are evaluated over subject, resource and environment
+// attributes, complementing the role and permission checks in the service
+// layer.
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+)
+
+// Attribute namespaces usable in condition paths, e.g. "subject.department".
+const (
+	SubjectPrefix     = "subject."
+	ResourcePrefix    = "resource."
+	EnvironmentPrefix = "environment."
+	ActionAttribute   = "action"
+)
+
+// Attributes is a flat bag of named values describing a subject, a resource
+// or the request environment.
+type Attributes map[string]any
+
+// Policy grants or denies a set of actions on a set of resource types when
+// all of its conditions hold.
+//
+// Actions are permission patterns and support the same wildcards as role
+// grants ("expenses:*"). Resources are resource types; "*" or an empty list
+// matches any type.
+type Policy struct {
+	Name        string      `json:"name" yaml:"name"`
+	Description string      `json:"description,omitempty" yaml:"description,omitempty"`
+	Effect      string      `json:"effect" yaml:"effect"`
+	Actions     []string    `json:"actions" yaml:"actions"`
+	Resources   []string    `json:"resources,omitempty" yaml:"resources,omitempty"`
+	Conditions  []Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+}
+
+// Condition compares the attribute at Attribute with either a literal Value
+// or, when Ref is set, the value of another attribute.
+type Condition struct {
+	Attribute string `json:"attribute" yaml:"attribute"`
+	Operator  string `json:"operator" yaml:"operator"`
+	Value     any    `json:"value,omitempty" yaml:"value,omitempty"`
+	Ref       string `json:"ref,omitempty" yaml:"ref,omitempty"`
+}
+
+// Request is a single access request to evaluate. Resource attributes should
+// include "type" (and usually "id").
+type Request struct {
+	Subject     Attributes
+	Action      string
+	Resource    Attributes
+	Environment Attributes
+}
+
+// Decision is the outcome of evaluating a Request. Policy names the policy
+// that produced it, if any.
+type Decision struct {
+	Allowed bool
+	Policy  string
+	Reason  string
+}
+
+// Validate reports the first structural problem with the policy.
+func (p Policy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("policy: missing name")
+	}
+	if p.Effect != models.EffectAllow && p.Effect != models.EffectDeny {
+		return fmt.Errorf("policy %q: effect must be %q or %q", p.Name, models.EffectAllow, models.EffectDeny)
+	}
+	if len(p.Actions) == 0 {
+		return fmt.Errorf("policy %q: at least one action is required", p.Name)
+	}
+
+	for i, c := range p.Conditions {
+		if !validPath(c.Attribute) {
+			return fmt.Errorf("policy %q: condition %d: unknown attribute %q", p.Name, i, c.Attribute)
+		}
+		if c.Ref != "" && !validPath(c.Ref) {
+			return fmt.Errorf("policy %q: condition %d: unknown ref %q", p.Name, i, c.Ref)
+		}
+		if _, ok := operators[c.Operator]; !ok {
+			return fmt.Errorf("policy %q: condition %d: unknown operator %q", p.Name, i, c.Operator)
+		}
+	}
+
+	return nil
+}
+
+func validPath(path string) bool {
+	if path == ActionAttribute {
+		return true
+	}
+	for _, prefix := range []string{SubjectPrefix, ResourcePrefix, EnvironmentPrefix} {
+		if strings.HasPrefix(path, prefix) && len(path) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,946 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"gorm.io/gorm"
+)
+
+// Store bundles the repositories a Syncer reads and writes.
+type Store struct {
+	Users       repository.UserRepository
+	Roles       repository.RoleRepository
+	Permissions repository.PermissionRepository
+	Bindings    repository.RoleBindingRepository
+	Attributes  repository.UserAttributeRepository
+	Policies    repository.PolicyRepository
+}
+
+// NewStore returns a Store over db.
+func NewStore(db *gorm.DB) Store {
+	return Store{
+		Users:       repository.NewUserRepository(db),
+		Roles:       repository.NewRoleRepository(db),
+		Permissions: repository.NewPermissionRepository(db),
+		Bindings:    repository.NewRoleBindingRepository(db),
+		Attributes:  repository.NewUserAttributeRepository(db),
+		Policies:    repository.NewPolicyRepository(db),
+	}
+}
+
+// Syncer reconciles a Document into the database and dumps the database back
+// into a Document.
+//
+// Import is idempotent and scoped to what the document declares: permissions,
+// roles and policies are created or updated, and the parents and grants of
+// every declared role, the roles, grants and attributes of every user listed
+// under users, and the bindings of every user mentioned under users or
+// bindings, are made to match the document exactly. A user mentioned only
+// under bindings keeps their roles, grants and attributes. Anything the
+// document does not mention is left alone.
+// Users are never created; they must already exist. An import runs in one
+// transaction, so one that fails changes nothing.
+type Syncer interface {
+	Import(ctx context.Context, doc *Document, dryRun bool) ([]string, error)
+	Export(ctx context.Context) (*Document, error)
+}
+
+type syncer struct {
+	db     *gorm.DB
+	stores func(db *gorm.DB) Store
+	store  Store
+	roles  service.RoleService
+}
+
+// NewSyncer returns a Syncer over the repositories stores builds on db,
+// typically NewStore. Import builds them again on its transaction.
+func NewSyncer(db *gorm.DB, stores func(db *gorm.DB) Store) Syncer {
+	return newSyncer(db, stores)
+}
+
+func newSyncer(db *gorm.DB, stores func(db *gorm.DB) Store) *syncer {
+	store := stores(db)
+	return &syncer{db: db, stores: stores, store: store, roles: service.NewRoleService(store.Roles)}
+}
+
+// changeLog records every change Import makes and skips applying them when
+// running dry.
+type changeLog struct {
+	dryRun  bool
+	changes []string
+}
+
+func (l *changeLog) apply(desc string, fn func() error) error {
+	l.changes = append(l.changes, desc)
+	if l.dryRun {
+		return nil
+	}
+	if err := fn(); err != nil {
+		return fmt.Errorf("%s: %w", desc, err)
+	}
+	return nil
+}
+
+// Import applies doc and returns a description of every change, in order.
+// With dryRun set nothing is written and the returned changes describe what
+// would have been done. On error the transaction is rolled back and no
+// changes are returned.
+func (s *syncer) Import(ctx context.Context, doc *Document, dryRun bool) ([]string, error) {
+	if dryRun {
+		return s.apply(ctx, doc, &changeLog{dryRun: true})
+	}
+
+	var changes []string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		changes, err = newSyncer(tx, s.stores).apply(ctx, doc, &changeLog{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// apply reconciles doc through s.store, recording every change in log.
+func (s *syncer) apply(ctx context.Context, doc *Document, log *changeLog) ([]string, error) {
+	users, err := s.validate(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	perms, err := s.syncPermissions(ctx, doc, log)
+	if err != nil {
+		return log.changes, err
+	}
+
+	roles, err := s.syncRoles(ctx, doc, log)
+	if err != nil {
+		return log.changes, err
+	}
+
+	if err := s.syncInheritance(ctx, doc, roles, log); err != nil {
+		return log.changes, err
+	}
+
+	for _, spec := range doc.Roles {
+		role := roles[spec.Name]
+		current, err := s.store.Permissions.FindRoleGrants(ctx, []uint{role.ID})
+		if err != nil {
+			return log.changes, err
+		}
+
+		err = syncGrants(current, spec.Allow, spec.Deny, perms, log, "role "+spec.Name,
+			func(permissionID uint, effect string) error {
+				return s.store.Permissions.GrantToRole(ctx, role.ID, permissionID, effect)
+			},
+			func(permissionID uint) error {
+				return s.store.Permissions.RevokeFromRole(ctx, role.ID, permissionID)
+			},
+		)
+		if err != nil {
+			return log.changes, err
+		}
+	}
+
+	if err := s.syncUsers(ctx, doc, users, roles, perms, log); err != nil {
+		return log.changes, err
+	}
+
+	if err := s.syncPolicies(ctx, doc, log); err != nil {
+		return log.changes, err
+	}
+
+	return log.changes, nil
+}
+
+// validate checks the document before anything is written and resolves the
+// users it mentions by email.
+func (s *syncer) validate(ctx context.Context, doc *Document) (map[string]*models.User, error) {
+	declared := make(map[string]bool, len(doc.Roles))
+	for _, r := range doc.Roles {
+		if r.Name == "" {
+			return nil, fmt.Errorf("policy: role without a name")
+		}
+		if declared[r.Name] {
+			return nil, fmt.Errorf("policy: role %q declared twice", r.Name)
+		}
+		declared[r.Name] = true
+
+		if err := checkGrantOverlap("role "+r.Name, r.Allow, r.Deny); err != nil {
+			return nil, err
+		}
+	}
+
+	roleExists := func(name string) error {
+		if declared[name] {
+			return nil
+		}
+		role, err := s.store.Roles.FindByName(ctx, name)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			return fmt.Errorf("policy: unknown role %q", name)
+		}
+		return nil
+	}
+
+	for _, r := range doc.Roles {
+		for _, parent := range r.Inherits {
+			if err := roleExists(parent); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.checkCycles(ctx, doc); err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]*models.User)
+	resolveUser := func(email string) error {
+		if _, ok := users[email]; ok {
+			return nil
+		}
+		user, err := s.store.Users.FindByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("policy: unknown user %q", email)
+		}
+		users[email] = user
+		return nil
+	}
+
+	for _, u := range doc.Users {
+		if err := resolveUser(u.Email); err != nil {
+			return nil, err
+		}
+		for _, r := range u.Roles {
+			if err := roleExists(r); err != nil {
+				return nil, err
+			}
+		}
+		if err := checkGrantOverlap("user "+u.Email, u.Allow, u.Deny); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, b := range doc.Bindings {
+		if b.ResourceType == "" || b.ResourceID == "" {
+			return nil, fmt.Errorf("policy: binding of %q to %q needs resource_type and resource_id", b.User, b.Role)
+		}
+		if err := resolveUser(b.User); err != nil {
+			return nil, err
+		}
+		if err := roleExists(b.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make(map[string]bool, len(doc.Policies))
+	for _, p := range doc.Policies {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("policy %q: duplicate name", p.Name)
+		}
+		names[p.Name] = true
+	}
+
+	return users, nil
+}
+
+func checkGrantOverlap(owner string, allow, deny []string) error {
+	allowed := make(map[string]bool, len(allow))
+	for _, p := range allow {
+		allowed[p] = true
+	}
+	for _, p := range deny {
+		if allowed[p] {
+			return fmt.Errorf("policy: %s both allows and denies %q", owner, p)
+		}
+	}
+	return nil
+}
+
+// checkCycles rejects a document whose role hierarchy, combined with the
+// stored parents of roles it does not declare, contains a cycle.
+func (s *syncer) checkCycles(ctx context.Context, doc *Document) error {
+	stored, err := s.store.Roles.List(ctx)
+	if err != nil {
+		return err
+	}
+	edges, err := s.store.Roles.ListInheritance(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[uint]string, len(stored))
+	for _, r := range stored {
+		names[r.ID] = r.Name
+	}
+
+	parents := make(map[string][]string)
+	for _, e := range edges {
+		parents[names[e.RoleID]] = append(parents[names[e.RoleID]], names[e.ParentID])
+	}
+	for _, r := range doc.Roles {
+		parents[r.Name] = r.Inherits
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("policy: role %q: %w", name, appErr.ErrRoleCycle)
+		case done:
+			return nil
+		}
+
+		state[name] = visiting
+		for _, p := range parents[name] {
+			if err := visit(p); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		return nil
+	}
+
+	for _, r := range doc.Roles {
+		if err := visit(r.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncPermissions makes sure every declared or referenced permission exists
+// and returns every known permission by name.
+func (s *syncer) syncPermissions(ctx context.Context, doc *Document, log *changeLog) (map[string]*models.Permission, error) {
+	descriptions := make(map[string]string)
+	var order []string
+
+	add := func(name, description string, declared bool) {
+		if _, ok := descriptions[name]; !ok {
+			order = append(order, name)
+			descriptions[name] = ""
+		}
+		if declared {
+			descriptions[name] = description
+		}
+	}
+
+	for _, p := range doc.Permissions {
+		add(p.Name, p.Description, true)
+	}
+	for _, r := range doc.Roles {
+		for _, p := range append(append([]string{}, r.Allow...), r.Deny...) {
+			add(p, "", false)
+		}
+	}
+	for _, u := range doc.Users {
+		for _, p := range append(append([]string{}, u.Allow...), u.Deny...) {
+			add(p, "", false)
+		}
+	}
+
+	declared := make(map[string]bool, len(doc.Permissions))
+	for _, p := range doc.Permissions {
+		declared[p.Name] = true
+	}
+
+	// Stale grants may reference permissions the document never mentions;
+	// index them all so they can be revoked by ID.
+	all, err := s.store.Permissions.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	perms := make(map[string]*models.Permission, len(all)+len(order))
+	for i := range all {
+		perms[all[i].Name] = &all[i]
+	}
+
+	for _, name := range order {
+		if name == "" {
+			return nil, fmt.Errorf("policy: permission without a name")
+		}
+
+		existing := perms[name]
+		if existing == nil {
+			perm := &models.Permission{Name: name, Description: descriptions[name]}
+			if err := log.apply(fmt.Sprintf("create permission %s", name), func() error {
+				return s.store.Permissions.Create(ctx, perm)
+			}); err != nil {
+				return nil, err
+			}
+			perms[name] = perm
+			continue
+		}
+
+		if declared[name] && existing.Description != descriptions[name] {
+			existing.Description = descriptions[name]
+			if err := log.apply(fmt.Sprintf("update permission %s", name), func() error {
+				return s.store.Permissions.Update(ctx, existing)
+			}); err != nil {
+				return nil, err
+			}
+		}
+		perms[name] = existing
+	}
+
+	return perms, nil
+}
+
+// syncRoles creates or updates every declared role and returns the declared
+// roles plus every stored role by name.
+func (s *syncer) syncRoles(ctx context.Context, doc *Document, log *changeLog) (map[string]*models.Role, error) {
+	stored, err := s.store.Roles.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]*models.Role, len(stored))
+	for i := range stored {
+		roles[stored[i].Name] = &stored[i]
+	}
+
+	for _, spec := range doc.Roles {
+		existing, ok := roles[spec.Name]
+		if !ok {
+			role := &models.Role{Name: spec.Name, Description: spec.Description, MFARequired: spec.MFARequired}
+			if err := log.apply(fmt.Sprintf("create role %s", spec.Name), func() error {
+				return s.store.Roles.Create(ctx, role)
+			}); err != nil {
+				return nil, err
+			}
+			roles[spec.Name] = role
+			continue
+		}
+
+		if existing.Description != spec.Description || existing.MFARequired != spec.MFARequired {
+			existing.Description = spec.Description
+			existing.MFARequired = spec.MFARequired
+			if err := log.apply(fmt.Sprintf("update role %s", spec.Name), func() error {
+				return s.store.Roles.Update(ctx, existing)
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return roles, nil
+}
+
+// syncInheritance removes stale parents before adding new ones so that a
+// reshuffled hierarchy never trips cycle detection half-way through.
+func (s *syncer) syncInheritance(ctx context.Context, doc *Document, roles map[string]*models.Role, log *changeLog) error {
+	edges, err := s.store.Roles.ListInheritance(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[uint]map[uint]bool)
+	for _, e := range edges {
+		if current[e.RoleID] == nil {
+			current[e.RoleID] = make(map[uint]bool)
+		}
+		current[e.RoleID][e.ParentID] = true
+	}
+
+	names := make(map[uint]string, len(roles))
+	for name, r := range roles {
+		names[r.ID] = name
+	}
+
+	type edge struct{ role, parent *models.Role }
+	var additions []edge
+
+	for _, spec := range doc.Roles {
+		role := roles[spec.Name]
+
+		want := make(map[uint]bool, len(spec.Inherits))
+		for _, parentName := range spec.Inherits {
+			parent := roles[parentName]
+			want[parent.ID] = true
+			if role.ID == 0 || parent.ID == 0 || !current[role.ID][parent.ID] {
+				additions = append(additions, edge{role, parent})
+			}
+		}
+
+		for parentID := range current[role.ID] {
+			if want[parentID] {
+				continue
+			}
+			roleID := role.ID
+			if err := log.apply(fmt.Sprintf("remove inheritance %s -> %s", spec.Name, names[parentID]), func() error {
+				return s.roles.RemoveParent(ctx, roleID, parentID)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, e := range additions {
+		if err := log.apply(fmt.Sprintf("add inheritance %s -> %s", e.role.Name, e.parent.Name), func() error {
+			return s.roles.AddParent(ctx, e.role.ID, e.parent.ID)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncGrants reconciles the current grants of one role or user with the
+// desired allow and deny lists.
+func syncGrants(
+	current []models.PermissionGrant,
+	allow, deny []string,
+	perms map[string]*models.Permission,
+	log *changeLog,
+	owner string,
+	grant func(permissionID uint, effect string) error,
+	revoke func(permissionID uint) error,
+) error {
+	want := make(map[string]string, len(allow)+len(deny))
+	for _, p := range allow {
+		want[p] = models.EffectAllow
+	}
+	for _, p := range deny {
+		want[p] = models.EffectDeny
+	}
+
+	have := make(map[string]string, len(current))
+	for _, g := range current {
+		have[g.Permission] = g.Effect
+	}
+
+	for _, name := range sortedKeys(want) {
+		effect := want[name]
+		if have[name] == effect {
+			continue
+		}
+		permissionID := perms[name].ID
+		if err := log.apply(fmt.Sprintf("%s: %s %s", owner, effect, name), func() error {
+			return grant(permissionID, effect)
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedKeys(have) {
+		if _, ok := want[name]; ok {
+			continue
+		}
+		perm, ok := perms[name]
+		if !ok {
+			continue
+		}
+		permissionID := perm.ID
+		if err := log.apply(fmt.Sprintf("%s: revoke %s", owner, name), func() error {
+			return revoke(permissionID)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) syncUsers(
+	ctx context.Context,
+	doc *Document,
+	users map[string]*models.User,
+	roles map[string]*models.Role,
+	perms map[string]*models.Permission,
+	log *changeLog,
+) error {
+	for _, spec := range doc.Users {
+		user := users[spec.Email]
+		owner := "user " + spec.Email
+
+		if err := s.syncUserRoles(ctx, user, spec, roles, log); err != nil {
+			return err
+		}
+
+		current, err := s.store.Permissions.FindUserGrants(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		err = syncGrants(current, spec.Allow, spec.Deny, perms, log, owner,
+			func(permissionID uint, effect string) error {
+				return s.store.Permissions.GrantToUser(ctx, user.ID, permissionID, effect)
+			},
+			func(permissionID uint) error {
+				return s.store.Permissions.RevokeFromUser(ctx, user.ID, permissionID)
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := s.syncAttributes(ctx, user, spec, log); err != nil {
+			return err
+		}
+	}
+
+	bindings := make(map[string][]BindingSpec)
+	for _, spec := range doc.Users {
+		bindings[spec.Email] = nil
+	}
+	for _, b := range doc.Bindings {
+		bindings[b.User] = append(bindings[b.User], b)
+	}
+
+	for _, email := range sortedKeys(bindings) {
+		if err := s.syncBindings(ctx, users[email], bindings[email], roles, log); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) syncUserRoles(ctx context.Context, user *models.User, spec UserSpec, roles map[string]*models.Role, log *changeLog) error {
+	current, err := s.store.Roles.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	have := make(map[string]bool, len(current))
+	for _, r := range current {
+		have[r.Name] = true
+	}
+	want := make(map[string]bool, len(spec.Roles))
+	for _, name := range spec.Roles {
+		want[name] = true
+	}
+
+	for _, name := range spec.Roles {
+		if have[name] {
+			continue
+		}
+		role := roles[name]
+		if err := log.apply(fmt.Sprintf("user %s: assign role %s", spec.Email, name), func() error {
+			return s.store.Roles.AssignToUser(ctx, user.ID, role.ID)
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range current {
+		if want[r.Name] {
+			continue
+		}
+		roleID := r.ID
+		if err := log.apply(fmt.Sprintf("user %s: unassign role %s", spec.Email, r.Name), func() error {
+			return s.store.Roles.RevokeFromUser(ctx, user.ID, roleID)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) syncAttributes(ctx context.Context, user *models.User, spec UserSpec, log *changeLog) error {
+	current, err := s.store.Attributes.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	have := make(map[string]string, len(current))
+	for _, a := range current {
+		have[a.Key] = a.Value
+	}
+
+	for _, key := range sortedKeys(spec.Attributes) {
+		value := spec.Attributes[key]
+		if v, ok := have[key]; ok && v == value {
+			continue
+		}
+		if err := log.apply(fmt.Sprintf("user %s: set attribute %s", spec.Email, key), func() error {
+			return s.store.Attributes.Set(ctx, user.ID, key, value)
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range sortedKeys(have) {
+		if _, ok := spec.Attributes[key]; ok {
+			continue
+		}
+		if err := log.apply(fmt.Sprintf("user %s: delete attribute %s", spec.Email, key), func() error {
+			return s.store.Attributes.Delete(ctx, user.ID, key)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) syncBindings(ctx context.Context, user *models.User, specs []BindingSpec, roles map[string]*models.Role, log *changeLog) error {
+	current, err := s.store.Bindings.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[uint]string, len(roles))
+	for name, r := range roles {
+		names[r.ID] = name
+	}
+
+	key := func(role, resourceType, resourceID string) string {
+		return role + " on " + resourceType + ":" + resourceID
+	}
+
+	have := make(map[string]models.RoleBinding, len(current))
+	for _, b := range current {
+		have[key(names[b.RoleID], b.ResourceType, b.ResourceID)] = b
+	}
+
+	want := make(map[string]bool, len(specs))
+	for _, b := range specs {
+		k := key(b.Role, b.ResourceType, b.ResourceID)
+		if want[k] {
+			continue
+		}
+		want[k] = true
+		if _, ok := have[k]; ok {
+			continue
+		}
+
+		binding := &models.RoleBinding{
+			UserID:       user.ID,
+			RoleID:       roles[b.Role].ID,
+			ResourceType: b.ResourceType,
+			ResourceID:   b.ResourceID,
+		}
+		if err := log.apply(fmt.Sprintf("user %s: bind %s", user.Email, k), func() error {
+			return s.store.Bindings.Create(ctx, binding)
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range sortedKeys(have) {
+		if want[k] {
+			continue
+		}
+		id := have[k].ID
+		if err := log.apply(fmt.Sprintf("user %s: unbind %s", user.Email, k), func() error {
+			return s.store.Bindings.Delete(ctx, id)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) syncPolicies(ctx context.Context, doc *Document, log *changeLog) error {
+	for _, p := range doc.Policies {
+		m, err := ToModel(p)
+		if err != nil {
+			return err
+		}
+
+		existing, err := s.store.Policies.FindByName(ctx, p.Name)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			stored, err := FromModel(*existing)
+			if err != nil {
+				return err
+			}
+			current, err := ToModel(stored)
+			if err != nil {
+				return err
+			}
+			if current.Definition == m.Definition {
+				continue
+			}
+		}
+
+		verb := "create"
+		if existing != nil {
+			verb = "update"
+		}
+		if err := log.apply(fmt.Sprintf("%s policy %s", verb, p.Name), func() error {
+			return s.store.Policies.Save(ctx, &m)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Export dumps the current access model. Sections are sorted so that two
+// exports of the same state are byte-for-byte identical.
+func (s *syncer) Export(ctx context.Context) (*Document, error) {
+	doc := &Document{}
+
+	perms, err := s.store.Permissions.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range perms {
+		doc.Permissions = append(doc.Permissions, PermissionSpec{Name: p.Name, Description: p.Description})
+	}
+
+	roles, err := s.store.Roles.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[uint]string, len(roles))
+	roleIDs := make([]uint, 0, len(roles))
+	for _, r := range roles {
+		names[r.ID] = r.Name
+		roleIDs = append(roleIDs, r.ID)
+	}
+
+	edges, err := s.store.Roles.ListInheritance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inherits := make(map[uint][]string)
+	for _, e := range edges {
+		inherits[e.RoleID] = append(inherits[e.RoleID], names[e.ParentID])
+	}
+
+	grants, err := s.store.Permissions.FindRoleGrants(ctx, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	allow, deny := splitGrants(grants, func(g models.PermissionGrant) uint { return g.RoleID })
+
+	for _, r := range roles {
+		spec := RoleSpec{
+			Name:        r.Name,
+			Description: r.Description,
+			MFARequired: r.MFARequired,
+			Inherits:    inherits[r.ID],
+			Allow:       allow[r.ID],
+			Deny:        deny[r.ID],
+		}
+		sort.Strings(spec.Inherits)
+		doc.Roles = append(doc.Roles, spec)
+	}
+
+	users, err := s.store.Users.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		spec, bindings, err := s.exportUser(ctx, u, names)
+		if err != nil {
+			return nil, err
+		}
+		if len(spec.Roles)+len(spec.Allow)+len(spec.Deny)+len(spec.Attributes) > 0 {
+			doc.Users = append(doc.Users, spec)
+		}
+		doc.Bindings = append(doc.Bindings, bindings...)
+	}
+
+	policies, err := LoadRepository(ctx, s.store.Policies)
+	if err != nil {
+		return nil, err
+	}
+	doc.Policies = policies
+
+	return doc, nil
+}
+
+func (s *syncer) exportUser(ctx context.Context, u models.User, roleNames map[uint]string) (UserSpec, []BindingSpec, error) {
+	spec := UserSpec{Email: u.Email}
+
+	roles, err := s.store.Roles.FindByUserId(ctx, u.ID)
+	if err != nil {
+		return spec, nil, err
+	}
+	for _, r := range roles {
+		spec.Roles = append(spec.Roles, r.Name)
+	}
+
+	grants, err := s.store.Permissions.FindUserGrants(ctx, u.ID)
+	if err != nil {
+		return spec, nil, err
+	}
+	allow, deny := splitGrants(grants, func(g models.PermissionGrant) uint { return g.UserID })
+	spec.Allow, spec.Deny = allow[u.ID], deny[u.ID]
+
+	attrs, err := s.store.Attributes.FindByUserId(ctx, u.ID)
+	if err != nil {
+		return spec, nil, err
+	}
+	for _, a := range attrs {
+		if spec.Attributes == nil {
+			spec.Attributes = make(map[string]string, len(attrs))
+		}
+		spec.Attributes[a.Key] = a.Value
+	}
+
+	stored, err := s.store.Bindings.FindByUserId(ctx, u.ID)
+	if err != nil {
+		return spec, nil, err
+	}
+	bindings := make([]BindingSpec, 0, len(stored))
+	for _, b := range stored {
+		bindings = append(bindings, BindingSpec{
+			User:         u.Email,
+			Role:         roleNames[b.RoleID],
+			ResourceType: b.ResourceType,
+			ResourceID:   b.ResourceID,
+		})
+	}
+
+	return spec, bindings, nil
+}
+
+func splitGrants(grants []models.PermissionGrant, owner func(models.PermissionGrant) uint) (allow, deny map[uint][]string) {
+	allow = make(map[uint][]string)
+	deny = make(map[uint][]string)
+
+	for _, g := range grants {
+		if g.Effect != models.EffectAllow {
+			deny[owner(g)] = append(deny[owner(g)], g.Permission)
+		} else {
+			allow[owner(g)] = append(allow[owner(g)], g.Permission)
+		}
+	}
+
+	return allow, deny
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
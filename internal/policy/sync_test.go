@@ -0,0 +1,293 @@
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/policy"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const accessYAML = `
+permissions:
+  - name: invoices:read
+    description: Read invoices
+roles:
+  - name: viewer
+    allow: [invoices:read]
+  - name: editor
+    mfa_required: true
+    inherits: [viewer]
+    allow: ["invoices:*"]
+    deny: [invoices:delete]
+users:
+  - email: alice@example.com
+    roles: [editor]
+    deny: [reports:export]
+    attributes:
+      department: finance
+bindings:
+  - user: bob@example.com
+    role: editor
+    resource_type: project
+    resource_id: "42"
+policies:
+  - name: managers-approve-expenses
+    effect: allow
+    actions: ["expenses:approve"]
+    conditions:
+      - {attribute: resource.amount, operator: lt, value: 10000}
+`
+
+func newSyncer(t *testing.T) (policy.Syncer, policy.Store) {
+	syncer, store, _ := newSyncerDB(t)
+	return syncer, store
+}
+
+func newSyncerDB(t *testing.T) (policy.Syncer, policy.Store, *gorm.DB) {
+	t.Helper()
+
+	db, err := repository.Connect(filepath.Join(t.TempDir(), "sentinel.db"))
+	require.NoError(t, err)
+	require.NoError(t, repository.Migrate(db))
+
+	store := policy.NewStore(db)
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		require.NoError(t, store.Users.Create(context.Background(), &models.User{Email: email, Password: "x"}))
+	}
+
+	return policy.NewSyncer(db, policy.NewStore), store, db
+}
+
+func decode(t *testing.T, data string) *policy.Document {
+	t.Helper()
+
+	doc, err := policy.Decode([]byte(data), policy.FormatYAML)
+	require.NoError(t, err)
+	return doc
+}
+
+func TestSyncer_ImportIsIdempotent(t *testing.T) {
+	syncer, store := newSyncer(t)
+	ctx := context.Background()
+
+	changes, err := syncer.Import(ctx, decode(t, accessYAML), false)
+	require.NoError(t, err)
+	require.NotEmpty(t, changes)
+
+	changes, err = syncer.Import(ctx, decode(t, accessYAML), false)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	editor, err := store.Roles.FindByName(ctx, "editor")
+	require.NoError(t, err)
+	grants, err := store.Permissions.FindRoleGrants(ctx, []uint{editor.ID})
+	require.NoError(t, err)
+	require.Len(t, grants, 2)
+}
+
+func TestSyncer_ImportReconcilesRemovals(t *testing.T) {
+	syncer, store := newSyncer(t)
+	ctx := context.Background()
+
+	_, err := syncer.Import(ctx, decode(t, accessYAML), false)
+	require.NoError(t, err)
+
+	changes, err := syncer.Import(ctx, decode(t, `
+roles:
+  - name: editor
+    allow: ["invoices:*"]
+users:
+  - email: alice@example.com
+`), false)
+	require.NoError(t, err)
+	require.Contains(t, changes, "remove inheritance editor -> viewer")
+	require.Contains(t, changes, "role editor: revoke invoices:delete")
+	require.Contains(t, changes, "user alice@example.com: unassign role editor")
+	require.Contains(t, changes, "user alice@example.com: delete attribute department")
+
+	alice, err := store.Users.FindByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	roles, err := store.Roles.FindByUserId(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Empty(t, roles)
+
+	// viewer is not declared, so it is left untouched
+	viewer, err := store.Roles.FindByName(ctx, "viewer")
+	require.NoError(t, err)
+	require.NotNil(t, viewer)
+}
+
+func TestSyncer_ImportBindingsOnlyUser(t *testing.T) {
+	syncer, store := newSyncer(t)
+	ctx := context.Background()
+
+	_, err := syncer.Import(ctx, decode(t, `
+roles:
+  - name: viewer
+    allow: [invoices:read]
+users:
+  - email: bob@example.com
+    roles: [viewer]
+    allow: [invoices:read]
+    attributes:
+      department: sales
+bindings:
+  - user: bob@example.com
+    role: viewer
+    resource_type: project
+    resource_id: "1"
+`), false)
+	require.NoError(t, err)
+
+	// bob is now only mentioned under bindings: their bindings are
+	// reconciled, their roles, grants and attributes are not.
+	changes, err := syncer.Import(ctx, decode(t, `
+roles:
+  - name: viewer
+    allow: [invoices:read]
+bindings:
+  - user: bob@example.com
+    role: viewer
+    resource_type: project
+    resource_id: "2"
+`), false)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	for _, change := range changes {
+		require.Contains(t, change, "bob@example.com: ")
+		require.Regexp(t, `(bind|unbind) viewer on project:[12]$`, change)
+	}
+
+	bob, err := store.Users.FindByEmail(ctx, "bob@example.com")
+	require.NoError(t, err)
+
+	roles, err := store.Roles.FindByUserId(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+
+	grants, err := store.Permissions.FindUserGrants(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+
+	attrs, err := store.Attributes.FindByUserId(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, attrs, 1)
+
+	bindings, err := store.Bindings.FindByUserId(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	require.Equal(t, "2", bindings[0].ResourceID)
+}
+
+func TestSyncer_DryRunWritesNothing(t *testing.T) {
+	syncer, store := newSyncer(t)
+	ctx := context.Background()
+
+	changes, err := syncer.Import(ctx, decode(t, accessYAML), true)
+	require.NoError(t, err)
+	require.Contains(t, changes, "create role editor")
+
+	roles, err := store.Roles.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, roles)
+}
+
+func TestSyncer_FailedImportWritesNothing(t *testing.T) {
+	syncer, store, db := newSyncerDB(t)
+	ctx := context.Background()
+
+	// Policies are written last, after permissions, roles and grants.
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("fail_policies", func(tx *gorm.DB) {
+		if tx.Statement.Table == "policies" {
+			_ = tx.AddError(errors.New("disk full"))
+		}
+	}))
+
+	changes, err := syncer.Import(ctx, decode(t, accessYAML), false)
+	require.ErrorContains(t, err, "disk full")
+	require.Empty(t, changes)
+
+	roles, err := store.Roles.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, roles)
+
+	perms, err := store.Permissions.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, perms)
+}
+
+func TestSyncer_ImportRejectsCycles(t *testing.T) {
+	syncer, store := newSyncer(t)
+	ctx := context.Background()
+
+	_, err := syncer.Import(ctx, decode(t, `
+roles:
+  - name: a
+    inherits: [b]
+  - name: b
+    inherits: [a]
+`), false)
+	require.ErrorIs(t, err, appErr.ErrRoleCycle)
+
+	roles, err := store.Roles.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, roles)
+}
+
+func TestSyncer_ImportRejectsUnknownReferences(t *testing.T) {
+	syncer, _ := newSyncer(t)
+	ctx := context.Background()
+
+	_, err := syncer.Import(ctx, decode(t, `
+users:
+  - email: nobody@example.com
+`), false)
+	require.ErrorContains(t, err, "unknown user")
+
+	_, err = syncer.Import(ctx, decode(t, `
+users:
+  - email: alice@example.com
+    roles: [ghost]
+`), false)
+	require.ErrorContains(t, err, "unknown role")
+}
+
+func TestSyncer_ExportRoundTrip(t *testing.T) {
+	syncer, _ := newSyncer(t)
+	ctx := context.Background()
+
+	_, err := syncer.Import(ctx, decode(t, accessYAML), false)
+	require.NoError(t, err)
+
+	doc, err := syncer.Export(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, doc.Permissions, 4)
+	require.Len(t, doc.Roles, 2)
+	for _, r := range doc.Roles {
+		require.Equal(t, r.Name == "editor", r.MFARequired, r.Name)
+	}
+	require.Len(t, doc.Users, 1)
+	require.Equal(t, map[string]string{"department": "finance"}, doc.Users[0].Attributes)
+	require.Equal(t, []policy.BindingSpec{{User: "bob@example.com", Role: "editor", ResourceType: "project", ResourceID: "42"}}, doc.Bindings)
+	require.Len(t, doc.Policies, 1)
+
+	for _, format := range []string{policy.FormatYAML, policy.FormatJSON} {
+		data, err := policy.Encode(doc, format)
+		require.NoError(t, err)
+
+		decoded, err := policy.Decode(data, format)
+		require.NoError(t, err)
+
+		changes, err := syncer.Import(ctx, decoded, false)
+		require.NoError(t, err)
+		require.Empty(t, changes, format)
+	}
+}
@@ -34,5 +34,22 @@ func Connect(dsn string) (*gorm.DB, error) {
 func Migrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.User{},
+		&models.Role{},
+		&models.Permission{},
+		&models.UserRole{},
+		&models.RolePermission{},
+		&models.UserPermission{},
+		&models.RoleInheritance{},
+		&models.RoleBinding{},
+		&models.UserAttribute{},
+		&models.Policy{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.Session{},
+		&models.TOTPEnrollment{},
+		&models.RecoveryCode{},
+		&models.MFAChallenge{},
+		&models.MFALockout{},
+		&models.PasswordReset{},
 	)
 }
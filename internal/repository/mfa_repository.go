@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MFARepository interface {
+	FindTOTP(ctx context.Context, userID uint) (*models.TOTPEnrollment, error)
+	// SaveTOTP stores a pending enrollment, replacing any earlier one.
+	SaveTOTP(ctx context.Context, enrollment *models.TOTPEnrollment) error
+	// ConfirmTOTP confirms the user's enrollment at step, enables MFA on the
+	// user and replaces their recovery codes, all in one transaction.
+	ConfirmTOTP(ctx context.Context, userID uint, step int64, at time.Time, codeHashes []string) error
+	// UseStep records step as the last accepted one if it is later than the
+	// current one and reports whether it did, so concurrent logins cannot
+	// both use the same code.
+	UseStep(ctx context.Context, userID uint, step int64) (bool, error)
+	// UseRecoveryCode marks an unused recovery code of the user as used and
+	// reports whether it did.
+	UseRecoveryCode(ctx context.Context, userID uint, hash string, at time.Time) (bool, error)
+	CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error
+	FindChallenge(ctx context.Context, hash string) (*models.MFAChallenge, error)
+	// FailChallenge counts a wrong code against the challenge.
+	FailChallenge(ctx context.Context, id uint) error
+	// UseChallenge marks an unused challenge as used and reports whether it
+	// did.
+	UseChallenge(ctx context.Context, id uint, at time.Time) (bool, error)
+	FindLockout(ctx context.Context, userID uint) (*models.MFALockout, error)
+	// FailMFA counts a wrong code against the user. The max-th failure locks
+	// the user until lockUntil and starts the count over.
+	FailMFA(ctx context.Context, userID uint, max int, lockUntil time.Time) error
+	// ClearMFAFailures forgets the user's wrong codes.
+	ClearMFAFailures(ctx context.Context, userID uint) error
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) FindTOTP(ctx context.Context, userID uint) (*models.TOTPEnrollment, error) {
+	var enrollment models.TOTPEnrollment
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		First(&enrollment).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &enrollment, err
+}
+
+func (r *mfaRepository) SaveTOTP(ctx context.Context, enrollment *models.TOTPEnrollment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().
+			Where("user_id = ?", enrollment.UserID).
+			Delete(&models.TOTPEnrollment{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(enrollment).Error
+	})
+}
+
+func (r *mfaRepository) ConfirmTOTP(ctx context.Context, userID uint, step int64, at time.Time, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.TOTPEnrollment{}).
+			Where("user_id = ?", userID).
+			Updates(map[string]any{"confirmed_at": at, "last_step": step}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Update("mfa_enabled", true).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).
+			Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]models.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, models.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *mfaRepository) UseStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.TOTPEnrollment{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID uint, hash string, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *mfaRepository) CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error {
+	return r.db.WithContext(ctx).Create(challenge).Error
+}
+
+func (r *mfaRepository) FindChallenge(ctx context.Context, hash string) (*models.MFAChallenge, error) {
+	var challenge models.MFAChallenge
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ?", hash).
+		First(&challenge).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &challenge, err
+}
+
+func (r *mfaRepository) FailChallenge(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).
+		Model(&models.MFAChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+func (r *mfaRepository) UseChallenge(ctx context.Context, id uint, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *mfaRepository) FindLockout(ctx context.Context, userID uint) (*models.MFALockout, error) {
+	var lockout models.MFALockout
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		First(&lockout).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &lockout, err
+}
+
+func (r *mfaRepository) FailMFA(ctx context.Context, userID uint, max int, lockUntil time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"failures":   gorm.Expr("failures + 1"),
+				"updated_at": gorm.Expr("excluded.updated_at"),
+			}),
+		}).Create(&models.MFALockout{UserID: userID, Failures: 1}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.MFALockout{}).
+			Where("user_id = ? AND failures >= ?", userID, max).
+			Updates(map[string]any{"failures": 0, "locked_until": lockUntil}).Error
+	})
+}
+
+func (r *mfaRepository) ClearMFAFailures(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&models.MFALockout{}).Error
+}
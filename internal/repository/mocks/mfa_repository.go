@@ -0,0 +1,82 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MFARepositoryMock struct {
+	mock.Mock
+}
+
+func (m *MFARepositoryMock) FindTOTP(ctx context.Context, userID uint) (*models.TOTPEnrollment, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TOTPEnrollment), args.Error(1)
+}
+
+func (m *MFARepositoryMock) SaveTOTP(ctx context.Context, enrollment *models.TOTPEnrollment) error {
+	args := m.Called(ctx, enrollment)
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) ConfirmTOTP(ctx context.Context, userID uint, step int64, at time.Time, codeHashes []string) error {
+	args := m.Called(ctx, userID, step, at, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) UseStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MFARepositoryMock) UseRecoveryCode(ctx context.Context, userID uint, hash string, at time.Time) (bool, error) {
+	args := m.Called(ctx, userID, hash, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MFARepositoryMock) CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error {
+	args := m.Called(ctx, challenge)
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) FindChallenge(ctx context.Context, hash string) (*models.MFAChallenge, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFAChallenge), args.Error(1)
+}
+
+func (m *MFARepositoryMock) FailChallenge(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) UseChallenge(ctx context.Context, id uint, at time.Time) (bool, error) {
+	args := m.Called(ctx, id, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MFARepositoryMock) FindLockout(ctx context.Context, userID uint) (*models.MFALockout, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFALockout), args.Error(1)
+}
+
+func (m *MFARepositoryMock) FailMFA(ctx context.Context, userID uint, max int, lockUntil time.Time) error {
+	args := m.Called(ctx, userID, max, lockUntil)
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) ClearMFAFailures(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type PasswordResetRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *PasswordResetRepositoryMock) Create(ctx context.Context, reset *models.PasswordReset) error {
+	args := m.Called(ctx, reset)
+	return args.Error(0)
+}
+
+func (m *PasswordResetRepositoryMock) FindByHash(ctx context.Context, hash string) (*models.PasswordReset, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PasswordReset), args.Error(1)
+}
+
+func (m *PasswordResetRepositoryMock) ResetPassword(ctx context.Context, id, userID uint, passwordHash string, at time.Time) (bool, error) {
+	args := m.Called(ctx, id, userID, passwordHash, at)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,82 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type PermissionRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *PermissionRepositoryMock) Create(ctx context.Context, permission *models.Permission) error {
+	args := m.Called(ctx, permission)
+	return args.Error(0)
+}
+
+func (m *PermissionRepositoryMock) Update(ctx context.Context, permission *models.Permission) error {
+	args := m.Called(ctx, permission)
+	return args.Error(0)
+}
+
+func (m *PermissionRepositoryMock) FindByName(ctx context.Context, name string) (*models.Permission, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Permission), args.Error(1)
+}
+
+func (m *PermissionRepositoryMock) FindById(ctx context.Context, id uint) (*models.Permission, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Permission), args.Error(1)
+}
+
+func (m *PermissionRepositoryMock) List(ctx context.Context) ([]models.Permission, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Permission), args.Error(1)
+}
+
+func (m *PermissionRepositoryMock) GrantToRole(ctx context.Context, roleID, permissionID uint, effect string) error {
+	args := m.Called(ctx, roleID, permissionID, effect)
+	return args.Error(0)
+}
+
+func (m *PermissionRepositoryMock) RevokeFromRole(ctx context.Context, roleID, permissionID uint) error {
+	args := m.Called(ctx, roleID, permissionID)
+	return args.Error(0)
+}
+
+func (m *PermissionRepositoryMock) GrantToUser(ctx context.Context, userID, permissionID uint, effect string) error {
+	args := m.Called(ctx, userID, permissionID, effect)
+	return args.Error(0)
+}
+
+func (m *PermissionRepositoryMock) RevokeFromUser(ctx context.Context, userID, permissionID uint) error {
+	args := m.Called(ctx, userID, permissionID)
+	return args.Error(0)
+}
+
+func (m *PermissionRepositoryMock) FindRoleGrants(ctx context.Context, roleIDs []uint) ([]models.PermissionGrant, error) {
+	args := m.Called(ctx, roleIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PermissionGrant), args.Error(1)
+}
+
+func (m *PermissionRepositoryMock) FindUserGrants(ctx context.Context, userID uint) ([]models.PermissionGrant, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PermissionGrant), args.Error(1)
+}
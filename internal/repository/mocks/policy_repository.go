@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type PolicyRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *PolicyRepositoryMock) Save(ctx context.Context, policy *models.Policy) error {
+	args := m.Called(ctx, policy)
+	return args.Error(0)
+}
+
+func (m *PolicyRepositoryMock) FindByName(ctx context.Context, name string) (*models.Policy, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Policy), args.Error(1)
+}
+
+func (m *PolicyRepositoryMock) List(ctx context.Context) ([]models.Policy, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Policy), args.Error(1)
+}
+
+func (m *PolicyRepositoryMock) Delete(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
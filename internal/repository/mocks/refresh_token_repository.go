@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RefreshTokenRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RefreshTokenRepositoryMock) Create(ctx context.Context, token *models.RefreshToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *RefreshTokenRepositoryMock) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *RefreshTokenRepositoryMock) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	args := m.Called(ctx, id, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *RefreshTokenRepositoryMock) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	args := m.Called(ctx, familyID, at)
+	return args.Error(0)
+}
+
+func (m *RefreshTokenRepositoryMock) RevokeUser(ctx context.Context, userID uint, at time.Time) error {
+	args := m.Called(ctx, userID, at)
+	return args.Error(0)
+}
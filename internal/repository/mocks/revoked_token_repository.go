@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RevokedTokenRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RevokedTokenRepositoryMock) Create(ctx context.Context, token *models.RevokedToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *RevokedTokenRepositoryMock) FindActive(ctx context.Context, now time.Time) ([]models.RevokedToken, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RevokedToken), args.Error(1)
+}
+
+func (m *RevokedTokenRepositoryMock) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RoleBindingRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RoleBindingRepositoryMock) Create(ctx context.Context, binding *models.RoleBinding) error {
+	args := m.Called(ctx, binding)
+	return args.Error(0)
+}
+
+func (m *RoleBindingRepositoryMock) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *RoleBindingRepositoryMock) FindByUserId(ctx context.Context, userID uint) ([]models.RoleBinding, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RoleBinding), args.Error(1)
+}
+
+func (m *RoleBindingRepositoryMock) FindRolesForResource(ctx context.Context, userID uint, resourceType, resourceID string) ([]models.Role, error) {
+	args := m.Called(ctx, userID, resourceType, resourceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Role), args.Error(1)
+}
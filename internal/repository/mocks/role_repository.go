@@ -0,0 +1,90 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RoleRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RoleRepositoryMock) Create(ctx context.Context, role *models.Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *RoleRepositoryMock) Update(ctx context.Context, role *models.Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *RoleRepositoryMock) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *RoleRepositoryMock) FindById(ctx context.Context, id uint) (*models.Role, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *RoleRepositoryMock) FindByIds(ctx context.Context, ids []uint) ([]models.Role, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *RoleRepositoryMock) List(ctx context.Context) ([]models.Role, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *RoleRepositoryMock) AssignToUser(ctx context.Context, userID, roleID uint) error {
+	args := m.Called(ctx, userID, roleID)
+	return args.Error(0)
+}
+
+func (m *RoleRepositoryMock) RevokeFromUser(ctx context.Context, userID, roleID uint) error {
+	args := m.Called(ctx, userID, roleID)
+	return args.Error(0)
+}
+
+func (m *RoleRepositoryMock) FindByUserId(ctx context.Context, userID uint) ([]models.Role, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *RoleRepositoryMock) AddParent(ctx context.Context, roleID, parentID uint) error {
+	args := m.Called(ctx, roleID, parentID)
+	return args.Error(0)
+}
+
+func (m *RoleRepositoryMock) RemoveParent(ctx context.Context, roleID, parentID uint) error {
+	args := m.Called(ctx, roleID, parentID)
+	return args.Error(0)
+}
+
+func (m *RoleRepositoryMock) ListInheritance(ctx context.Context) ([]models.RoleInheritance, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RoleInheritance), args.Error(1)
+}
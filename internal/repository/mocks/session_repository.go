@@ -0,0 +1,72 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *SessionRepositoryMock) Create(ctx context.Context, session *models.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) FindByID(ctx context.Context, id uint) (*models.Session, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *SessionRepositoryMock) FindByFamily(ctx context.Context, familyID string) (*models.Session, error) {
+	args := m.Called(ctx, familyID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *SessionRepositoryMock) FindActiveByUser(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) {
+	args := m.Called(ctx, userID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *SessionRepositoryMock) Touch(ctx context.Context, id uint, at, expiresAt time.Time) error {
+	args := m.Called(ctx, id, at, expiresAt)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) MarkUsed(ctx context.Context, id uint, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) Reauthenticate(ctx context.Context, id uint, at time.Time, methods string) error {
+	args := m.Called(ctx, id, at, methods)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) Revoke(ctx context.Context, id uint, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	args := m.Called(ctx, familyID, at)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) RevokeUser(ctx context.Context, userID uint, at time.Time) error {
+	args := m.Called(ctx, userID, at)
+	return args.Error(0)
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type UserAttributeRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *UserAttributeRepositoryMock) Set(ctx context.Context, userID uint, key, value string) error {
+	args := m.Called(ctx, userID, key, value)
+	return args.Error(0)
+}
+
+func (m *UserAttributeRepositoryMock) Delete(ctx context.Context, userID uint, key string) error {
+	args := m.Called(ctx, userID, key)
+	return args.Error(0)
+}
+
+func (m *UserAttributeRepositoryMock) FindByUserId(ctx context.Context, userID uint) ([]models.UserAttribute, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UserAttribute), args.Error(1)
+}
@@ -31,3 +31,16 @@ func (m *UserRepositoryMock) FindById(ctx context.Context, id uint) (*models.Use
 	}
 	return args.Get(0).(*models.User), args.Error(1)
 }
+
+func (m *UserRepositoryMock) List(ctx context.Context) ([]models.User, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	args := m.Called(ctx, id, hash)
+	return args.Error(0)
+}
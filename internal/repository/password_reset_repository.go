@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	// Create stores a reset token, discarding the user's earlier unused ones
+	// so only the latest email works.
+	Create(ctx context.Context, reset *models.PasswordReset) error
+	FindByHash(ctx context.Context, hash string) (*models.PasswordReset, error)
+	// ResetPassword marks an unused reset token as used and sets the user's
+	// password hash in one transaction. It reports whether the token was
+	// still unused; if not, the password is left unchanged.
+	ResetPassword(ctx context.Context, id, userID uint, passwordHash string, at time.Time) (bool, error)
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Delete(&models.PasswordReset{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+func (r *passwordResetRepository) FindByHash(ctx context.Context, hash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ?", hash).
+		First(&reset).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &reset, err
+}
+
+func (r *passwordResetRepository) ResetPassword(ctx context.Context, id, userID uint, passwordHash string, at time.Time) (bool, error) {
+	used := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND user_id = ? AND used_at IS NULL", id, userID).
+			Update("used_at", at)
+		if res.Error != nil || res.RowsAffected != 1 {
+			return res.Error
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Update("password", passwordHash).Error; err != nil {
+			return err
+		}
+
+		used = true
+		return nil
+	})
+
+	return used, err
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PermissionRepository interface {
+	Create(ctx context.Context, permission *models.Permission) error
+	Update(ctx context.Context, permission *models.Permission) error
+	FindByName(ctx context.Context, name string) (*models.Permission, error)
+	FindById(ctx context.Context, id uint) (*models.Permission, error)
+	List(ctx context.Context) ([]models.Permission, error)
+	GrantToRole(ctx context.Context, roleID, permissionID uint, effect string) error
+	RevokeFromRole(ctx context.Context, roleID, permissionID uint) error
+	GrantToUser(ctx context.Context, userID, permissionID uint, effect string) error
+	RevokeFromUser(ctx context.Context, userID, permissionID uint) error
+	FindRoleGrants(ctx context.Context, roleIDs []uint) ([]models.PermissionGrant, error)
+	FindUserGrants(ctx context.Context, userID uint) ([]models.PermissionGrant, error)
+}
+
+type permissionRepository struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) PermissionRepository {
+	return &permissionRepository{db: db}
+}
+
+func (r *permissionRepository) Create(ctx context.Context, permission *models.Permission) error {
+	return r.db.WithContext(ctx).Create(permission).Error
+}
+
+func (r *permissionRepository) Update(ctx context.Context, permission *models.Permission) error {
+	return r.db.WithContext(ctx).Save(permission).Error
+}
+
+func (r *permissionRepository) FindByName(ctx context.Context, name string) (*models.Permission, error) {
+	var permission models.Permission
+	err := r.db.WithContext(ctx).
+		Where("name = ?", name).
+		First(&permission).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &permission, err
+}
+
+func (r *permissionRepository) FindById(ctx context.Context, id uint) (*models.Permission, error) {
+	var permission models.Permission
+	err := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		First(&permission).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &permission, err
+}
+
+func (r *permissionRepository) List(ctx context.Context) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := r.db.WithContext(ctx).
+		Order("name").
+		Find(&permissions).Error
+
+	return permissions, err
+}
+
+func (r *permissionRepository) GrantToRole(ctx context.Context, roleID, permissionID uint, effect string) error {
+	if !validEffect(effect) {
+		return appErr.ErrInvalidInput
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "role_id"}, {Name: "permission_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"effect"}),
+		}).
+		Create(&models.RolePermission{RoleID: roleID, PermissionID: permissionID, Effect: effect}).Error
+}
+
+func (r *permissionRepository) RevokeFromRole(ctx context.Context, roleID, permissionID uint) error {
+	return r.db.WithContext(ctx).
+		Where("role_id = ? AND permission_id = ?", roleID, permissionID).
+		Delete(&models.RolePermission{}).Error
+}
+
+func (r *permissionRepository) GrantToUser(ctx context.Context, userID, permissionID uint, effect string) error {
+	if !validEffect(effect) {
+		return appErr.ErrInvalidInput
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "permission_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"effect"}),
+		}).
+		Create(&models.UserPermission{UserID: userID, PermissionID: permissionID, Effect: effect}).Error
+}
+
+func (r *permissionRepository) RevokeFromUser(ctx context.Context, userID, permissionID uint) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND permission_id = ?", userID, permissionID).
+		Delete(&models.UserPermission{}).Error
+}
+
+func (r *permissionRepository) FindRoleGrants(ctx context.Context, roleIDs []uint) ([]models.PermissionGrant, error) {
+	var grants []models.PermissionGrant
+	if len(roleIDs) == 0 {
+		return grants, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Table("role_permissions").
+		Select("permissions.name AS permission, role_permissions.effect AS effect, roles.id AS role_id, roles.name AS role_name").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id AND permissions.deleted_at IS NULL").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id AND roles.deleted_at IS NULL").
+		Where("role_permissions.role_id IN ?", roleIDs).
+		Order("permissions.name").
+		Scan(&grants).Error
+
+	return grants, err
+}
+
+func (r *permissionRepository) FindUserGrants(ctx context.Context, userID uint) ([]models.PermissionGrant, error) {
+	var grants []models.PermissionGrant
+	err := r.db.WithContext(ctx).
+		Table("user_permissions").
+		Select("permissions.name AS permission, user_permissions.effect AS effect, user_permissions.user_id AS user_id").
+		Joins("JOIN permissions ON permissions.id = user_permissions.permission_id AND permissions.deleted_at IS NULL").
+		Where("user_permissions.user_id = ?", userID).
+		Order("permissions.name").
+		Scan(&grants).Error
+
+	return grants, err
+}
+
+func validEffect(effect string) bool {
+	return effect == models.EffectAllow || effect == models.EffectDeny
+}
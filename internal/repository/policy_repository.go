@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PolicyRepository interface {
+	Save(ctx context.Context, policy *models.Policy) error
+	FindByName(ctx context.Context, name string) (*models.Policy, error)
+	List(ctx context.Context) ([]models.Policy, error)
+	Delete(ctx context.Context, name string) error
+}
+
+type policyRepository struct {
+	db *gorm.DB
+}
+
+func NewPolicyRepository(db *gorm.DB) PolicyRepository {
+	return &policyRepository{db: db}
+}
+
+// Save creates the policy or replaces the definition of the policy with the
+// same name.
+func (r *policyRepository) Save(ctx context.Context, policy *models.Policy) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"definition", "updated_at"}),
+		}).
+		Create(policy).Error
+}
+
+func (r *policyRepository) FindByName(ctx context.Context, name string) (*models.Policy, error) {
+	var policy models.Policy
+	err := r.db.WithContext(ctx).
+		Where("name = ?", name).
+		First(&policy).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &policy, err
+}
+
+func (r *policyRepository) List(ctx context.Context) ([]models.Policy, error) {
+	var policies []models.Policy
+	err := r.db.WithContext(ctx).
+		Order("name").
+		Find(&policies).Error
+
+	return policies, err
+}
+
+func (r *policyRepository) Delete(ctx context.Context, name string) error {
+	return r.db.WithContext(ctx).
+		Unscoped().
+		Where("name = ?", name).
+		Delete(&models.Policy{}).Error
+}
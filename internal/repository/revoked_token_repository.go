@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"gorm.io/gorm"
+)
+
+type RevokedTokenRepository interface {
+	Create(ctx context.Context, token *models.RevokedToken) error
+	FindActive(ctx context.Context, now time.Time) ([]models.RevokedToken, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{db: db}
+}
+
+func (r *revokedTokenRepository) Create(ctx context.Context, token *models.RevokedToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *revokedTokenRepository) FindActive(ctx context.Context, now time.Time) ([]models.RevokedToken, error) {
+	var tokens []models.RevokedToken
+	err := r.db.WithContext(ctx).
+		Where("expires_at > ?", now).
+		Find(&tokens).Error
+
+	return tokens, err
+}
+
+func (r *revokedTokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("expires_at <= ?", now).
+		Delete(&models.RevokedToken{})
+
+	return res.RowsAffected, res.Error
+}
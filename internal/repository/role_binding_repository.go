@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"gorm.io/gorm"
+)
+
+type RoleBindingRepository interface {
+	Create(ctx context.Context, binding *models.RoleBinding) error
+	Delete(ctx context.Context, id uint) error
+	FindByUserId(ctx context.Context, userID uint) ([]models.RoleBinding, error)
+	FindRolesForResource(ctx context.Context, userID uint, resourceType, resourceID string) ([]models.Role, error)
+}
+
+type roleBindingRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleBindingRepository(db *gorm.DB) RoleBindingRepository {
+	return &roleBindingRepository{db: db}
+}
+
+func (r *roleBindingRepository) Create(ctx context.Context, binding *models.RoleBinding) error {
+	return r.db.WithContext(ctx).Create(binding).Error
+}
+
+func (r *roleBindingRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.RoleBinding{}, id).Error
+}
+
+func (r *roleBindingRepository) FindByUserId(ctx context.Context, userID uint) ([]models.RoleBinding, error) {
+	var bindings []models.RoleBinding
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("resource_type, resource_id").
+		Find(&bindings).Error
+
+	return bindings, err
+}
+
+func (r *roleBindingRepository) FindRolesForResource(ctx context.Context, userID uint, resourceType, resourceID string) ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.WithContext(ctx).
+		Joins("JOIN role_bindings ON role_bindings.role_id = roles.id AND role_bindings.deleted_at IS NULL").
+		Where("role_bindings.user_id = ? AND role_bindings.resource_type = ? AND role_bindings.resource_id = ?", userID, resourceType, resourceID).
+		Order("roles.name").
+		Find(&roles).Error
+
+	return roles, err
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	Create(ctx context.Context, role *models.Role) error
+	Update(ctx context.Context, role *models.Role) error
+	FindByName(ctx context.Context, name string) (*models.Role, error)
+	FindById(ctx context.Context, id uint) (*models.Role, error)
+	FindByIds(ctx context.Context, ids []uint) ([]models.Role, error)
+	List(ctx context.Context) ([]models.Role, error)
+	AssignToUser(ctx context.Context, userID, roleID uint) error
+	RevokeFromUser(ctx context.Context, userID, roleID uint) error
+	FindByUserId(ctx context.Context, userID uint) ([]models.Role, error)
+	AddParent(ctx context.Context, roleID, parentID uint) error
+	RemoveParent(ctx context.Context, roleID, parentID uint) error
+	ListInheritance(ctx context.Context) ([]models.RoleInheritance, error)
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).Create(role).Error
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).Save(role).Error
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	err := r.db.WithContext(ctx).
+		Where("name = ?", name).
+		First(&role).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &role, err
+}
+
+func (r *roleRepository) FindById(ctx context.Context, id uint) (*models.Role, error) {
+	var role models.Role
+	err := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		First(&role).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &role, err
+}
+
+func (r *roleRepository) FindByIds(ctx context.Context, ids []uint) ([]models.Role, error) {
+	var roles []models.Role
+	if len(ids) == 0 {
+		return roles, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("id IN ?", ids).
+		Order("name").
+		Find(&roles).Error
+
+	return roles, err
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.WithContext(ctx).
+		Order("name").
+		Find(&roles).Error
+
+	return roles, err
+}
+
+func (r *roleRepository) AssignToUser(ctx context.Context, userID, roleID uint) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.UserRole{UserID: userID, RoleID: roleID}).Error
+}
+
+func (r *roleRepository) RevokeFromUser(ctx context.Context, userID, roleID uint) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND role_id = ?", userID, roleID).
+		Delete(&models.UserRole{}).Error
+}
+
+func (r *roleRepository) FindByUserId(ctx context.Context, userID uint) ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Find(&roles).Error
+
+	return roles, err
+}
+
+func (r *roleRepository) AddParent(ctx context.Context, roleID, parentID uint) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RoleInheritance{RoleID: roleID, ParentID: parentID}).Error
+}
+
+func (r *roleRepository) RemoveParent(ctx context.Context, roleID, parentID uint) error {
+	return r.db.WithContext(ctx).
+		Where("role_id = ? AND parent_id = ?", roleID, parentID).
+		Delete(&models.RoleInheritance{}).Error
+}
+
+func (r *roleRepository) ListInheritance(ctx context.Context) ([]models.RoleInheritance, error) {
+	var edges []models.RoleInheritance
+	err := r.db.WithContext(ctx).Find(&edges).Error
+	return edges, err
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, id uint) (*models.Session, error)
+	FindByFamily(ctx context.Context, familyID string) (*models.Session, error)
+	// FindActiveByUser returns the user's unrevoked sessions that have not
+	// expired at now, most recently used first.
+	FindActiveByUser(ctx context.Context, userID uint, now time.Time) ([]models.Session, error)
+	// Touch records a refresh of the session's tokens.
+	Touch(ctx context.Context, id uint, at, expiresAt time.Time) error
+	// MarkUsed records a request authenticated with one of the session's
+	// access tokens.
+	MarkUsed(ctx context.Context, id uint, at time.Time) error
+	// Reauthenticate records that the user proved their identity again with
+	// the given space-separated authentication methods.
+	Reauthenticate(ctx context.Context, id uint, at time.Time, methods string) error
+	Revoke(ctx context.Context, id uint, at time.Time) error
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	RevokeUser(ctx context.Context, userID uint, at time.Time) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepository) FindByID(ctx context.Context, id uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).First(&session, id).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &session, err
+}
+
+func (r *sessionRepository) FindByFamily(ctx context.Context, familyID string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).
+		Where("family_id = ?", familyID).
+		First(&session).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &session, err
+}
+
+func (r *sessionRepository) FindActiveByUser(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+
+	return sessions, err
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, id uint, at, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"last_used_at": at, "expires_at": expiresAt}).Error
+}
+
+func (r *sessionRepository) MarkUsed(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL AND last_used_at < ?", id, at).
+		Update("last_used_at", at).Error
+}
+
+func (r *sessionRepository) Reauthenticate(ctx context.Context, id uint, at time.Time, methods string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]any{"auth_time": at, "auth_methods": methods}).Error
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (r *sessionRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *sessionRepository) RevokeUser(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserAttributeRepository interface {
+	Set(ctx context.Context, userID uint, key, value string) error
+	Delete(ctx context.Context, userID uint, key string) error
+	FindByUserId(ctx context.Context, userID uint) ([]models.UserAttribute, error)
+}
+
+type userAttributeRepository struct {
+	db *gorm.DB
+}
+
+func NewUserAttributeRepository(db *gorm.DB) UserAttributeRepository {
+	return &userAttributeRepository{db: db}
+}
+
+func (r *userAttributeRepository) Set(ctx context.Context, userID uint, key, value string) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).
+		Create(&models.UserAttribute{UserID: userID, Key: key, Value: value}).Error
+}
+
+func (r *userAttributeRepository) Delete(ctx context.Context, userID uint, key string) error {
+	return r.db.WithContext(ctx).
+		Unscoped().
+		Where("user_id = ? AND key = ?", userID, key).
+		Delete(&models.UserAttribute{}).Error
+}
+
+func (r *userAttributeRepository) FindByUserId(ctx context.Context, userID uint) ([]models.UserAttribute, error) {
+	var attrs []models.UserAttribute
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("key").
+		Find(&attrs).Error
+
+	return attrs, err
+}
@@ -12,6 +12,8 @@ type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	FindById(ctx context.Context, id uint) (*models.User, error)
+	List(ctx context.Context) ([]models.User, error)
+	UpdatePassword(ctx context.Context, id uint, hash string) error
 }
 
 type userRepository struct {
@@ -51,3 +53,19 @@ func (r *userRepository) FindById(ctx context.Context, id uint) (*models.User, e
 
 	return &user, err
 }
+
+func (r *userRepository) List(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).
+		Order("email").
+		Find(&users).Error
+
+	return users, err
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Update("password", hash).Error
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+)
+
+// Check is one entry of an AuthorizeBatch call. Without a Resource the
+// permission is checked as Authorize would, otherwise as AuthorizeOn would.
+type Check struct {
+	Permission string
+	Resource   *Resource
+}
+
+// AuthorizeBatch evaluates every check for the same user and returns the
+// decisions in order. Role, hierarchy and grant lookups are done once per
+// call and shared between checks, so a batch costs about as much as a single
+// Authorize plus one binding lookup per distinct resource.
+func (s *authzService) AuthorizeBatch(ctx context.Context, user *models.User, checks []Check) ([]Decision, error) {
+	if user == nil {
+		return nil, appErr.ErrUserNotFound
+	}
+
+	for _, c := range checks {
+		if c.Permission == "" || (c.Resource != nil && (c.Resource.Type == "" || c.Resource.ID == "")) {
+			return nil, appErr.ErrInvalidInput
+		}
+	}
+
+	memo := &grantMemo{
+		svc:        s,
+		user:       user,
+		roleGrants: make(map[uint][]models.PermissionGrant),
+		resolved:   make(map[Resource][]models.PermissionGrant),
+	}
+
+	decisions := make([]Decision, len(checks))
+	for i, c := range checks {
+		grants, err := memo.grants(ctx, c.Resource)
+		if err != nil {
+			return nil, err
+		}
+		decisions[i] = EvaluateGrants(grants, RequireAll, c.Permission)
+	}
+
+	return decisions, nil
+}
+
+// grantMemo resolves a user's effective grants, globally or on a resource,
+// caching every lookup for the lifetime of one AuthorizeBatch call.
+type grantMemo struct {
+	svc  *authzService
+	user *models.User
+
+	loaded     bool
+	direct     []uint
+	edges      []models.RoleInheritance
+	userGrants []models.PermissionGrant
+
+	roleGrants map[uint][]models.PermissionGrant
+	resolved   map[Resource][]models.PermissionGrant
+}
+
+func (m *grantMemo) load(ctx context.Context) error {
+	if m.loaded {
+		return nil
+	}
+
+	sources, err := m.svc.directRoles(ctx, m.user, nil)
+	if err != nil {
+		return err
+	}
+	for _, src := range sources {
+		m.direct = append(m.direct, src.Role.ID)
+	}
+
+	m.edges, err = m.svc.roles.ListInheritance(ctx)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+
+	m.userGrants, err = m.svc.permissions.FindUserGrants(ctx, m.user.ID)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+
+	m.loaded = true
+	return nil
+}
+
+func (m *grantMemo) grants(ctx context.Context, resource *Resource) ([]models.PermissionGrant, error) {
+	var key Resource
+	if resource != nil {
+		key = *resource
+	}
+	if grants, ok := m.resolved[key]; ok {
+		return grants, nil
+	}
+
+	if err := m.load(ctx); err != nil {
+		return nil, err
+	}
+
+	ids := append([]uint(nil), m.direct...)
+	if resource != nil {
+		bound, err := m.svc.bindings.FindRolesForResource(ctx, m.user.ID, resource.Type, resource.ID)
+		if err != nil {
+			return nil, appErr.ErrInternal
+		}
+		for _, r := range bound {
+			ids = append(ids, r.ID)
+		}
+	}
+
+	ids = ancestorRoleIds(m.edges, ids)
+
+	var missing []uint
+	for _, id := range ids {
+		if _, ok := m.roleGrants[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		fetched, err := m.svc.permissions.FindRoleGrants(ctx, missing)
+		if err != nil {
+			return nil, appErr.ErrInternal
+		}
+		for _, id := range missing {
+			m.roleGrants[id] = nil
+		}
+		for _, g := range fetched {
+			m.roleGrants[g.RoleID] = append(m.roleGrants[g.RoleID], g)
+		}
+	}
+
+	var grants []models.PermissionGrant
+	for _, id := range ids {
+		grants = append(grants, m.roleGrants[id]...)
+	}
+	grants = append(grants, m.userGrants...)
+
+	m.resolved[key] = grants
+	return grants, nil
+}
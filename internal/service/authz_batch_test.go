@@ -0,0 +1,69 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestAuthzService_AuthorizeBatch_MemoizesLookups(t *testing.T) {
+	roles, perms, bindings, svc := newScopedAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}}
+
+	roles.On("FindByUserId", mock.Anything, uint(1)).
+		Return([]models.Role{{Model: gorm.Model{ID: 1}, Name: "editor"}}, nil).Once()
+	roles.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{{RoleID: 1, ParentID: 2}}, nil).Once()
+	perms.On("FindUserGrants", mock.Anything, uint(1)).
+		Return([]models.PermissionGrant{{Permission: "invoices:delete", Effect: models.EffectDeny}}, nil).Once()
+	perms.On("FindRoleGrants", mock.Anything, []uint{1, 2}).
+		Return([]models.PermissionGrant{
+			{Permission: "invoices:*", Effect: models.EffectAllow, RoleID: 1, RoleName: "editor"},
+			{Permission: "reports:read", Effect: models.EffectAllow, RoleID: 2, RoleName: "viewer"},
+		}, nil).Once()
+	bindings.On("FindRolesForResource", mock.Anything, uint(1), "project", "42").
+		Return([]models.Role{{Model: gorm.Model{ID: 3}, Name: "owner"}}, nil).Once()
+	perms.On("FindRoleGrants", mock.Anything, []uint{3}).
+		Return([]models.PermissionGrant{{Permission: "projects:*", Effect: models.EffectAllow, RoleID: 3, RoleName: "owner"}}, nil).Once()
+
+	project := &service.Resource{Type: "project", ID: "42"}
+	got, err := svc.AuthorizeBatch(context.Background(), user, []service.Check{
+		{Permission: "invoices:read"},
+		{Permission: "invoices:delete"},
+		{Permission: "reports:read"},
+		{Permission: "projects:write"},
+		{Permission: "projects:write", Resource: project},
+		{Permission: "invoices:delete", Resource: project},
+		{Permission: "projects:delete", Resource: &service.Resource{Type: "project", ID: "42"}},
+	})
+
+	require.NoError(t, err)
+	allowed := make([]bool, 0, len(got))
+	for _, d := range got {
+		allowed = append(allowed, d.Allowed)
+	}
+	assert.Equal(t, []bool{true, false, true, false, true, false, true}, allowed)
+
+	roles.AssertExpectations(t)
+	perms.AssertExpectations(t)
+	bindings.AssertExpectations(t)
+}
+
+func TestAuthzService_AuthorizeBatch_InvalidCheck(t *testing.T) {
+	_, _, _, svc := newScopedAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}}
+
+	_, err := svc.AuthorizeBatch(context.Background(), user, []service.Check{
+		{Permission: "invoices:read"},
+		{Permission: "projects:write", Resource: &service.Resource{Type: "project"}},
+	})
+
+	assert.Equal(t, appErr.ErrInvalidInput, err)
+}
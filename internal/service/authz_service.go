@@ -0,0 +1,396 @@
+package service
+
+import (
+	"context"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+)
+
+// PermissionMode controls how a set of required permissions is evaluated.
+type PermissionMode int
+
+const (
+	// RequireAll passes only if the user holds every required permission.
+	RequireAll PermissionMode = iota
+	// RequireAny passes if the user holds at least one required permission.
+	RequireAny
+)
+
+// Decision is the outcome of an authorization check. Permission is the
+// required permission that settled the outcome and Grant the grant that
+// matched it, if any.
+type Decision struct {
+	Allowed    bool
+	Permission string
+	Grant      *models.PermissionGrant
+	Reason     string
+}
+
+// Resource identifies a single object, e.g. {Type: "project", ID: "42"},
+// for checks that take resource-scoped role bindings into account.
+type Resource struct {
+	Type string
+	ID   string
+}
+
+// Ways a role can apply to a user, as reported in RoleSource.Source.
+const (
+	RoleSourceAssigned  = "assigned"
+	RoleSourcePrimary   = "primary"
+	RoleSourceBound     = "bound"
+	RoleSourceInherited = "inherited"
+)
+
+// RoleSource explains why a role applies to a user. Via names the role it was
+// inherited through for RoleSourceInherited, and the resource ("project:42")
+// for RoleSourceBound.
+type RoleSource struct {
+	Role   models.Role
+	Source string
+	Via    string
+}
+
+// Explanation is a Decision together with everything that produced it: the
+// roles that applied, each per-permission outcome and every allow or deny
+// grant matching one of the requested permissions.
+type Explanation struct {
+	Decision Decision
+	Checks   []Decision
+	Roles    []RoleSource
+	Grants   []models.PermissionGrant
+}
+
+type AuthzService interface {
+	EffectiveRoles(ctx context.Context, user *models.User) ([]models.Role, error)
+	EffectiveRolesOn(ctx context.Context, user *models.User, resource Resource) ([]models.Role, error)
+	EffectiveGrants(ctx context.Context, user *models.User) ([]models.PermissionGrant, error)
+	EffectivePermissions(ctx context.Context, user *models.User) ([]string, error)
+	Authorize(ctx context.Context, user *models.User, mode PermissionMode, perms ...string) (Decision, error)
+	AuthorizeOn(ctx context.Context, user *models.User, resource Resource, mode PermissionMode, perms ...string) (Decision, error)
+	AuthorizeBatch(ctx context.Context, user *models.User, checks []Check) ([]Decision, error)
+	Explain(ctx context.Context, user *models.User, resource *Resource, mode PermissionMode, perms ...string) (Explanation, error)
+}
+
+type authzService struct {
+	roles       repository.RoleRepository
+	permissions repository.PermissionRepository
+	bindings    repository.RoleBindingRepository
+}
+
+func NewAuthzService(roles repository.RoleRepository, permissions repository.PermissionRepository, bindings repository.RoleBindingRepository) AuthzService {
+	return &authzService{roles: roles, permissions: permissions, bindings: bindings}
+}
+
+// EffectiveRoles returns the roles assigned to the user, including the legacy
+// User.Role name when a matching Role record exists, plus every role they
+// inherit from through the role hierarchy.
+func (s *authzService) EffectiveRoles(ctx context.Context, user *models.User) ([]models.Role, error) {
+	return s.effectiveRoles(ctx, user, nil)
+}
+
+// EffectiveRolesOn is EffectiveRoles plus the roles bound to the user on the
+// given resource, and everything those roles inherit.
+func (s *authzService) EffectiveRolesOn(ctx context.Context, user *models.User, resource Resource) ([]models.Role, error) {
+	return s.effectiveRoles(ctx, user, &resource)
+}
+
+func (s *authzService) effectiveRoles(ctx context.Context, user *models.User, resource *Resource) ([]models.Role, error) {
+	sources, err := s.resolveRoles(ctx, user, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]models.Role, 0, len(sources))
+	for _, src := range sources {
+		roles = append(roles, src.Role)
+	}
+	return roles, nil
+}
+
+// resolution is what every authorization decision is made from: the roles
+// that apply to the user, with how each applies, and the grants of those
+// roles followed by the user's direct grants.
+type resolution struct {
+	roles  []RoleSource
+	grants []models.PermissionGrant
+}
+
+// resolve gathers the roles and grants that apply to the user, globally or,
+// with a resource, including the roles bound to them on it. Authorize,
+// AuthorizeOn and Explain all decide from it, so explanations match
+// decisions.
+func (s *authzService) resolve(ctx context.Context, user *models.User, resource *Resource) (resolution, error) {
+	roles, err := s.resolveRoles(ctx, user, resource)
+	if err != nil {
+		return resolution{}, err
+	}
+
+	roleIDs := make([]uint, 0, len(roles))
+	for _, r := range roles {
+		roleIDs = append(roleIDs, r.Role.ID)
+	}
+
+	grants, err := s.permissions.FindRoleGrants(ctx, roleIDs)
+	if err != nil {
+		return resolution{}, appErr.ErrInternal
+	}
+
+	userGrants, err := s.permissions.FindUserGrants(ctx, user.ID)
+	if err != nil {
+		return resolution{}, appErr.ErrInternal
+	}
+
+	return resolution{roles: roles, grants: append(grants, userGrants...)}, nil
+}
+
+// resolveRoles returns the user's direct roles followed by every role they
+// inherit.
+func (s *authzService) resolveRoles(ctx context.Context, user *models.User, resource *Resource) ([]RoleSource, error) {
+	direct, err := s.directRoles(ctx, user, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(direct) == 0 {
+		return direct, nil
+	}
+	return s.inheritedRoles(ctx, direct)
+}
+
+// directRoles returns the roles that apply to the user without walking the
+// hierarchy: assigned roles, the legacy User.Role and, with a resource, the
+// roles bound to them on it.
+func (s *authzService) directRoles(ctx context.Context, user *models.User, resource *Resource) ([]RoleSource, error) {
+	if user == nil {
+		return nil, appErr.ErrUserNotFound
+	}
+
+	assigned, err := s.roles.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	sources := make([]RoleSource, 0, len(assigned)+1)
+	for _, r := range assigned {
+		sources = append(sources, RoleSource{Role: r, Source: RoleSourceAssigned})
+	}
+
+	if user.Role != "" && !containsRoleName(assigned, user.Role) {
+		primary, err := s.roles.FindByName(ctx, user.Role)
+		if err != nil {
+			return nil, appErr.ErrInternal
+		}
+		if primary != nil {
+			sources = append(sources, RoleSource{Role: *primary, Source: RoleSourcePrimary})
+		}
+	}
+
+	if resource != nil {
+		bound, err := s.bindings.FindRolesForResource(ctx, user.ID, resource.Type, resource.ID)
+		if err != nil {
+			return nil, appErr.ErrInternal
+		}
+		for _, r := range bound {
+			if !containsRoleSource(sources, r.Name) {
+				sources = append(sources, RoleSource{
+					Role:   r,
+					Source: RoleSourceBound,
+					Via:    resource.Type + PermissionSeparator + resource.ID,
+				})
+			}
+		}
+	}
+
+	return sources, nil
+}
+
+// EffectiveGrants returns every allow and deny grant that applies to the
+// user, both through their (inherited) roles and attached to them directly.
+func (s *authzService) EffectiveGrants(ctx context.Context, user *models.User) ([]models.PermissionGrant, error) {
+	return s.effectiveGrants(ctx, user, nil)
+}
+
+func (s *authzService) effectiveGrants(ctx context.Context, user *models.User, resource *Resource) ([]models.PermissionGrant, error) {
+	res, err := s.resolve(ctx, user, resource)
+	if err != nil {
+		return nil, err
+	}
+	return res.grants, nil
+}
+
+// EffectivePermissions returns the permission patterns allowed to the user.
+// Deny grants are not listed; use Authorize to evaluate a concrete permission.
+func (s *authzService) EffectivePermissions(ctx context.Context, user *models.User) ([]string, error) {
+	grants, err := s.EffectiveGrants(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(grants))
+	names := make([]string, 0, len(grants))
+	for _, g := range grants {
+		if g.Effect != models.EffectAllow || seen[g.Permission] {
+			continue
+		}
+		seen[g.Permission] = true
+		names = append(names, g.Permission)
+	}
+
+	return names, nil
+}
+
+func (s *authzService) Authorize(ctx context.Context, user *models.User, mode PermissionMode, perms ...string) (Decision, error) {
+	if len(perms) == 0 {
+		return Decision{}, appErr.ErrInvalidInput
+	}
+
+	grants, err := s.EffectiveGrants(ctx, user)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	return EvaluateGrants(grants, mode, perms...), nil
+}
+
+// AuthorizeOn evaluates the permissions against the user's global grants
+// together with the grants of roles bound to them on the given resource.
+func (s *authzService) AuthorizeOn(ctx context.Context, user *models.User, resource Resource, mode PermissionMode, perms ...string) (Decision, error) {
+	if len(perms) == 0 || resource.Type == "" || resource.ID == "" {
+		return Decision{}, appErr.ErrInvalidInput
+	}
+
+	grants, err := s.effectiveGrants(ctx, user, &resource)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	return EvaluateGrants(grants, mode, perms...), nil
+}
+
+// Explain evaluates the permissions like Authorize, or AuthorizeOn when a
+// resource is given, and reports how the decision came about.
+func (s *authzService) Explain(ctx context.Context, user *models.User, resource *Resource, mode PermissionMode, perms ...string) (Explanation, error) {
+	if len(perms) == 0 || (resource != nil && (resource.Type == "" || resource.ID == "")) {
+		return Explanation{}, appErr.ErrInvalidInput
+	}
+
+	res, err := s.resolve(ctx, user, resource)
+	if err != nil {
+		return Explanation{}, err
+	}
+	grants := res.grants
+
+	exp := Explanation{
+		Decision: EvaluateGrants(grants, mode, perms...),
+		Roles:    res.roles,
+	}
+
+	for _, p := range perms {
+		exp.Checks = append(exp.Checks, EvaluateGrants(grants, RequireAll, p))
+	}
+
+	for _, g := range grants {
+		for _, p := range perms {
+			if MatchPermission(g.Permission, p) {
+				exp.Grants = append(exp.Grants, g)
+				break
+			}
+		}
+	}
+
+	return exp, nil
+}
+
+// inheritedRoles appends to direct every role it inherits, recording the role
+// each one was first reached through.
+func (s *authzService) inheritedRoles(ctx context.Context, direct []RoleSource) ([]RoleSource, error) {
+	edges, err := s.roles.ListInheritance(ctx)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	directIDs := make([]uint, 0, len(direct))
+	byID := make(map[uint]models.Role, len(direct))
+	for _, src := range direct {
+		directIDs = append(directIDs, src.Role.ID)
+		byID[src.Role.ID] = src.Role
+	}
+
+	allIDs, via := walkAncestors(edges, directIDs)
+	if len(allIDs) == len(directIDs) {
+		return direct, nil
+	}
+
+	roles, err := s.roles.FindByIds(ctx, allIDs)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	for _, r := range roles {
+		byID[r.ID] = r
+	}
+
+	result := append([]RoleSource(nil), direct...)
+	for _, id := range allIDs[len(directIDs):] {
+		role, ok := byID[id]
+		if !ok {
+			continue
+		}
+		result = append(result, RoleSource{
+			Role:   role,
+			Source: RoleSourceInherited,
+			Via:    byID[via[id]].Name,
+		})
+	}
+
+	return result, nil
+}
+
+// EvaluateGrants decides whether grants satisfy the required permissions
+// with authz.Evaluate: for each permission a matching deny grant always
+// wins, whatever its specificity; otherwise the most specific allow grant
+// (see BestMatch) is reported as the reason access was granted.
+func EvaluateGrants(grants []models.PermissionGrant, mode PermissionMode, perms ...string) Decision {
+	converted := make([]authz.Grant, len(grants))
+	for i, g := range grants {
+		converted[i] = authz.Grant{Permission: g.Permission, Effect: g.Effect, Role: g.RoleName}
+	}
+
+	authzMode := authz.RequireAll
+	if mode == RequireAny {
+		authzMode = authz.RequireAny
+	}
+
+	d := authz.Evaluate(converted, authzMode, perms...)
+	decision := Decision{Allowed: d.Allowed, Permission: d.Permission, Reason: d.Reason}
+
+	for i := range converted {
+		if &converted[i] == d.Grant {
+			g := grants[i]
+			decision.Grant = &g
+			break
+		}
+	}
+
+	return decision
+}
+
+func containsRoleSource(sources []RoleSource, name string) bool {
+	for _, src := range sources {
+		if src.Role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRoleName(roles []models.Role, name string) bool {
+	for _, r := range roles {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
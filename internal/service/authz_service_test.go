@@ -0,0 +1,410 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newAuthzFixture() (*mocks.RoleRepositoryMock, *mocks.PermissionRepositoryMock, service.AuthzService) {
+	roles, perms, _, svc := newScopedAuthzFixture()
+	return roles, perms, svc
+}
+
+func newScopedAuthzFixture() (*mocks.RoleRepositoryMock, *mocks.PermissionRepositoryMock, *mocks.RoleBindingRepositoryMock, service.AuthzService) {
+	roles := new(mocks.RoleRepositoryMock)
+	perms := new(mocks.PermissionRepositoryMock)
+	bindings := new(mocks.RoleBindingRepositoryMock)
+	return roles, perms, bindings, service.NewAuthzService(roles, perms, bindings)
+}
+
+func TestAuthzService_EffectivePermissions_IncludesLegacyRole(t *testing.T) {
+	roles, perms, svc := newAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}, Role: "viewer"}
+
+	roles.On("FindByUserId", mock.Anything, uint(1)).
+		Return([]models.Role{{Model: gorm.Model{ID: 2}, Name: "billing"}}, nil)
+	roles.On("FindByName", mock.Anything, "viewer").
+		Return(&models.Role{Model: gorm.Model{ID: 3}, Name: "viewer"}, nil)
+	roles.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{}, nil)
+	perms.On("FindRoleGrants", mock.Anything, []uint{2, 3}).
+		Return([]models.PermissionGrant{
+			{Permission: "invoices:read", Effect: models.EffectAllow, RoleID: 2, RoleName: "billing"},
+			{Permission: "invoices:write", Effect: models.EffectDeny, RoleID: 2, RoleName: "billing"},
+			{Permission: "reports:read", Effect: models.EffectAllow, RoleID: 3, RoleName: "viewer"},
+		}, nil)
+	perms.On("FindUserGrants", mock.Anything, uint(1)).
+		Return([]models.PermissionGrant{}, nil)
+
+	got, err := svc.EffectivePermissions(context.Background(), user)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoices:read", "reports:read"}, got)
+	roles.AssertExpectations(t)
+	perms.AssertExpectations(t)
+}
+
+func TestAuthzService_Authorize(t *testing.T) {
+	roles, perms, svc := newAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}}
+
+	roles.On("FindByUserId", mock.Anything, uint(1)).
+		Return([]models.Role{{Model: gorm.Model{ID: 2}, Name: "billing"}}, nil)
+	roles.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{}, nil)
+	perms.On("FindRoleGrants", mock.Anything, []uint{2}).
+		Return([]models.PermissionGrant{{Permission: "invoices:read", Effect: models.EffectAllow, RoleID: 2, RoleName: "billing"}}, nil)
+	perms.On("FindUserGrants", mock.Anything, uint(1)).
+		Return([]models.PermissionGrant{}, nil)
+
+	ctx := context.Background()
+
+	d, err := svc.Authorize(ctx, user, service.RequireAll, "invoices:read")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	d, err = svc.Authorize(ctx, user, service.RequireAll, "invoices:read", "invoices:write")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+
+	d, err = svc.Authorize(ctx, user, service.RequireAny, "invoices:write", "invoices:read")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	d, err = svc.Authorize(ctx, user, service.RequireAny, "invoices:write")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+}
+
+func TestAuthzService_EffectiveRoles_WalksHierarchy(t *testing.T) {
+	roles, _, svc := newAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}}
+
+	admin := models.Role{Model: gorm.Model{ID: 1}, Name: "admin"}
+	editor := models.Role{Model: gorm.Model{ID: 2}, Name: "editor"}
+	viewer := models.Role{Model: gorm.Model{ID: 3}, Name: "viewer"}
+
+	roles.On("FindByUserId", mock.Anything, uint(1)).
+		Return([]models.Role{admin}, nil)
+	roles.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{
+			{RoleID: 1, ParentID: 2},
+			{RoleID: 2, ParentID: 3},
+		}, nil)
+	roles.On("FindByIds", mock.Anything, []uint{1, 2, 3}).
+		Return([]models.Role{admin, editor, viewer}, nil)
+
+	got, err := svc.EffectiveRoles(context.Background(), user)
+
+	require.NoError(t, err)
+	assert.Len(t, got, 3)
+	roles.AssertExpectations(t)
+}
+
+func TestAuthzService_Authorize_Wildcard(t *testing.T) {
+	roles, perms, svc := newAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}}
+
+	roles.On("FindByUserId", mock.Anything, uint(1)).
+		Return([]models.Role{{Model: gorm.Model{ID: 2}, Name: "billing-admin"}}, nil)
+	roles.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{}, nil)
+	perms.On("FindRoleGrants", mock.Anything, []uint{2}).
+		Return([]models.PermissionGrant{{Permission: "billing:*", Effect: models.EffectAllow, RoleID: 2, RoleName: "billing-admin"}}, nil)
+	perms.On("FindUserGrants", mock.Anything, uint(1)).
+		Return([]models.PermissionGrant{}, nil)
+
+	ctx := context.Background()
+
+	d, err := svc.Authorize(ctx, user, service.RequireAll, "billing:invoices:read", "billing:payouts:approve")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	d, err = svc.Authorize(ctx, user, service.RequireAll, "users:delete")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+}
+
+func TestAuthzService_Authorize_UserDenyOverridesRoleAllow(t *testing.T) {
+	roles, perms, svc := newAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}}
+
+	roles.On("FindByUserId", mock.Anything, uint(1)).
+		Return([]models.Role{{Model: gorm.Model{ID: 2}, Name: "admin"}}, nil)
+	roles.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{}, nil)
+	perms.On("FindRoleGrants", mock.Anything, []uint{2}).
+		Return([]models.PermissionGrant{{Permission: "users:*", Effect: models.EffectAllow, RoleID: 2, RoleName: "admin"}}, nil)
+	perms.On("FindUserGrants", mock.Anything, uint(1)).
+		Return([]models.PermissionGrant{{Permission: "users:delete", Effect: models.EffectDeny, UserID: 1}}, nil)
+
+	d, err := svc.Authorize(context.Background(), user, service.RequireAll, "users:delete")
+
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	require.NotNil(t, d.Grant)
+	assert.Equal(t, models.EffectDeny, d.Grant.Effect)
+	assert.Equal(t, `"users:delete" denied by user deny "users:delete"`, d.Reason)
+}
+
+func TestEvaluateGrants(t *testing.T) {
+	contractor := []models.PermissionGrant{
+		{Permission: "users:*", Effect: models.EffectAllow, RoleID: 1, RoleName: "staff"},
+		{Permission: "users:delete", Effect: models.EffectDeny, RoleID: 2, RoleName: "contractor"},
+		{Permission: "billing:*", Effect: models.EffectDeny, RoleID: 2, RoleName: "contractor"},
+		{Permission: "billing:invoices:read", Effect: models.EffectAllow, RoleID: 1, RoleName: "staff"},
+	}
+
+	tests := []struct {
+		name    string
+		mode    service.PermissionMode
+		perms   []string
+		allowed bool
+		reason  string
+	}{
+		{
+			name:    "allow through wildcard",
+			mode:    service.RequireAll,
+			perms:   []string{"users:read"},
+			allowed: true,
+			reason:  `"users:read" granted by role "staff" allow "users:*"`,
+		},
+		{
+			name:   "deny overrides broader allow",
+			mode:   service.RequireAll,
+			perms:  []string{"users:delete"},
+			reason: `"users:delete" denied by role "contractor" deny "users:delete"`,
+		},
+		{
+			name:   "broad deny overrides specific allow",
+			mode:   service.RequireAll,
+			perms:  []string{"billing:invoices:read"},
+			reason: `"billing:invoices:read" denied by role "contractor" deny "billing:*"`,
+		},
+		{
+			name:   "all-of stops at first deny",
+			mode:   service.RequireAll,
+			perms:  []string{"users:read", "users:delete"},
+			reason: `"users:delete" denied by role "contractor" deny "users:delete"`,
+		},
+		{
+			name:    "any-of skips denied permission",
+			mode:    service.RequireAny,
+			perms:   []string{"users:delete", "users:read"},
+			allowed: true,
+			reason:  `"users:read" granted by role "staff" allow "users:*"`,
+		},
+		{
+			name:   "any-of reports deny when nothing allowed",
+			mode:   service.RequireAny,
+			perms:  []string{"reports:read", "users:delete"},
+			reason: `"users:delete" denied by role "contractor" deny "users:delete"`,
+		},
+		{
+			name:   "no grant",
+			mode:   service.RequireAll,
+			perms:  []string{"reports:read"},
+			reason: `no grant for "reports:read"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := service.EvaluateGrants(contractor, tt.mode, tt.perms...)
+			require.Equal(t, tt.allowed, d.Allowed)
+			require.Equal(t, tt.reason, d.Reason)
+		})
+	}
+}
+
+func TestAuthzService_AuthorizeOn_UsesResourceBindings(t *testing.T) {
+	roles, perms, bindings, svc := newScopedAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}}
+
+	roles.On("FindByUserId", mock.Anything, uint(1)).
+		Return([]models.Role{{Model: gorm.Model{ID: 3}, Name: "viewer"}}, nil)
+	bindings.On("FindRolesForResource", mock.Anything, uint(1), "project", "42").
+		Return([]models.Role{{Model: gorm.Model{ID: 2}, Name: "editor"}}, nil)
+	roles.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{}, nil)
+	perms.On("FindRoleGrants", mock.Anything, []uint{3, 2}).
+		Return([]models.PermissionGrant{
+			{Permission: "projects:read", Effect: models.EffectAllow, RoleID: 3, RoleName: "viewer"},
+			{Permission: "projects:write", Effect: models.EffectAllow, RoleID: 2, RoleName: "editor"},
+		}, nil)
+	perms.On("FindUserGrants", mock.Anything, uint(1)).
+		Return([]models.PermissionGrant{}, nil)
+
+	d, err := svc.AuthorizeOn(context.Background(), user, service.Resource{Type: "project", ID: "42"}, service.RequireAll, "projects:write")
+
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, "editor", d.Grant.RoleName)
+	bindings.AssertExpectations(t)
+}
+
+func TestAuthzService_AuthorizeOn_MissingResource(t *testing.T) {
+	_, _, _, svc := newScopedAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}}
+
+	_, err := svc.AuthorizeOn(context.Background(), user, service.Resource{Type: "project"}, service.RequireAll, "projects:write")
+
+	assert.Equal(t, appErr.ErrInvalidInput, err)
+}
+
+func TestAuthzService_Explain_ReportsRoleChainAndDenies(t *testing.T) {
+	roles, perms, bindings, svc := newScopedAuthzFixture()
+	user := &models.User{Model: gorm.Model{ID: 1}, Role: "contractor"}
+
+	admin := models.Role{Model: gorm.Model{ID: 1}, Name: "admin"}
+	editor := models.Role{Model: gorm.Model{ID: 2}, Name: "editor"}
+	contractor := models.Role{Model: gorm.Model{ID: 3}, Name: "contractor"}
+	owner := models.Role{Model: gorm.Model{ID: 4}, Name: "owner"}
+
+	roles.On("FindByUserId", mock.Anything, uint(1)).
+		Return([]models.Role{admin}, nil)
+	roles.On("FindByName", mock.Anything, "contractor").
+		Return(&contractor, nil)
+	bindings.On("FindRolesForResource", mock.Anything, uint(1), "project", "42").
+		Return([]models.Role{owner}, nil)
+	roles.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{{RoleID: 1, ParentID: 2}}, nil)
+	roles.On("FindByIds", mock.Anything, []uint{1, 3, 4, 2}).
+		Return([]models.Role{admin, editor, contractor, owner}, nil)
+	perms.On("FindRoleGrants", mock.Anything, []uint{1, 3, 4, 2}).
+		Return([]models.PermissionGrant{
+			{Permission: "users:*", Effect: models.EffectAllow, RoleID: 2, RoleName: "editor"},
+			{Permission: "users:delete", Effect: models.EffectDeny, RoleID: 3, RoleName: "contractor"},
+			{Permission: "reports:read", Effect: models.EffectAllow, RoleID: 4, RoleName: "owner"},
+		}, nil)
+	perms.On("FindUserGrants", mock.Anything, uint(1)).
+		Return([]models.PermissionGrant{}, nil)
+
+	resource := &service.Resource{Type: "project", ID: "42"}
+	exp, err := svc.Explain(context.Background(), user, resource, service.RequireAll, "users:read", "users:delete")
+
+	require.NoError(t, err)
+	assert.False(t, exp.Decision.Allowed)
+	assert.Equal(t, `"users:delete" denied by role "contractor" deny "users:delete"`, exp.Decision.Reason)
+
+	require.Len(t, exp.Checks, 2)
+	assert.True(t, exp.Checks[0].Allowed)
+	assert.False(t, exp.Checks[1].Allowed)
+
+	assert.Equal(t, []service.RoleSource{
+		{Role: admin, Source: service.RoleSourceAssigned},
+		{Role: contractor, Source: service.RoleSourcePrimary},
+		{Role: owner, Source: service.RoleSourceBound, Via: "project:42"},
+		{Role: editor, Source: service.RoleSourceInherited, Via: "admin"},
+	}, exp.Roles)
+
+	require.Len(t, exp.Grants, 2)
+	assert.Equal(t, "users:*", exp.Grants[0].Permission)
+	assert.Equal(t, "users:delete", exp.Grants[1].Permission)
+}
+
+func TestAuthzService_ExplainMatchesAuthorize(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := repository.Connect(filepath.Join(t.TempDir(), "sentinel.db"))
+	require.NoError(t, err)
+	require.NoError(t, repository.Migrate(db))
+
+	users := repository.NewUserRepository(db)
+	roles := repository.NewRoleRepository(db)
+	perms := repository.NewPermissionRepository(db)
+	bindings := repository.NewRoleBindingRepository(db)
+	svc := service.NewAuthzService(roles, perms, bindings)
+
+	permission := func(name string) uint {
+		p := &models.Permission{Name: name}
+		require.NoError(t, perms.Create(ctx, p))
+		return p.ID
+	}
+	role := func(name string) *models.Role {
+		r := &models.Role{Name: name}
+		require.NoError(t, roles.Create(ctx, r))
+		return r
+	}
+
+	invoicesRead, invoicesAll, invoicesDelete := permission("invoices:read"), permission("invoices:*"), permission("invoices:delete")
+	reportsAll, reportsRead, projectsAll := permission("reports:*"), permission("reports:read"), permission("projects:*")
+
+	viewer, editor, contractor, owner := role("viewer"), role("editor"), role("contractor"), role("owner")
+	require.NoError(t, roles.AddParent(ctx, editor.ID, viewer.ID))
+	require.NoError(t, perms.GrantToRole(ctx, viewer.ID, invoicesRead, models.EffectAllow))
+	require.NoError(t, perms.GrantToRole(ctx, editor.ID, invoicesAll, models.EffectAllow))
+	require.NoError(t, perms.GrantToRole(ctx, editor.ID, invoicesDelete, models.EffectDeny))
+	require.NoError(t, perms.GrantToRole(ctx, contractor.ID, reportsAll, models.EffectDeny))
+	require.NoError(t, perms.GrantToRole(ctx, owner.ID, projectsAll, models.EffectAllow))
+
+	alice := &models.User{Email: "alice@example.com", Password: "x", Role: "contractor"}
+	bob := &models.User{Email: "bob@example.com", Password: "x"}
+	require.NoError(t, users.Create(ctx, alice))
+	require.NoError(t, users.Create(ctx, bob))
+	require.NoError(t, roles.AssignToUser(ctx, alice.ID, editor.ID))
+	require.NoError(t, perms.GrantToUser(ctx, alice.ID, reportsRead, models.EffectAllow))
+	require.NoError(t, bindings.Create(ctx, &models.RoleBinding{UserID: alice.ID, RoleID: owner.ID, ResourceType: "project", ResourceID: "42"}))
+	require.NoError(t, bindings.Create(ctx, &models.RoleBinding{UserID: bob.ID, RoleID: editor.ID, ResourceType: "project", ResourceID: "42"}))
+
+	project42 := &service.Resource{Type: "project", ID: "42"}
+	project7 := &service.Resource{Type: "project", ID: "7"}
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		resource *service.Resource
+		mode     service.PermissionMode
+		perms    []string
+	}{
+		{"inherited allow", alice, nil, service.RequireAll, []string{"invoices:read"}},
+		{"wildcard allow", alice, nil, service.RequireAll, []string{"invoices:export"}},
+		{"role deny", alice, nil, service.RequireAll, []string{"invoices:delete"}},
+		{"legacy role deny beats user allow", alice, nil, service.RequireAll, []string{"reports:read"}},
+		{"no grant", alice, nil, service.RequireAll, []string{"users:read"}},
+		{"any of allow and deny", alice, nil, service.RequireAny, []string{"invoices:delete", "invoices:read"}},
+		{"all of allow and deny", alice, nil, service.RequireAll, []string{"invoices:read", "invoices:delete"}},
+		{"bound role", alice, project42, service.RequireAll, []string{"projects:update"}},
+		{"bound role elsewhere", alice, project7, service.RequireAll, []string{"projects:update"}},
+		{"bound role global", alice, nil, service.RequireAll, []string{"projects:update"}},
+		{"bound role inherits", bob, project42, service.RequireAll, []string{"invoices:read"}},
+		{"bound deny", bob, project42, service.RequireAny, []string{"invoices:delete"}},
+		{"no roles", bob, nil, service.RequireAny, []string{"invoices:read", "projects:read"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want service.Decision
+			if tt.resource == nil {
+				want, err = svc.Authorize(ctx, tt.user, tt.mode, tt.perms...)
+			} else {
+				want, err = svc.AuthorizeOn(ctx, tt.user, *tt.resource, tt.mode, tt.perms...)
+			}
+			require.NoError(t, err)
+
+			exp, err := svc.Explain(ctx, tt.user, tt.resource, tt.mode, tt.perms...)
+			require.NoError(t, err)
+
+			assert.Equal(t, want.Allowed, exp.Decision.Allowed)
+			assert.Equal(t, want.Reason, exp.Decision.Reason)
+
+			if len(tt.perms) == 1 {
+				batch, err := svc.AuthorizeBatch(ctx, tt.user, []service.Check{{Permission: tt.perms[0], Resource: tt.resource}})
+				require.NoError(t, err)
+				assert.Equal(t, want.Allowed, batch[0].Allowed)
+				assert.Equal(t, want.Reason, batch[0].Reason)
+			}
+		})
+	}
+}
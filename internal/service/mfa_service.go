@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/config"
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/corradoisidoro/sentinel-rbac/internal/totp"
+)
+
+const (
+	// MFAChallengeTTL is how long the second login step may take.
+	MFAChallengeTTL = 5 * time.Minute
+	// MFAMaxAttempts is how many wrong codes end a challenge.
+	MFAMaxAttempts = 5
+	// MFAMaxFailures is how many wrong codes or reauthentication passwords,
+	// across challenges and sessions, lock a user out of second-factor checks
+	// and reauthentication for MFALockoutDuration.
+	MFAMaxFailures     = 10
+	MFALockoutDuration = 15 * time.Minute
+
+	recoveryCodeCount = 10
+	// totpSkew accepts the codes of the previous and next period too.
+	totpSkew = 1
+)
+
+// TOTPSetup is a pending TOTP enrollment: the secret to type into an
+// authenticator app and the otpauth URI to show as a QR code instead.
+type TOTPSetup struct {
+	Secret string
+	URI    string
+}
+
+// MFAChallenge is returned by Login instead of tokens when the user has MFA
+// enabled. Token is exchanged for tokens together with a valid code before
+// ExpiresAt.
+type MFAChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// MFAService manages TOTP enrollment, recovery codes and the second step of
+// logins. Codes are either 6-digit TOTP codes, each accepted once, or
+// one-time recovery codes.
+type MFAService interface {
+	// Enroll starts a TOTP enrollment, replacing a pending one. It fails with
+	// ErrMFAAlreadyEnabled once MFA is enabled.
+	Enroll(ctx context.Context, user *models.User) (*TOTPSetup, error)
+	// Confirm enables MFA with a code from the newly set up authenticator and
+	// returns recovery codes, which are stored hashed and never shown again.
+	Confirm(ctx context.Context, user *models.User, code string) ([]string, error)
+	// Verify checks a TOTP or recovery code of the user. Wrong codes count
+	// towards a lockout; while locked out it fails with ErrMFALocked.
+	Verify(ctx context.Context, userID uint, code string) error
+	// Lockout fails with ErrMFALocked while the user is locked out.
+	Lockout(ctx context.Context, userID uint) error
+	// Attempt records the outcome of an identity check other than a code,
+	// such as a reauthentication password: a failure counts towards the same
+	// lockout as wrong codes, a success clears the count.
+	Attempt(ctx context.Context, userID uint, ok bool) error
+	// Challenge starts the second step of a login made by client. It fails
+	// with ErrMFALocked while the user is locked out.
+	Challenge(ctx context.Context, userID uint, client ClientInfo) (*MFAChallenge, error)
+	// Complete verifies code for the challenge token and returns the user and
+	// client of the login.
+	Complete(ctx context.Context, token, code string) (uint, ClientInfo, error)
+}
+
+type mfaService struct {
+	repo   repository.MFARepository
+	issuer string
+	now    func() time.Time
+}
+
+// NewMFAService returns an MFAService naming JWT_ISSUER as the issuer shown
+// in authenticator apps.
+func NewMFAService(repo repository.MFARepository, cfg config.Config) MFAService {
+	issuer := cfg.JWTIssuer
+	if issuer == "" {
+		issuer = config.DefaultJWTIssuer
+	}
+	return &mfaService{repo: repo, issuer: issuer, now: time.Now}
+}
+
+func (s *mfaService) Enroll(ctx context.Context, user *models.User) (*TOTPSetup, error) {
+	if user.MFAEnabled {
+		return nil, appErr.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	if err := s.repo.SaveTOTP(ctx, &models.TOTPEnrollment{UserID: user.ID, Secret: secret}); err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	return &TOTPSetup{Secret: secret, URI: totp.URI(s.issuer, user.Email, secret)}, nil
+}
+
+func (s *mfaService) Confirm(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, appErr.ErrMFAAlreadyEnabled
+	}
+
+	enrollment, err := s.repo.FindTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	if enrollment == nil {
+		return nil, appErr.ErrMFANotEnrolled
+	}
+
+	now := s.now()
+	step, ok := totp.Validate(enrollment.Secret, normalizeCode(code), now, totpSkew)
+	if !ok {
+		return nil, appErr.ErrInvalidMFACode
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := recoveryCode()
+		if err != nil {
+			return nil, appErr.ErrInternal
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeCode(code)))
+	}
+
+	if err := s.repo.ConfirmTOTP(ctx, user.ID, step, now, hashes); err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	return codes, nil
+}
+
+func (s *mfaService) Verify(ctx context.Context, userID uint, code string) error {
+	lockout, err := s.lockout(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	switch err := s.verify(ctx, userID, code); err {
+	case nil:
+		if lockout != nil && lockout.Failures > 0 {
+			if err := s.repo.ClearMFAFailures(ctx, userID); err != nil {
+				return appErr.ErrInternal
+			}
+		}
+		return nil
+	case appErr.ErrInvalidMFACode:
+		if err := s.Attempt(ctx, userID, false); err != nil {
+			return err
+		}
+		return appErr.ErrInvalidMFACode
+	default:
+		return err
+	}
+}
+
+func (s *mfaService) Lockout(ctx context.Context, userID uint) error {
+	_, err := s.lockout(ctx, userID)
+	return err
+}
+
+func (s *mfaService) Attempt(ctx context.Context, userID uint, ok bool) error {
+	if ok {
+		if err := s.repo.ClearMFAFailures(ctx, userID); err != nil {
+			return appErr.ErrInternal
+		}
+		return nil
+	}
+
+	if err := s.repo.FailMFA(ctx, userID, MFAMaxFailures, s.now().Add(MFALockoutDuration)); err != nil {
+		return appErr.ErrInternal
+	}
+	return nil
+}
+
+// lockout returns the user's failure count, or ErrMFALocked while they
+// are locked out.
+func (s *mfaService) lockout(ctx context.Context, userID uint) (*models.MFALockout, error) {
+	lockout, err := s.repo.FindLockout(ctx, userID)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	if lockout != nil && lockout.LockedUntil != nil && s.now().Before(*lockout.LockedUntil) {
+		return nil, appErr.ErrMFALocked
+	}
+	return lockout, nil
+}
+
+func (s *mfaService) verify(ctx context.Context, userID uint, code string) error {
+	code = normalizeCode(code)
+	if code == "" {
+		return appErr.ErrInvalidMFACode
+	}
+
+	if len(code) != totp.Digits {
+		used, err := s.repo.UseRecoveryCode(ctx, userID, hashToken(code), s.now())
+		if err != nil {
+			return appErr.ErrInternal
+		}
+		if !used {
+			return appErr.ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	enrollment, err := s.repo.FindTOTP(ctx, userID)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+	if enrollment == nil || enrollment.ConfirmedAt == nil {
+		return appErr.ErrInvalidMFACode
+	}
+
+	step, ok := totp.Validate(enrollment.Secret, code, s.now(), totpSkew)
+	if !ok || step <= enrollment.LastStep {
+		return appErr.ErrInvalidMFACode
+	}
+
+	// A concurrent login may have used the same code since it was read.
+	used, err := s.repo.UseStep(ctx, userID, step)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+	if !used {
+		return appErr.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *mfaService) Challenge(ctx context.Context, userID uint, client ClientInfo) (*MFAChallenge, error) {
+	if _, err := s.lockout(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, appErr.ErrFailedToGenerateToken
+	}
+
+	challenge := &MFAChallenge{Token: token, ExpiresAt: s.now().Add(MFAChallengeTTL)}
+	if err := s.repo.CreateChallenge(ctx, &models.MFAChallenge{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		ExpiresAt: challenge.ExpiresAt,
+	}); err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	return challenge, nil
+}
+
+func (s *mfaService) Complete(ctx context.Context, token, code string) (uint, ClientInfo, error) {
+	if token == "" {
+		return 0, ClientInfo{}, appErr.ErrInvalidMFAToken
+	}
+
+	challenge, err := s.repo.FindChallenge(ctx, hashToken(token))
+	if err != nil {
+		return 0, ClientInfo{}, appErr.ErrInternal
+	}
+	if challenge == nil || challenge.UsedAt != nil || challenge.Attempts >= MFAMaxAttempts || !s.now().Before(challenge.ExpiresAt) {
+		return 0, ClientInfo{}, appErr.ErrInvalidMFAToken
+	}
+
+	if err := s.Verify(ctx, challenge.UserID, code); err != nil {
+		if err == appErr.ErrInvalidMFACode {
+			if err := s.repo.FailChallenge(ctx, challenge.ID); err != nil {
+				return 0, ClientInfo{}, appErr.ErrInternal
+			}
+		}
+		return 0, ClientInfo{}, err
+	}
+
+	used, err := s.repo.UseChallenge(ctx, challenge.ID, s.now())
+	if err != nil {
+		return 0, ClientInfo{}, appErr.ErrInternal
+	}
+	if !used {
+		return 0, ClientInfo{}, appErr.ErrInvalidMFAToken
+	}
+
+	return challenge.UserID, ClientInfo{IPAddress: challenge.IPAddress, UserAgent: challenge.UserAgent}, nil
+}
+
+// recoveryCode returns 40 random bits as "xxxx-xxxx" in lowercase base32.
+func recoveryCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+	return code[:4] + "-" + code[4:], nil
+}
+
+// normalizeCode drops the separators users type or paste along with a code.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
@@ -0,0 +1,197 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/config"
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/corradoisidoro/sentinel-rbac/internal/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func currentCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestMFAService_EnrollAndConfirm(t *testing.T) {
+	repo := new(mocks.MFARepositoryMock)
+	svc := service.NewMFAService(repo, config.Config{JWTIssuer: "sentinel"})
+	user := &models.User{Model: gorm.Model{ID: 7}, Email: "alice@example.com"}
+
+	var enrollment *models.TOTPEnrollment
+	repo.On("SaveTOTP", mock.Anything, mock.AnythingOfType("*models.TOTPEnrollment")).
+		Run(func(args mock.Arguments) { enrollment = args.Get(1).(*models.TOTPEnrollment) }).
+		Return(nil)
+
+	setup, err := svc.Enroll(context.Background(), user)
+	require.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, setup.Secret)
+	assert.Contains(t, setup.URI, "otpauth://totp/sentinel:alice@example.com?")
+	assert.Nil(t, enrollment.ConfirmedAt, "pending until confirmed")
+
+	repo.On("FindTOTP", mock.Anything, uint(7)).Return(enrollment, nil)
+
+	_, err = svc.Confirm(context.Background(), user, "000000")
+	if currentCode(t, setup.Secret) != "000000" {
+		assert.Equal(t, appErr.ErrInvalidMFACode, err)
+	}
+
+	var hashes []string
+	repo.On("ConfirmTOTP", mock.Anything, uint(7), totp.Step(time.Now()), mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { hashes = args.Get(4).([]string) }).
+		Return(nil)
+
+	codes, err := svc.Confirm(context.Background(), user, currentCode(t, setup.Secret))
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.Len(t, hashes, 10)
+	assert.NotContains(t, hashes, codes[0], "only hashes are stored")
+
+	user.MFAEnabled = true
+	_, err = svc.Enroll(context.Background(), user)
+	assert.Equal(t, appErr.ErrMFAAlreadyEnabled, err)
+}
+
+func TestMFAService_Verify(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	step := totp.Step(time.Now())
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		used     bool
+		err      error
+	}{
+		{"totp code", currentCode(t, secret), step - 2, true, nil},
+		{"replayed totp code", currentCode(t, secret), step + 1, true, appErr.ErrInvalidMFACode},
+		{"totp code used concurrently", currentCode(t, secret), step - 2, false, appErr.ErrInvalidMFACode},
+		{"recovery code", "ABCD-efgh", 0, true, nil},
+		{"used recovery code", "abcd-efgh", 0, false, appErr.ErrInvalidMFACode},
+		{"empty", " ", 0, false, appErr.ErrInvalidMFACode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mocks.MFARepositoryMock)
+			svc := service.NewMFAService(repo, config.Config{})
+
+			repo.On("FindTOTP", mock.Anything, uint(7)).Return(&models.TOTPEnrollment{
+				UserID:      7,
+				Secret:      secret,
+				ConfirmedAt: ptrTime(time.Now()),
+				LastStep:    tt.lastStep,
+			}, nil)
+			repo.On("FindLockout", mock.Anything, uint(7)).Return(nil, nil)
+			repo.On("FailMFA", mock.Anything, uint(7), service.MFAMaxFailures, mock.Anything).Return(nil)
+			repo.On("UseStep", mock.Anything, uint(7), mock.Anything).Return(tt.used, nil)
+			repo.On("UseRecoveryCode", mock.Anything, uint(7), mock.Anything, mock.Anything).Return(tt.used, nil)
+
+			assert.Equal(t, tt.err, svc.Verify(context.Background(), 7, tt.code))
+			if tt.err == appErr.ErrInvalidMFACode {
+				repo.AssertCalled(t, "FailMFA", mock.Anything, uint(7), service.MFAMaxFailures, mock.Anything)
+			} else {
+				repo.AssertNotCalled(t, "FailMFA", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestMFAService_Complete(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge *models.MFAChallenge
+		code      bool
+		err       error
+	}{
+		{"valid", &models.MFAChallenge{ID: 1, UserID: 7, IPAddress: "203.0.113.7", ExpiresAt: time.Now().Add(time.Minute)}, true, nil},
+		{"wrong code", &models.MFAChallenge{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(time.Minute)}, false, appErr.ErrInvalidMFACode},
+		{"unknown", nil, true, appErr.ErrInvalidMFAToken},
+		{"expired", &models.MFAChallenge{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(-time.Minute)}, true, appErr.ErrInvalidMFAToken},
+		{"used", &models.MFAChallenge{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(time.Minute), UsedAt: ptrTime(time.Now())}, true, appErr.ErrInvalidMFAToken},
+		{"too many attempts", &models.MFAChallenge{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(time.Minute), Attempts: service.MFAMaxAttempts}, true, appErr.ErrInvalidMFAToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mocks.MFARepositoryMock)
+			svc := service.NewMFAService(repo, config.Config{})
+
+			if tt.challenge == nil {
+				repo.On("FindChallenge", mock.Anything, mock.Anything).Return(nil, nil)
+			} else {
+				repo.On("FindChallenge", mock.Anything, mock.Anything).Return(tt.challenge, nil)
+			}
+			repo.On("UseRecoveryCode", mock.Anything, uint(7), mock.Anything, mock.Anything).Return(tt.code, nil)
+			repo.On("FailChallenge", mock.Anything, uint(1)).Return(nil)
+			repo.On("FindLockout", mock.Anything, uint(7)).Return(nil, nil)
+			repo.On("FailMFA", mock.Anything, uint(7), service.MFAMaxFailures, mock.Anything).Return(nil)
+			repo.On("UseChallenge", mock.Anything, uint(1), mock.Anything).Return(true, nil)
+
+			userID, client, err := svc.Complete(context.Background(), "token", "abcd-efgh")
+
+			assert.Equal(t, tt.err, err)
+			if tt.err == nil {
+				assert.Equal(t, uint(7), userID)
+				assert.Equal(t, "203.0.113.7", client.IPAddress)
+			} else {
+				repo.AssertNotCalled(t, "UseChallenge", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.err == appErr.ErrInvalidMFACode {
+				repo.AssertCalled(t, "FailChallenge", mock.Anything, uint(1))
+			}
+		})
+	}
+}
+
+func TestMFAService_LockoutAcrossChallenges(t *testing.T) {
+	ctx := context.Background()
+	db, err := repository.Connect(filepath.Join(t.TempDir(), "sentinel.db"))
+	require.NoError(t, err)
+	require.NoError(t, repository.Migrate(db))
+
+	user := &models.User{Email: "mfa@example.com", Password: "hash"}
+	require.NoError(t, repository.NewUserRepository(db).Create(ctx, user))
+
+	svc := service.NewMFAService(repository.NewMFARepository(db), config.Config{})
+	setup, err := svc.Enroll(ctx, user)
+	require.NoError(t, err)
+	recoveryCodes, err := svc.Confirm(ctx, user, currentCode(t, setup.Secret))
+	require.NoError(t, err)
+
+	// Each challenge ends before MFAMaxAttempts, so only the per-user count
+	// can stop the guessing.
+	failures := 0
+	for failures < service.MFAMaxFailures {
+		challenge, err := svc.Challenge(ctx, user.ID, service.ClientInfo{})
+		require.NoError(t, err, "failure %d", failures)
+
+		for range service.MFAMaxAttempts - 1 {
+			if failures == service.MFAMaxFailures {
+				break
+			}
+			_, _, err := svc.Complete(ctx, challenge.Token, "zzzz-zzzz")
+			require.Equal(t, appErr.ErrInvalidMFACode, err)
+			failures++
+		}
+	}
+
+	_, err = svc.Challenge(ctx, user.ID, service.ClientInfo{})
+	assert.Equal(t, appErr.ErrMFALocked, err)
+	assert.Equal(t, appErr.ErrMFALocked, svc.Verify(ctx, user.ID, recoveryCodes[0]), "even a valid code is refused")
+}
@@ -0,0 +1,71 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type AuthzServiceMock struct {
+	mock.Mock
+}
+
+func (m *AuthzServiceMock) EffectiveRoles(ctx context.Context, user *models.User) ([]models.Role, error) {
+	args := m.Called(ctx, user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *AuthzServiceMock) EffectiveRolesOn(ctx context.Context, user *models.User, resource service.Resource) ([]models.Role, error) {
+	args := m.Called(ctx, user, resource)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *AuthzServiceMock) EffectiveGrants(ctx context.Context, user *models.User) ([]models.PermissionGrant, error) {
+	args := m.Called(ctx, user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PermissionGrant), args.Error(1)
+}
+
+func (m *AuthzServiceMock) EffectivePermissions(ctx context.Context, user *models.User) ([]string, error) {
+	args := m.Called(ctx, user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *AuthzServiceMock) Authorize(ctx context.Context, user *models.User, mode service.PermissionMode, perms ...string) (service.Decision, error) {
+	args := m.Called(ctx, user, mode, perms)
+	return args.Get(0).(service.Decision), args.Error(1)
+}
+
+func (m *AuthzServiceMock) AuthorizeOn(ctx context.Context, user *models.User, resource service.Resource, mode service.PermissionMode, perms ...string) (service.Decision, error) {
+	args := m.Called(ctx, user, resource, mode, perms)
+	return args.Get(0).(service.Decision), args.Error(1)
+}
+
+func (m *AuthzServiceMock) AuthorizeBatch(ctx context.Context, user *models.User, checks []service.Check) ([]service.Decision, error) {
+	args := m.Called(ctx, user, checks)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]service.Decision), args.Error(1)
+}
+
+func (m *AuthzServiceMock) Explain(ctx context.Context, user *models.User, resource *service.Resource, mode service.PermissionMode, perms ...string) (service.Explanation, error) {
+	args := m.Called(ctx, user, resource, mode, perms)
+	return args.Get(0).(service.Explanation), args.Error(1)
+}
+
+// 🔒 Compile-time interface check
+var _ service.AuthzService = (*AuthzServiceMock)(nil)
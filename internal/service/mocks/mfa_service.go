@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type MFAServiceMock struct {
+	mock.Mock
+}
+
+func (m *MFAServiceMock) Enroll(ctx context.Context, user *models.User) (*service.TOTPSetup, error) {
+	args := m.Called(ctx, user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TOTPSetup), args.Error(1)
+}
+
+func (m *MFAServiceMock) Confirm(ctx context.Context, user *models.User, code string) ([]string, error) {
+	args := m.Called(ctx, user, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MFAServiceMock) Verify(ctx context.Context, userID uint, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MFAServiceMock) Lockout(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MFAServiceMock) Attempt(ctx context.Context, userID uint, ok bool) error {
+	args := m.Called(ctx, userID, ok)
+	return args.Error(0)
+}
+
+func (m *MFAServiceMock) Challenge(ctx context.Context, userID uint, client service.ClientInfo) (*service.MFAChallenge, error) {
+	args := m.Called(ctx, userID, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.MFAChallenge), args.Error(1)
+}
+
+func (m *MFAServiceMock) Complete(ctx context.Context, token, code string) (uint, service.ClientInfo, error) {
+	args := m.Called(ctx, token, code)
+	return args.Get(0).(uint), args.Get(1).(service.ClientInfo), args.Error(2)
+}
+
+var _ service.MFAService = (*MFAServiceMock)(nil)
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type PasswordResetServiceMock struct {
+	mock.Mock
+}
+
+func (m *PasswordResetServiceMock) Forgot(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *PasswordResetServiceMock) Reset(ctx context.Context, token, password string) error {
+	args := m.Called(ctx, token, password)
+	return args.Error(0)
+}
+
+func (m *PasswordResetServiceMock) Wait() {
+	m.Called()
+}
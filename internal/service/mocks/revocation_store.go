@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type RevocationStoreMock struct {
+	mock.Mock
+}
+
+func (m *RevocationStoreMock) RevokeToken(ctx context.Context, userID uint, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *RevocationStoreMock) RevokeUser(ctx context.Context, userID uint, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *RevocationStoreMock) RevokeSession(ctx context.Context, userID, sessionID uint, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, sessionID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *RevocationStoreMock) IsRevoked(userID, sessionID uint, jti string, issuedAt time.Time) bool {
+	args := m.Called(userID, sessionID, jti, issuedAt)
+	return args.Bool(0)
+}
+
+func (m *RevocationStoreMock) Prune(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *RevocationStoreMock) Run(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+var _ service.RevocationStore = (*RevocationStoreMock)(nil)
@@ -0,0 +1,18 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionActivityMock struct {
+	mock.Mock
+}
+
+func (m *SessionActivityMock) Touch(ctx context.Context, sessionID uint) {
+	m.Called(ctx, sessionID)
+}
+
+var _ service.SessionActivity = (*SessionActivityMock)(nil)
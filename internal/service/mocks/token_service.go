@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type TokenServiceMock struct {
+	mock.Mock
+}
+
+func (m *TokenServiceMock) Issue(ctx context.Context, user *models.User, client service.ClientInfo, methods []string) (*service.TokenPair, error) {
+	args := m.Called(ctx, user, client, methods)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TokenPair), args.Error(1)
+}
+
+func (m *TokenServiceMock) Refresh(ctx context.Context, refreshToken string) (*service.TokenPair, error) {
+	args := m.Called(ctx, refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TokenPair), args.Error(1)
+}
+
+func (m *TokenServiceMock) Reauthenticate(ctx context.Context, user *models.User, sessionID uint, methods []string) (*service.TokenPair, error) {
+	args := m.Called(ctx, user, sessionID, methods)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TokenPair), args.Error(1)
+}
+
+func (m *TokenServiceMock) Revoke(ctx context.Context, refreshToken string) error {
+	args := m.Called(ctx, refreshToken)
+	return args.Error(0)
+}
+
+func (m *TokenServiceMock) RevokeAccess(ctx context.Context, userID uint, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *TokenServiceMock) RevokeAll(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *TokenServiceMock) Sessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *TokenServiceMock) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *TokenServiceMock) RevokeOtherSessions(ctx context.Context, userID, keepID uint) error {
+	args := m.Called(ctx, userID, keepID)
+	return args.Error(0)
+}
+
+var _ service.TokenService = (*TokenServiceMock)(nil)
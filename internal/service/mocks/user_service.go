@@ -5,6 +5,7 @@ import (
 
 	"github.com/corradoisidoro/sentinel-rbac/internal/models"
 	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -20,9 +21,84 @@ func (m *UserServiceMock) Register(ctx context.Context, email, password, role st
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *UserServiceMock) Login(ctx context.Context, email, password string) (string, error) {
-	args := m.Called(ctx, email, password)
-	return args.String(0), args.Error(1)
+func (m *UserServiceMock) Login(ctx context.Context, email, password string, client service.ClientInfo) (*service.TokenPair, *service.MFAChallenge, error) {
+	args := m.Called(ctx, email, password, client)
+	pair, _ := args.Get(0).(*service.TokenPair)
+	challenge, _ := args.Get(1).(*service.MFAChallenge)
+	return pair, challenge, args.Error(2)
+}
+
+func (m *UserServiceMock) VerifyMFA(ctx context.Context, mfaToken, code string) (*service.TokenPair, error) {
+	args := m.Called(ctx, mfaToken, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TokenPair), args.Error(1)
+}
+
+func (m *UserServiceMock) Reauthenticate(ctx context.Context, userID, sessionID uint, password, code string) (*service.TokenPair, error) {
+	args := m.Called(ctx, userID, sessionID, password, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TokenPair), args.Error(1)
+}
+
+func (m *UserServiceMock) Refresh(ctx context.Context, refreshToken string) (*service.TokenPair, error) {
+	args := m.Called(ctx, refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TokenPair), args.Error(1)
+}
+
+func (m *UserServiceMock) Logout(ctx context.Context, access *authz.Claims, refreshToken string) error {
+	args := m.Called(ctx, access, refreshToken)
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) ChangePassword(ctx context.Context, userID uint, current, password string) error {
+	args := m.Called(ctx, userID, current, password)
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) RevokeTokens(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) Sessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *UserServiceMock) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) RevokeOtherSessions(ctx context.Context, userID, currentID uint) error {
+	args := m.Called(ctx, userID, currentID)
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) FindUser(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
 }
 
 // 🔒 Compile-time interface check
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/config"
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/mail"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordResetService lets users who forgot their password set a new one
+// through a single-use link sent by email.
+type PasswordResetService interface {
+	// Forgot emails a reset token to the user with email, if there is one.
+	// The token is stored and sent in the background, so the call takes as
+	// long for unknown addresses as for registered ones, and it succeeds
+	// even if the email cannot be sent. Callers cannot learn which addresses
+	// are registered.
+	Forgot(ctx context.Context, email string) error
+	// Reset sets a new password with a token from Forgot, then revokes all
+	// of the user's sessions. Tokens work once and expire after
+	// PASSWORD_RESET_TTL.
+	Reset(ctx context.Context, token, password string) error
+	// Wait blocks until the emails Forgot started sending are done.
+	Wait()
+}
+
+// passwordResetSendTimeout bounds storing and sending one reset token in the
+// background, once the request that asked for it is gone.
+const passwordResetSendTimeout = time.Minute
+
+type passwordResetService struct {
+	resets repository.PasswordResetRepository
+	users  repository.UserRepository
+	tokens TokenService
+	mailer mail.Mailer
+	config config.Config
+	now    func() time.Time
+
+	pending sync.WaitGroup
+}
+
+// NewPasswordResetService returns a PasswordResetService sending links to
+// PASSWORD_RESET_URL through mailer.
+func NewPasswordResetService(resets repository.PasswordResetRepository, users repository.UserRepository, tokens TokenService, mailer mail.Mailer, cfg config.Config) PasswordResetService {
+	if cfg.PasswordResetTTL == 0 {
+		cfg.PasswordResetTTL = config.DefaultPasswordResetTTL
+	}
+	return &passwordResetService{resets: resets, users: users, tokens: tokens, mailer: mailer, config: cfg, now: time.Now}
+}
+
+func (s *passwordResetService) Forgot(ctx context.Context, email string) error {
+	if email == "" {
+		return appErr.ErrInvalidInput
+	}
+
+	user, err := s.users.FindByEmail(ctx, email)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+	if user == nil {
+		return nil
+	}
+
+	// The request must not wait on the database write or the mail server:
+	// how long it takes would tell registered addresses apart.
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetSendTimeout)
+	s.pending.Go(func() {
+		defer cancel()
+		if err := s.send(sendCtx, user); err != nil {
+			log.Printf("[WARN] sending password reset email to user %d failed: %v", user.ID, err)
+		}
+	})
+	return nil
+}
+
+func (s *passwordResetService) Wait() {
+	s.pending.Wait()
+}
+
+// send stores a new reset token for user and emails it to them.
+func (s *passwordResetService) send(ctx context.Context, user *models.User) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.resets.Create(ctx, &models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: s.now().Add(s.config.PasswordResetTTL),
+	}); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, s.message(user.Email, token))
+}
+
+func (s *passwordResetService) Reset(ctx context.Context, token, password string) error {
+	if token == "" || len(password) < 8 {
+		return appErr.ErrInvalidInput
+	}
+
+	reset, err := s.resets.FindByHash(ctx, hashToken(token))
+	if err != nil {
+		return appErr.ErrInternal
+	}
+
+	now := s.now()
+	if reset == nil || reset.UsedAt != nil || !now.Before(reset.ExpiresAt) {
+		return appErr.ErrInvalidResetToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+
+	// A concurrent request may have used the token since it was read.
+	used, err := s.resets.ResetPassword(ctx, reset.ID, reset.UserID, string(hashedPassword), now)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+	if !used {
+		return appErr.ErrInvalidResetToken
+	}
+
+	return s.tokens.RevokeAll(ctx, reset.UserID)
+}
+
+func (s *passwordResetService) message(to, token string) mail.Message {
+	action := "Use this token to reset it:\n" + token
+	if s.config.PasswordResetURL != "" {
+		if link, err := url.Parse(s.config.PasswordResetURL); err == nil {
+			q := link.Query()
+			q.Set("token", token)
+			link.RawQuery = q.Encode()
+			action = "Reset it here:\n" + link.String()
+		}
+	}
+
+	return mail.Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password of your account.\n\n%s\n\nIt works once and expires in %d minutes. If you did not ask for it, ignore this email; your password has not changed.\n",
+			action, int(s.config.PasswordResetTTL.Minutes()),
+		),
+	}
+}
@@ -0,0 +1,196 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/config"
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/mail"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	serviceMocks "github.com/corradoisidoro/sentinel-rbac/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var resetConfig = config.Config{
+	PasswordResetTTL: 30 * time.Minute,
+	PasswordResetURL: "https://app.example.com/reset-password?lang=en",
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, mail.Message) error {
+	return errors.New("smtp unavailable")
+}
+
+func TestPasswordResetService_Forgot(t *testing.T) {
+	resets := new(mocks.PasswordResetRepositoryMock)
+	users := new(mocks.UserRepositoryMock)
+	outbox := mail.NewOutbox()
+	svc := service.NewPasswordResetService(resets, users, new(serviceMocks.TokenServiceMock), outbox, resetConfig)
+
+	users.On("FindByEmail", mock.Anything, "alice@example.com").
+		Return(&models.User{Model: gorm.Model{ID: 7}, Email: "alice@example.com"}, nil)
+
+	var stored *models.PasswordReset
+	resets.On("Create", mock.Anything, mock.AnythingOfType("*models.PasswordReset")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.PasswordReset) }).
+		Return(nil)
+
+	require.NoError(t, svc.Forgot(context.Background(), "alice@example.com"))
+	svc.Wait()
+
+	messages := outbox.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "alice@example.com", messages[0].To)
+
+	link := messages[0].Body[strings.Index(messages[0].Body, "https://"):]
+	link = link[:strings.IndexAny(link, "\n")]
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "en", u.Query().Get("lang"))
+	token := u.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	require.NotNil(t, stored)
+	assert.Equal(t, uint(7), stored.UserID)
+	sum := sha256.Sum256([]byte(token))
+	assert.Equal(t, hex.EncodeToString(sum[:]), stored.TokenHash, "only the hash is stored")
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), stored.ExpiresAt, time.Minute)
+}
+
+func TestPasswordResetService_Forgot_DoesNotRevealAccounts(t *testing.T) {
+	t.Run("unknown email", func(t *testing.T) {
+		resets := new(mocks.PasswordResetRepositoryMock)
+		users := new(mocks.UserRepositoryMock)
+		outbox := mail.NewOutbox()
+		svc := service.NewPasswordResetService(resets, users, new(serviceMocks.TokenServiceMock), outbox, resetConfig)
+
+		users.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, nil)
+
+		require.NoError(t, svc.Forgot(context.Background(), "nobody@example.com"))
+		svc.Wait()
+		assert.Empty(t, outbox.Messages())
+		resets.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("mail failure", func(t *testing.T) {
+		resets := new(mocks.PasswordResetRepositoryMock)
+		users := new(mocks.UserRepositoryMock)
+		svc := service.NewPasswordResetService(resets, users, new(serviceMocks.TokenServiceMock), failingMailer{}, resetConfig)
+
+		users.On("FindByEmail", mock.Anything, "alice@example.com").
+			Return(&models.User{Model: gorm.Model{ID: 7}, Email: "alice@example.com"}, nil)
+		resets.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		require.NoError(t, svc.Forgot(context.Background(), "alice@example.com"))
+		svc.Wait()
+		resets.AssertExpectations(t)
+	})
+}
+
+// slowMailer blocks every Send until release is closed.
+type slowMailer struct {
+	release chan struct{}
+	sent    chan error
+}
+
+func (m *slowMailer) Send(ctx context.Context, _ mail.Message) error {
+	<-m.release
+	m.sent <- ctx.Err()
+	return nil
+}
+
+func TestPasswordResetService_Forgot_DoesNotWaitForMail(t *testing.T) {
+	resets := new(mocks.PasswordResetRepositoryMock)
+	users := new(mocks.UserRepositoryMock)
+	mailer := &slowMailer{release: make(chan struct{}), sent: make(chan error, 1)}
+	svc := service.NewPasswordResetService(resets, users, new(serviceMocks.TokenServiceMock), mailer, resetConfig)
+
+	users.On("FindByEmail", mock.Anything, "alice@example.com").
+		Return(&models.User{Model: gorm.Model{ID: 7}, Email: "alice@example.com"}, nil)
+	resets.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- svc.Forgot(ctx, "alice@example.com") }()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Forgot waited for the mailer")
+	}
+
+	// The email outlives the request that asked for it.
+	cancel()
+	close(mailer.release)
+	svc.Wait()
+	assert.NoError(t, <-mailer.sent)
+}
+
+func TestPasswordResetService_Reset(t *testing.T) {
+	resets := new(mocks.PasswordResetRepositoryMock)
+	tokens := new(serviceMocks.TokenServiceMock)
+	svc := service.NewPasswordResetService(resets, new(mocks.UserRepositoryMock), tokens, mail.NewOutbox(), resetConfig)
+
+	sum := sha256.Sum256([]byte("reset-token"))
+	resets.On("FindByHash", mock.Anything, hex.EncodeToString(sum[:])).
+		Return(&models.PasswordReset{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+	resets.On("ResetPassword", mock.Anything, uint(1), uint(7), mock.MatchedBy(func(h string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(h), []byte("new-password")) == nil
+	}), mock.Anything).Return(true, nil)
+	tokens.On("RevokeAll", mock.Anything, uint(7)).Return(nil)
+
+	require.NoError(t, svc.Reset(context.Background(), "reset-token", "new-password"))
+	resets.AssertExpectations(t)
+	tokens.AssertExpectations(t)
+}
+
+func TestPasswordResetService_Reset_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		reset    *models.PasswordReset
+		used     bool
+		err      error
+	}{
+		{"short password", "short", &models.PasswordReset{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(time.Minute)}, true, appErr.ErrInvalidInput},
+		{"unknown token", "new-password", nil, true, appErr.ErrInvalidResetToken},
+		{"expired", "new-password", &models.PasswordReset{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(-time.Minute)}, true, appErr.ErrInvalidResetToken},
+		{"already used", "new-password", &models.PasswordReset{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(time.Minute), UsedAt: ptrTime(time.Now())}, true, appErr.ErrInvalidResetToken},
+		{"used concurrently", "new-password", &models.PasswordReset{ID: 1, UserID: 7, ExpiresAt: time.Now().Add(time.Minute)}, false, appErr.ErrInvalidResetToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resets := new(mocks.PasswordResetRepositoryMock)
+			tokens := new(serviceMocks.TokenServiceMock)
+			svc := service.NewPasswordResetService(resets, new(mocks.UserRepositoryMock), tokens, mail.NewOutbox(), resetConfig)
+
+			if tt.reset == nil {
+				resets.On("FindByHash", mock.Anything, mock.Anything).Return(nil, nil)
+			} else {
+				resets.On("FindByHash", mock.Anything, mock.Anything).Return(tt.reset, nil)
+			}
+			resets.On("ResetPassword", mock.Anything, uint(1), uint(7), mock.Anything, mock.Anything).Return(tt.used, nil)
+
+			err := svc.Reset(context.Background(), "reset-token", tt.password)
+
+			assert.Equal(t, tt.err, err)
+			tokens.AssertNotCalled(t, "RevokeAll", mock.Anything, mock.Anything)
+		})
+	}
+}
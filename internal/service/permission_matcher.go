@@ -0,0 +1,22 @@
+package service
+
+import "github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+
+// PermissionSeparator splits a permission into namespaced segments, e.g.
+// "billing:invoices:read" has the segments billing, invoices and read.
+const PermissionSeparator = authz.Separator
+
+// PermissionWildcard matches any segment of a permission.
+const PermissionWildcard = authz.Wildcard
+
+// MatchPermission reports whether a granted permission covers the required
+// one. It is authz.Match, which documents the matching rules.
+func MatchPermission(grant, required string) bool {
+	return authz.Match(grant, required)
+}
+
+// BestMatch returns the grant that covers the required permission with the
+// highest precedence. It is authz.BestMatch, which documents the precedence.
+func BestMatch(grants []string, required string) (string, bool) {
+	return authz.BestMatch(grants, required)
+}
@@ -0,0 +1,65 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPermission(t *testing.T) {
+	tests := []struct {
+		name     string
+		grant    string
+		required string
+		want     bool
+	}{
+		{"exact match", "billing:invoices:read", "billing:invoices:read", true},
+		{"different action", "billing:invoices:read", "billing:invoices:write", false},
+		{"global wildcard", "*", "billing:invoices:read", true},
+		{"trailing wildcard covers subtree", "billing:*", "billing:invoices:read", true},
+		{"trailing wildcard covers direct child", "billing:*", "billing:invoices", true},
+		{"trailing wildcard needs a segment", "billing:*", "billing", false},
+		{"trailing wildcard other namespace", "billing:*", "users:delete", false},
+		{"leading wildcard single segment", "*:read", "invoices:read", true},
+		{"leading wildcard does not span", "*:read", "billing:invoices:read", false},
+		{"middle wildcard", "billing:*:read", "billing:invoices:read", true},
+		{"middle wildcard wrong action", "billing:*:read", "billing:invoices:write", false},
+		{"grant longer than required", "billing:invoices:read", "billing:invoices", false},
+		{"grant shorter than required", "billing:invoices", "billing:invoices:read", false},
+		{"required wildcard is literal", "billing:invoices:read", "billing:*", false},
+		{"empty grant", "", "billing:invoices:read", false},
+		{"empty segment", "billing::read", "billing:x:read", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, service.MatchPermission(tt.grant, tt.required))
+		})
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		grants   []string
+		required string
+		want     string
+		found    bool
+	}{
+		{"no grants", nil, "billing:invoices:read", "", false},
+		{"no match", []string{"users:read"}, "billing:invoices:read", "", false},
+		{"exact beats wildcard", []string{"*", "billing:*", "billing:invoices:read"}, "billing:invoices:read", "billing:invoices:read", true},
+		{"more literals win", []string{"*", "*:invoices:read", "billing:*"}, "billing:invoices:read", "*:invoices:read", true},
+		{"deeper prefix wins on tie", []string{"*:invoices:*", "billing:*:read"}, "billing:invoices:read", "billing:*:read", true},
+		{"narrower subtree wins", []string{"*", "billing:*", "billing:invoices:*"}, "billing:invoices:read", "billing:invoices:*", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := service.BestMatch(tt.grants, tt.required)
+			require.Equal(t, tt.found, found)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+)
+
+// RevocationStore records revoked access tokens. Revocations are written to
+// the database and mirrored in memory, so IsRevoked never touches the
+// database; Run keeps the cache in sync with revocations made by other
+// instances and prunes entries whose tokens have expired.
+type RevocationStore interface {
+	// RevokeToken revokes the access token with the given jti.
+	RevokeToken(ctx context.Context, userID uint, jti string, expiresAt time.Time) error
+	// RevokeUser revokes every access token issued to the user before now.
+	// expiresAt must be no earlier than the expiry of the newest such token.
+	RevokeUser(ctx context.Context, userID uint, expiresAt time.Time) error
+	// RevokeSession revokes every access token of the session.
+	RevokeSession(ctx context.Context, userID, sessionID uint, expiresAt time.Time) error
+	// IsRevoked reports whether a token is revoked. sessionID is 0 for tokens
+	// without a "sid" claim.
+	IsRevoked(userID, sessionID uint, jti string, issuedAt time.Time) bool
+	Prune(ctx context.Context) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type revocationStore struct {
+	repo repository.RevokedTokenRepository
+	now  func() time.Time
+
+	mu       sync.RWMutex
+	tokens   map[string]struct{}
+	sessions map[uint]struct{}
+	users    map[uint]time.Time // tokens issued before are revoked
+}
+
+// NewRevocationStore returns a RevocationStore backed by repo, with the cache
+// loaded from the revocations that have not expired yet.
+func NewRevocationStore(ctx context.Context, repo repository.RevokedTokenRepository) (RevocationStore, error) {
+	s := &revocationStore{repo: repo, now: time.Now}
+	if err := s.reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *revocationStore) RevokeToken(ctx context.Context, userID uint, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+
+	entry := models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		RevokedAt: s.now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Create(ctx, &entry); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(entry)
+	return nil
+}
+
+func (s *revocationStore) RevokeUser(ctx context.Context, userID uint, expiresAt time.Time) error {
+	// iat has second precision, so tokens issued within the current second
+	// are kept: otherwise a token issued right after revoking would be
+	// revoked too.
+	entry := models.RevokedToken{
+		UserID:    userID,
+		RevokedAt: s.now().Truncate(time.Second),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Create(ctx, &entry); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(entry)
+	return nil
+}
+
+func (s *revocationStore) RevokeSession(ctx context.Context, userID, sessionID uint, expiresAt time.Time) error {
+	if sessionID == 0 {
+		return nil
+	}
+
+	entry := models.RevokedToken{
+		SessionID: sessionID,
+		UserID:    userID,
+		RevokedAt: s.now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Create(ctx, &entry); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(entry)
+	return nil
+}
+
+func (s *revocationStore) IsRevoked(userID, sessionID uint, jti string, issuedAt time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[jti]; ok && jti != "" {
+		return true
+	}
+	if _, ok := s.sessions[sessionID]; ok && sessionID != 0 {
+		return true
+	}
+	before, ok := s.users[userID]
+	return ok && issuedAt.Before(before)
+}
+
+func (s *revocationStore) Prune(ctx context.Context) error {
+	if _, err := s.repo.DeleteExpired(ctx, s.now()); err != nil {
+		return err
+	}
+	return s.reload(ctx)
+}
+
+func (s *revocationStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Prune(ctx); err != nil {
+				log.Printf("[WARN] pruning revoked tokens failed: %v", err)
+			}
+		}
+	}
+}
+
+func (s *revocationStore) reload(ctx context.Context) error {
+	entries, err := s.repo.FindActive(ctx, s.now())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = make(map[string]struct{}, len(entries))
+	s.sessions = make(map[uint]struct{})
+	s.users = make(map[uint]time.Time)
+	for _, e := range entries {
+		s.add(e)
+	}
+	return nil
+}
+
+// add caches entry; the caller must hold s.mu.
+func (s *revocationStore) add(e models.RevokedToken) {
+	if e.JTI != "" {
+		s.tokens[e.JTI] = struct{}{}
+		return
+	}
+	if e.SessionID != 0 {
+		s.sessions[e.SessionID] = struct{}{}
+		return
+	}
+	if e.RevokedAt.After(s.users[e.UserID]) {
+		s.users[e.UserID] = e.RevokedAt
+	}
+}
@@ -0,0 +1,58 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.RevokedTokenRepositoryMock)
+	now := time.Now()
+
+	repo.On("FindActive", mock.Anything, mock.Anything).Return([]models.RevokedToken{
+		{JTI: "loaded", UserID: 1, RevokedAt: now, ExpiresAt: now.Add(time.Hour)},
+	}, nil).Once()
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*models.RevokedToken")).Return(nil)
+
+	store, err := service.NewRevocationStore(ctx, repo)
+	require.NoError(t, err)
+
+	assert.True(t, store.IsRevoked(1, 0, "loaded", now), "loaded from the database")
+	assert.False(t, store.IsRevoked(1, 0, "other", now))
+	assert.False(t, store.IsRevoked(1, 0, "", now))
+
+	require.NoError(t, store.RevokeToken(ctx, 2, "logout", now.Add(time.Hour)))
+	assert.True(t, store.IsRevoked(2, 0, "logout", now))
+
+	require.NoError(t, store.RevokeUser(ctx, 3, now.Add(time.Hour)))
+	assert.True(t, store.IsRevoked(3, 0, "any", now.Add(-time.Minute)), "issued before the revocation")
+	assert.True(t, store.IsRevoked(3, 0, "", time.Time{}), "tokens without iat")
+	assert.False(t, store.IsRevoked(3, 0, "any", now.Add(time.Second)), "issued after the revocation")
+	assert.False(t, store.IsRevoked(4, 0, "any", now.Add(-time.Minute)))
+
+	require.NoError(t, store.RevokeSession(ctx, 5, 9, now.Add(time.Hour)))
+	assert.True(t, store.IsRevoked(5, 9, "any", now.Add(time.Second)), "every token of the session")
+	assert.False(t, store.IsRevoked(5, 10, "any", now.Add(-time.Minute)), "other sessions")
+	assert.False(t, store.IsRevoked(5, 0, "any", now.Add(-time.Minute)), "tokens without sid")
+
+	// Pruning reloads the cache from the database, dropping what expired there.
+	repo.On("DeleteExpired", mock.Anything, mock.Anything).Return(int64(3), nil)
+	repo.On("FindActive", mock.Anything, mock.Anything).Return([]models.RevokedToken{
+		{JTI: "logout", UserID: 2, RevokedAt: now, ExpiresAt: now.Add(time.Hour)},
+	}, nil).Once()
+
+	require.NoError(t, store.Prune(ctx))
+	assert.False(t, store.IsRevoked(1, 0, "loaded", now))
+	assert.True(t, store.IsRevoked(2, 0, "logout", now))
+	assert.False(t, store.IsRevoked(3, 0, "any", now.Add(-time.Minute)))
+	repo.AssertExpectations(t)
+}
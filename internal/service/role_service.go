@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+)
+
+type RoleService interface {
+	Create(ctx context.Context, name, description string) (*models.Role, error)
+	AddParent(ctx context.Context, roleID, parentID uint) error
+	RemoveParent(ctx context.Context, roleID, parentID uint) error
+}
+
+type roleService struct {
+	repo repository.RoleRepository
+}
+
+func NewRoleService(repo repository.RoleRepository) RoleService {
+	return &roleService{repo: repo}
+}
+
+func (s *roleService) Create(ctx context.Context, name, description string) (*models.Role, error) {
+	if name == "" {
+		return nil, appErr.ErrInvalidInput
+	}
+
+	existing, err := s.repo.FindByName(ctx, name)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	if existing != nil {
+		return nil, appErr.ErrInvalidRole
+	}
+
+	role := &models.Role{Name: name, Description: description}
+	if err := s.repo.Create(ctx, role); err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	return role, nil
+}
+
+// AddParent makes roleID inherit the grants of parentID. The edge is rejected
+// with ErrRoleCycle if parentID already inherits, directly or transitively,
+// from roleID.
+func (s *roleService) AddParent(ctx context.Context, roleID, parentID uint) error {
+	if roleID == parentID {
+		return appErr.ErrRoleCycle
+	}
+
+	for _, id := range []uint{roleID, parentID} {
+		role, err := s.repo.FindById(ctx, id)
+		if err != nil {
+			return appErr.ErrInternal
+		}
+		if role == nil {
+			return appErr.ErrRoleNotFound
+		}
+	}
+
+	edges, err := s.repo.ListInheritance(ctx)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+
+	for _, id := range ancestorRoleIds(edges, []uint{parentID}) {
+		if id == roleID {
+			return appErr.ErrRoleCycle
+		}
+	}
+
+	if err := s.repo.AddParent(ctx, roleID, parentID); err != nil {
+		return appErr.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *roleService) RemoveParent(ctx context.Context, roleID, parentID uint) error {
+	if err := s.repo.RemoveParent(ctx, roleID, parentID); err != nil {
+		return appErr.ErrInternal
+	}
+	return nil
+}
+
+// ancestorRoleIds returns the given role IDs followed by every role they
+// inherit from, walking the hierarchy breadth-first. Each ID appears once, so
+// a cycle that slipped into the table cannot cause an infinite loop.
+func ancestorRoleIds(edges []models.RoleInheritance, start []uint) []uint {
+	ids, _ := walkAncestors(edges, start)
+	return ids
+}
+
+// walkAncestors is ancestorRoleIds that also returns, for every inherited
+// role, the ID of the role it was first reached from.
+func walkAncestors(edges []models.RoleInheritance, start []uint) ([]uint, map[uint]uint) {
+	parents := make(map[uint][]uint, len(edges))
+	for _, e := range edges {
+		parents[e.RoleID] = append(parents[e.RoleID], e.ParentID)
+	}
+
+	type step struct {
+		id, from  uint
+		inherited bool
+	}
+
+	seen := make(map[uint]bool, len(start))
+	via := make(map[uint]uint)
+	result := make([]uint, 0, len(start))
+	queue := make([]step, 0, len(start))
+	for _, id := range start {
+		queue = append(queue, step{id: id})
+	}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		if seen[cur.id] {
+			continue
+		}
+		seen[cur.id] = true
+		result = append(result, cur.id)
+		if cur.inherited {
+			via[cur.id] = cur.from
+		}
+
+		for _, p := range parents[cur.id] {
+			queue = append(queue, step{id: p, from: cur.id, inherited: true})
+		}
+	}
+
+	return result, via
+}
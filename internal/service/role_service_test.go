@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestRoleService_AddParent_Success(t *testing.T) {
+	repo := new(mocks.RoleRepositoryMock)
+	svc := service.NewRoleService(repo)
+
+	repo.On("FindById", mock.Anything, uint(1)).Return(&models.Role{Model: gorm.Model{ID: 1}, Name: "admin"}, nil)
+	repo.On("FindById", mock.Anything, uint(2)).Return(&models.Role{Model: gorm.Model{ID: 2}, Name: "editor"}, nil)
+	repo.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{{RoleID: 2, ParentID: 3}}, nil)
+	repo.On("AddParent", mock.Anything, uint(1), uint(2)).Return(nil)
+
+	err := svc.AddParent(context.Background(), 1, 2)
+
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestRoleService_AddParent_DetectsCycle(t *testing.T) {
+	repo := new(mocks.RoleRepositoryMock)
+	svc := service.NewRoleService(repo)
+
+	// admin -> editor -> viewer already exists; viewer -> admin would close the loop.
+	repo.On("FindById", mock.Anything, uint(3)).Return(&models.Role{Model: gorm.Model{ID: 3}, Name: "viewer"}, nil)
+	repo.On("FindById", mock.Anything, uint(1)).Return(&models.Role{Model: gorm.Model{ID: 1}, Name: "admin"}, nil)
+	repo.On("ListInheritance", mock.Anything).
+		Return([]models.RoleInheritance{
+			{RoleID: 1, ParentID: 2},
+			{RoleID: 2, ParentID: 3},
+		}, nil)
+
+	err := svc.AddParent(context.Background(), 3, 1)
+
+	assert.Equal(t, appErr.ErrRoleCycle, err)
+	repo.AssertNotCalled(t, "AddParent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRoleService_AddParent_SelfReference(t *testing.T) {
+	repo := new(mocks.RoleRepositoryMock)
+	svc := service.NewRoleService(repo)
+
+	err := svc.AddParent(context.Background(), 1, 1)
+
+	assert.Equal(t, appErr.ErrRoleCycle, err)
+}
+
+func TestRoleService_AddParent_UnknownRole(t *testing.T) {
+	repo := new(mocks.RoleRepositoryMock)
+	svc := service.NewRoleService(repo)
+
+	repo.On("FindById", mock.Anything, uint(1)).Return(nil, nil)
+
+	err := svc.AddParent(context.Background(), 1, 2)
+
+	assert.Equal(t, appErr.ErrRoleNotFound, err)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+)
+
+// SessionActivity records when sessions were last used to authenticate a
+// request. Writes are throttled: a session is written at most once per
+// interval on each instance, so Session.LastUsedAt may lag by that much.
+type SessionActivity interface {
+	Touch(ctx context.Context, sessionID uint)
+}
+
+type sessionActivity struct {
+	sessions repository.SessionRepository
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	touched map[uint]time.Time
+}
+
+// NewSessionActivity returns a SessionActivity writing to sessions at most
+// once per interval for each session.
+func NewSessionActivity(sessions repository.SessionRepository, interval time.Duration) SessionActivity {
+	return &sessionActivity{
+		sessions: sessions,
+		interval: interval,
+		now:      time.Now,
+		touched:  make(map[uint]time.Time),
+	}
+}
+
+func (a *sessionActivity) Touch(ctx context.Context, sessionID uint) {
+	if sessionID == 0 {
+		return
+	}
+
+	now := a.now()
+	a.mu.Lock()
+	if last, ok := a.touched[sessionID]; ok && now.Sub(last) < a.interval {
+		a.mu.Unlock()
+		return
+	}
+	a.touched[sessionID] = now
+	a.prune(now)
+	a.mu.Unlock()
+
+	if err := a.sessions.MarkUsed(ctx, sessionID, now); err != nil {
+		log.Printf("[WARN] failed to record use of session %d: %v", sessionID, err)
+		a.mu.Lock()
+		delete(a.touched, sessionID)
+		a.mu.Unlock()
+	}
+}
+
+// prune forgets sessions not touched within the last interval, as they would
+// be written on their next use anyway. a.mu must be held.
+func (a *sessionActivity) prune(now time.Time) {
+	if len(a.touched) < 1024 {
+		return
+	}
+	for id, last := range a.touched {
+		if now.Sub(last) >= a.interval {
+			delete(a.touched, id)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSessionActivity_ThrottlesWrites(t *testing.T) {
+	ctx := context.Background()
+	sessions := new(mocks.SessionRepositoryMock)
+	sessions.On("MarkUsed", mock.Anything, uint(1), mock.Anything).Return(nil).Once()
+	sessions.On("MarkUsed", mock.Anything, uint(2), mock.Anything).Return(nil).Once()
+
+	activity := service.NewSessionActivity(sessions, time.Hour)
+	activity.Touch(ctx, 1)
+	activity.Touch(ctx, 1)
+	activity.Touch(ctx, 2)
+	activity.Touch(ctx, 0)
+
+	sessions.AssertExpectations(t)
+	sessions.AssertNumberOfCalls(t, "MarkUsed", 2)
+}
+
+func TestSessionActivity_RetriesFailedWrites(t *testing.T) {
+	ctx := context.Background()
+	sessions := new(mocks.SessionRepositoryMock)
+	sessions.On("MarkUsed", mock.Anything, uint(1), mock.Anything).Return(errors.New("db down")).Once()
+	sessions.On("MarkUsed", mock.Anything, uint(1), mock.Anything).Return(nil).Once()
+
+	activity := service.NewSessionActivity(sessions, time.Hour)
+	activity.Touch(ctx, 1)
+	activity.Touch(ctx, 1)
+	activity.Touch(ctx, 1)
+
+	sessions.AssertExpectations(t)
+	sessions.AssertNumberOfCalls(t, "MarkUsed", 2)
+}
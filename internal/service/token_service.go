@@ -0,0 +1,419 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/config"
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
+	"github.com/golang-jwt/jwt"
+)
+
+// TokenPair is a short-lived access token (a JWT) and the opaque refresh
+// token that renews it.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// ClientInfo describes where a login was made from, as recorded on its
+// session.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// Authentication methods reported in the "amr" claim (RFC 8176).
+const (
+	AuthMethodPassword = "pwd"
+	AuthMethodOTP      = "otp"
+	AuthMethodMFA      = "mfa"
+)
+
+// TokenService issues token pairs and rotates refresh tokens. Every refresh
+// token is single-use: Refresh marks it used and returns a new pair of the
+// same family. Presenting a used token again is treated as theft and revokes
+// the whole family.
+//
+// Each family is a session: Issue starts one and access tokens name it in
+// their "sid" claim, so revoking a session also revokes its access tokens.
+// Access tokens also carry when and how the user last authenticated in the
+// session as "auth_time" and "amr"; refreshing does not change them.
+type TokenService interface {
+	// Issue starts a session for a user who authenticated with methods.
+	Issue(ctx context.Context, user *models.User, client ClientInfo, methods []string) (*TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	// Reauthenticate records that the user authenticated again with methods
+	// in one of their sessions and returns a new access token for it. The
+	// pair has no refresh token: the session's current one stays valid and
+	// carries the new auth_time on.
+	Reauthenticate(ctx context.Context, user *models.User, sessionID uint, methods []string) (*TokenPair, error)
+	// Revoke revokes a refresh token and its family.
+	Revoke(ctx context.Context, refreshToken string) error
+	// RevokeAccess revokes a single access token by its jti.
+	RevokeAccess(ctx context.Context, userID uint, jti string, expiresAt time.Time) error
+	// RevokeAll revokes every access and refresh token issued to the user.
+	RevokeAll(ctx context.Context, userID uint) error
+	// Sessions returns the user's active sessions, most recently used first.
+	Sessions(ctx context.Context, userID uint) ([]models.Session, error)
+	// RevokeSession revokes one of the user's sessions and all of its tokens.
+	RevokeSession(ctx context.Context, userID, sessionID uint) error
+	// RevokeOtherSessions revokes every active session of the user except
+	// keepID.
+	RevokeOtherSessions(ctx context.Context, userID, keepID uint) error
+}
+
+// TokenSigner signs access token claims. keyring.Keyring implements it.
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+type tokenService struct {
+	refresh     repository.RefreshTokenRepository
+	sessions    repository.SessionRepository
+	users       repository.UserRepository
+	revocations RevocationStore
+	signer      TokenSigner
+	config      config.Config
+	now         func() time.Time
+}
+
+// NewTokenService returns a TokenService signing access tokens with signer.
+// Zero token lifetimes fall back to the config defaults.
+func NewTokenService(refresh repository.RefreshTokenRepository, sessions repository.SessionRepository, users repository.UserRepository, revocations RevocationStore, signer TokenSigner, cfg config.Config) TokenService {
+	if cfg.AccessTokenTTL == 0 {
+		cfg.AccessTokenTTL = config.DefaultAccessTokenTTL
+	}
+	if cfg.RefreshTokenTTL == 0 {
+		cfg.RefreshTokenTTL = config.DefaultRefreshTokenTTL
+	}
+	return &tokenService{refresh: refresh, sessions: sessions, users: users, revocations: revocations, signer: signer, config: cfg, now: time.Now}
+}
+
+func (s *tokenService) Issue(ctx context.Context, user *models.User, client ClientInfo, methods []string) (*TokenPair, error) {
+	family, err := randomToken()
+	if err != nil {
+		return nil, appErr.ErrFailedToGenerateToken
+	}
+
+	now := s.now()
+	session := &models.Session{
+		UserID:      user.ID,
+		FamilyID:    family,
+		IPAddress:   client.IPAddress,
+		UserAgent:   client.UserAgent,
+		AuthTime:    &now,
+		AuthMethods: strings.Join(methods, " "),
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(s.config.RefreshTokenTTL),
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	return s.issue(ctx, user, session, now)
+}
+
+func (s *tokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, appErr.ErrInvalidInput
+	}
+
+	stored, err := s.refresh.FindByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	if stored == nil || stored.RevokedAt != nil {
+		return nil, appErr.ErrInvalidRefreshToken
+	}
+
+	now := s.now()
+
+	if stored.UsedAt != nil {
+		return nil, s.revokeReused(ctx, stored.FamilyID, now)
+	}
+
+	if !now.Before(stored.ExpiresAt) {
+		return nil, appErr.ErrInvalidRefreshToken
+	}
+
+	// A concurrent request may have rotated the token since it was read.
+	marked, err := s.refresh.MarkUsed(ctx, stored.ID, now)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	if !marked {
+		return nil, s.revokeReused(ctx, stored.FamilyID, now)
+	}
+
+	user, err := s.users.FindById(ctx, stored.UserID)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	if user == nil {
+		return nil, appErr.ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessions.FindByFamily(ctx, stored.FamilyID)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	if session == nil {
+		// Families issued before sessions were recorded get one on refresh.
+		session = &models.Session{
+			UserID:     user.ID,
+			FamilyID:   stored.FamilyID,
+			LastUsedAt: now,
+			ExpiresAt:  now.Add(s.config.RefreshTokenTTL),
+		}
+		err = s.sessions.Create(ctx, session)
+	} else {
+		err = s.sessions.Touch(ctx, session.ID, now, now.Add(s.config.RefreshTokenTTL))
+	}
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	return s.issue(ctx, user, session, now)
+}
+
+func (s *tokenService) Reauthenticate(ctx context.Context, user *models.User, sessionID uint, methods []string) (*TokenPair, error) {
+	session, err := s.sessions.FindByID(ctx, sessionID)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	now := s.now()
+
+	if session == nil || session.UserID != user.ID || session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return nil, appErr.ErrSessionNotFound
+	}
+
+	session.AuthTime = &now
+	session.AuthMethods = strings.Join(methods, " ")
+	if err := s.sessions.Reauthenticate(ctx, session.ID, now, session.AuthMethods); err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	pair := &TokenPair{}
+	pair.AccessToken, pair.AccessExpiresAt, err = s.accessToken(user, session, now)
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *tokenService) Revoke(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return appErr.ErrInvalidInput
+	}
+
+	stored, err := s.refresh.FindByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return appErr.ErrInternal
+	}
+	if stored == nil {
+		return appErr.ErrInvalidRefreshToken
+	}
+
+	return s.revokeFamily(ctx, stored.FamilyID, s.now())
+}
+
+func (s *tokenService) RevokeAccess(ctx context.Context, userID uint, jti string, expiresAt time.Time) error {
+	if err := s.revocations.RevokeToken(ctx, userID, jti, expiresAt); err != nil {
+		return appErr.ErrInternal
+	}
+	return nil
+}
+
+func (s *tokenService) RevokeAll(ctx context.Context, userID uint) error {
+	now := s.now()
+
+	// The user-wide cutoff has second precision and spares tokens issued
+	// earlier in the same second, so their sessions are revoked by ID too.
+	sessions, err := s.sessions.FindActiveByUser(ctx, userID, now)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+
+	if err := s.refresh.RevokeUser(ctx, userID, now); err != nil {
+		return appErr.ErrInternal
+	}
+	if err := s.sessions.RevokeUser(ctx, userID, now); err != nil {
+		return appErr.ErrInternal
+	}
+	for _, session := range sessions {
+		if err := s.revocations.RevokeSession(ctx, userID, session.ID, now.Add(s.config.AccessTokenTTL)); err != nil {
+			return appErr.ErrInternal
+		}
+	}
+	if err := s.revocations.RevokeUser(ctx, userID, now.Add(s.config.AccessTokenTTL)); err != nil {
+		return appErr.ErrInternal
+	}
+	return nil
+}
+
+func (s *tokenService) Sessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	sessions, err := s.sessions.FindActiveByUser(ctx, userID, s.now())
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	return sessions, nil
+}
+
+func (s *tokenService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	session, err := s.sessions.FindByID(ctx, sessionID)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+
+	now := s.now()
+
+	// Other users' sessions are reported as missing rather than forbidden.
+	if session == nil || session.UserID != userID || session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return appErr.ErrSessionNotFound
+	}
+
+	return s.revokeSession(ctx, session, now)
+}
+
+func (s *tokenService) RevokeOtherSessions(ctx context.Context, userID, keepID uint) error {
+	now := s.now()
+
+	sessions, err := s.sessions.FindActiveByUser(ctx, userID, now)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+
+	for i := range sessions {
+		if sessions[i].ID == keepID {
+			continue
+		}
+		if err := s.revokeSession(ctx, &sessions[i], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeSession revokes the session's refresh token family and every access
+// token issued within it.
+func (s *tokenService) revokeSession(ctx context.Context, session *models.Session, now time.Time) error {
+	if err := s.revokeFamily(ctx, session.FamilyID, now); err != nil {
+		return err
+	}
+	if err := s.revocations.RevokeSession(ctx, session.UserID, session.ID, now.Add(s.config.AccessTokenTTL)); err != nil {
+		return appErr.ErrInternal
+	}
+	return nil
+}
+
+// revokeFamily revokes a refresh token family and ends its session.
+func (s *tokenService) revokeFamily(ctx context.Context, familyID string, now time.Time) error {
+	if err := s.refresh.RevokeFamily(ctx, familyID, now); err != nil {
+		return appErr.ErrInternal
+	}
+	if err := s.sessions.RevokeFamily(ctx, familyID, now); err != nil {
+		return appErr.ErrInternal
+	}
+	return nil
+}
+
+func (s *tokenService) revokeReused(ctx context.Context, familyID string, now time.Time) error {
+	if err := s.revokeFamily(ctx, familyID, now); err != nil {
+		return err
+	}
+	return appErr.ErrRefreshTokenReused
+}
+
+func (s *tokenService) issue(ctx context.Context, user *models.User, session *models.Session, now time.Time) (*TokenPair, error) {
+	pair := &TokenPair{
+		RefreshExpiresAt: now.Add(s.config.RefreshTokenTTL),
+	}
+
+	var err error
+	pair.AccessToken, pair.AccessExpiresAt, err = s.accessToken(user, session, now)
+	if err != nil {
+		return nil, err
+	}
+
+	pair.RefreshToken, err = randomToken()
+	if err != nil {
+		return nil, appErr.ErrFailedToGenerateToken
+	}
+
+	if err := s.refresh.Create(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  session.FamilyID,
+		TokenHash: hashToken(pair.RefreshToken),
+		ExpiresAt: pair.RefreshExpiresAt,
+	}); err != nil {
+		return nil, appErr.ErrInternal
+	}
+
+	return pair, nil
+}
+
+// accessToken signs an access token for the user's session.
+func (s *tokenService) accessToken(user *models.User, session *models.Session, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(s.config.AccessTokenTTL)
+
+	jti, err := randomToken()
+	if err != nil {
+		return "", time.Time{}, appErr.ErrFailedToGenerateToken
+	}
+
+	claims := jwt.MapClaims{
+		"sub":  user.ID,
+		"sid":  session.ID,
+		"jti":  jti,
+		"iat":  now.Unix(),
+		"nbf":  now.Unix(),
+		"exp":  expiresAt.Unix(),
+		"role": user.Role,
+	}
+	if s.config.JWTIssuer != "" {
+		claims["iss"] = s.config.JWTIssuer
+	}
+	if s.config.JWTAudience != "" {
+		claims["aud"] = s.config.JWTAudience
+	}
+	// Sessions recorded before auth_time was tracked omit it, so
+	// RequireFreshAuth asks them to reauthenticate.
+	if session.AuthTime != nil {
+		claims["auth_time"] = session.AuthTime.Unix()
+	}
+	if methods := strings.Fields(session.AuthMethods); len(methods) > 0 {
+		claims["amr"] = methods
+	}
+
+	token, err := s.signer.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, appErr.ErrFailedToGenerateToken
+	}
+	return token, expiresAt, nil
+}
+
+// randomToken returns 32 random bytes, base64url encoded.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,356 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/internal/config"
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+	"github.com/corradoisidoro/sentinel-rbac/internal/keyring"
+	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	serviceMocks "github.com/corradoisidoro/sentinel-rbac/internal/service/mocks"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var tokenConfig = config.Config{
+	AccessTokenTTL:  15 * time.Minute,
+	RefreshTokenTTL: 24 * time.Hour,
+	JWTIssuer:       "sentinel-rbac",
+	JWTAudience:     "api",
+}
+
+func tokenKeys() *keyring.Keyring {
+	keys, err := keyring.New("k1", keyring.Key{ID: "k1", Secret: "secret"})
+	if err != nil {
+		panic(err)
+	}
+	return keys
+}
+
+type tokenFixture struct {
+	svc         service.TokenService
+	refresh     *mocks.RefreshTokenRepositoryMock
+	sessions    *mocks.SessionRepositoryMock
+	users       *mocks.UserRepositoryMock
+	revocations *serviceMocks.RevocationStoreMock
+}
+
+func newTokenService() *tokenFixture {
+	f := &tokenFixture{
+		refresh:     new(mocks.RefreshTokenRepositoryMock),
+		sessions:    new(mocks.SessionRepositoryMock),
+		users:       new(mocks.UserRepositoryMock),
+		revocations: new(serviceMocks.RevocationStoreMock),
+	}
+	f.svc = service.NewTokenService(f.refresh, f.sessions, f.users, f.revocations, tokenKeys(), tokenConfig)
+	return f
+}
+
+func TestTokenService_Issue(t *testing.T) {
+	f := newTokenService()
+	user := &models.User{Model: gorm.Model{ID: 7}, Role: "editor"}
+
+	var session *models.Session
+	f.sessions.On("Create", mock.Anything, mock.AnythingOfType("*models.Session")).
+		Run(func(args mock.Arguments) {
+			session = args.Get(1).(*models.Session)
+			session.ID = 3
+		}).
+		Return(nil)
+
+	var stored *models.RefreshToken
+	f.refresh.On("Create", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.RefreshToken) }).
+		Return(nil)
+
+	client := service.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "curl/8.0"}
+	pair, err := f.svc.Issue(context.Background(), user, client, []string{service.AuthMethodPassword})
+	require.NoError(t, err)
+
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), pair.AccessExpiresAt, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), pair.RefreshExpiresAt, time.Minute)
+
+	token, err := jwt.Parse(pair.AccessToken, func(*jwt.Token) (any, error) { return []byte("secret"), nil })
+	require.NoError(t, err)
+	assert.Equal(t, "k1", token.Header["kid"])
+	claims := token.Claims.(jwt.MapClaims)
+	assert.Equal(t, float64(7), claims["sub"])
+	assert.Equal(t, "editor", claims["role"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.NotEmpty(t, claims["iat"])
+	assert.Equal(t, claims["iat"], claims["nbf"])
+	assert.Equal(t, "sentinel-rbac", claims["iss"])
+	assert.Equal(t, "api", claims["aud"])
+	assert.Equal(t, float64(3), claims["sid"])
+	assert.Equal(t, claims["iat"], claims["auth_time"])
+	assert.Equal(t, []any{"pwd"}, claims["amr"])
+
+	require.NotNil(t, session)
+	assert.Equal(t, uint(7), session.UserID)
+	assert.Equal(t, "203.0.113.7", session.IPAddress)
+	assert.Equal(t, "curl/8.0", session.UserAgent)
+	assert.Equal(t, pair.RefreshExpiresAt, session.ExpiresAt)
+
+	require.NotNil(t, stored)
+	assert.Equal(t, uint(7), stored.UserID)
+	assert.NotEmpty(t, stored.FamilyID)
+	assert.Equal(t, session.FamilyID, stored.FamilyID)
+	assert.NotEqual(t, pair.RefreshToken, stored.TokenHash, "only the hash is stored")
+	assert.Len(t, stored.TokenHash, 64)
+}
+
+func TestTokenService_Refresh_Rotates(t *testing.T) {
+	f := newTokenService()
+	user := &models.User{Model: gorm.Model{ID: 7}, Role: "editor"}
+
+	var session *models.Session
+	f.sessions.On("Create", mock.Anything, mock.AnythingOfType("*models.Session")).
+		Run(func(args mock.Arguments) {
+			session = args.Get(1).(*models.Session)
+			session.ID = 3
+		}).
+		Return(nil).Once()
+
+	var issued []*models.RefreshToken
+	f.refresh.On("Create", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).
+		Run(func(args mock.Arguments) { issued = append(issued, args.Get(1).(*models.RefreshToken)) }).
+		Return(nil)
+
+	first, err := f.svc.Issue(context.Background(), user, service.ClientInfo{}, []string{service.AuthMethodPassword})
+	require.NoError(t, err)
+	issued[0].ID = 1
+
+	f.refresh.On("FindByHash", mock.Anything, issued[0].TokenHash).Return(issued[0], nil)
+	f.refresh.On("MarkUsed", mock.Anything, uint(1), mock.Anything).Return(true, nil)
+	f.users.On("FindById", mock.Anything, uint(7)).Return(user, nil)
+	f.sessions.On("FindByFamily", mock.Anything, session.FamilyID).Return(session, nil)
+	f.sessions.On("Touch", mock.Anything, uint(3), mock.Anything, mock.Anything).Return(nil)
+
+	second, err := f.svc.Refresh(context.Background(), first.RefreshToken)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.Equal(t, accessClaims(t, first.AccessToken)["auth_time"], accessClaims(t, second.AccessToken)["auth_time"],
+		"refreshing does not count as authenticating")
+	require.Len(t, issued, 2)
+	assert.Equal(t, issued[0].FamilyID, issued[1].FamilyID)
+	f.refresh.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything, mock.Anything)
+	f.sessions.AssertExpectations(t)
+}
+
+func TestTokenService_Reauthenticate(t *testing.T) {
+	user := &models.User{Model: gorm.Model{ID: 7}, Role: "editor"}
+	authTime := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		session *models.Session
+		err     error
+	}{
+		{"active", &models.Session{Model: gorm.Model{ID: 3}, UserID: 7, AuthTime: &authTime, ExpiresAt: time.Now().Add(time.Hour)}, nil},
+		{"unknown", nil, appErr.ErrSessionNotFound},
+		{"other user", &models.Session{Model: gorm.Model{ID: 3}, UserID: 8, ExpiresAt: time.Now().Add(time.Hour)}, appErr.ErrSessionNotFound},
+		{"revoked", &models.Session{Model: gorm.Model{ID: 3}, UserID: 7, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: ptrTime(time.Now())}, appErr.ErrSessionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTokenService()
+			if tt.session == nil {
+				f.sessions.On("FindByID", mock.Anything, uint(3)).Return(nil, nil)
+			} else {
+				f.sessions.On("FindByID", mock.Anything, uint(3)).Return(tt.session, nil)
+			}
+			f.sessions.On("Reauthenticate", mock.Anything, uint(3), mock.Anything, "pwd otp mfa").Return(nil)
+
+			pair, err := f.svc.Reauthenticate(context.Background(), user, 3, []string{"pwd", "otp", "mfa"})
+
+			assert.Equal(t, tt.err, err)
+			if tt.err != nil {
+				f.sessions.AssertNotCalled(t, "Reauthenticate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.Empty(t, pair.RefreshToken, "the session keeps its refresh token")
+			claims := accessClaims(t, pair.AccessToken)
+			assert.Equal(t, float64(3), claims["sid"])
+			assert.Equal(t, claims["iat"], claims["auth_time"])
+			assert.Equal(t, []any{"pwd", "otp", "mfa"}, claims["amr"])
+			f.refresh.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestTokenService_Refresh_ReuseRevokesFamily(t *testing.T) {
+	tests := []struct {
+		name   string
+		usedAt *time.Time
+		marked bool
+	}{
+		{"already used", ptrTime(time.Now().Add(-time.Minute)), false},
+		{"lost rotation race", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTokenService()
+
+			stored := &models.RefreshToken{
+				Model:     gorm.Model{ID: 1},
+				UserID:    7,
+				FamilyID:  "family",
+				ExpiresAt: time.Now().Add(time.Hour),
+				UsedAt:    tt.usedAt,
+			}
+			f.refresh.On("FindByHash", mock.Anything, mock.Anything).Return(stored, nil)
+			f.refresh.On("MarkUsed", mock.Anything, uint(1), mock.Anything).Return(tt.marked, nil)
+			f.refresh.On("RevokeFamily", mock.Anything, "family", mock.Anything).Return(nil)
+			f.sessions.On("RevokeFamily", mock.Anything, "family", mock.Anything).Return(nil)
+
+			pair, err := f.svc.Refresh(context.Background(), "replayed")
+
+			assert.Nil(t, pair)
+			assert.Equal(t, appErr.ErrRefreshTokenReused, err)
+			f.refresh.AssertCalled(t, "RevokeFamily", mock.Anything, "family", mock.Anything)
+			f.sessions.AssertCalled(t, "RevokeFamily", mock.Anything, "family", mock.Anything)
+			f.users.AssertNotCalled(t, "FindById", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestTokenService_Refresh_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored *models.RefreshToken
+	}{
+		{"unknown", nil},
+		{"expired", &models.RefreshToken{FamilyID: "family", ExpiresAt: time.Now().Add(-time.Minute)}},
+		{"revoked", &models.RefreshToken{FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour), RevokedAt: ptrTime(time.Now())}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTokenService()
+			if tt.stored == nil {
+				f.refresh.On("FindByHash", mock.Anything, mock.Anything).Return(nil, nil)
+			} else {
+				f.refresh.On("FindByHash", mock.Anything, mock.Anything).Return(tt.stored, nil)
+			}
+
+			pair, err := f.svc.Refresh(context.Background(), "token")
+
+			assert.Nil(t, pair)
+			assert.Equal(t, appErr.ErrInvalidRefreshToken, err)
+			f.refresh.AssertNotCalled(t, "MarkUsed", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestTokenService_Revoke(t *testing.T) {
+	f := newTokenService()
+
+	f.refresh.On("FindByHash", mock.Anything, mock.Anything).
+		Return(&models.RefreshToken{FamilyID: "family"}, nil)
+	f.refresh.On("RevokeFamily", mock.Anything, "family", mock.Anything).Return(nil)
+	f.sessions.On("RevokeFamily", mock.Anything, "family", mock.Anything).Return(nil)
+
+	require.NoError(t, f.svc.Revoke(context.Background(), "token"))
+	f.refresh.AssertExpectations(t)
+	f.sessions.AssertExpectations(t)
+}
+
+func TestTokenService_RevokeAll(t *testing.T) {
+	f := newTokenService()
+
+	sessions := []models.Session{{Model: gorm.Model{ID: 3}, UserID: 7}, {Model: gorm.Model{ID: 4}, UserID: 7}}
+	f.sessions.On("FindActiveByUser", mock.Anything, uint(7), mock.Anything).Return(sessions, nil)
+	f.refresh.On("RevokeUser", mock.Anything, uint(7), mock.Anything).Return(nil)
+	f.sessions.On("RevokeUser", mock.Anything, uint(7), mock.Anything).Return(nil)
+	// Tokens issued in the same second as the cutoff die with their session.
+	f.revocations.On("RevokeSession", mock.Anything, uint(7), uint(3), mock.Anything).Return(nil)
+	f.revocations.On("RevokeSession", mock.Anything, uint(7), uint(4), mock.Anything).Return(nil)
+	f.revocations.On("RevokeUser", mock.Anything, uint(7), mock.MatchedBy(func(expiresAt time.Time) bool {
+		return expiresAt.After(time.Now().Add(14 * time.Minute))
+	})).Return(nil)
+
+	require.NoError(t, f.svc.RevokeAll(context.Background(), 7))
+	f.refresh.AssertExpectations(t)
+	f.sessions.AssertExpectations(t)
+	f.revocations.AssertExpectations(t)
+}
+
+func TestTokenService_RevokeSession(t *testing.T) {
+	active := &models.Session{Model: gorm.Model{ID: 3}, UserID: 7, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name    string
+		session *models.Session
+		err     error
+	}{
+		{"own session", active, nil},
+		{"unknown", nil, appErr.ErrSessionNotFound},
+		{"other user", &models.Session{Model: gorm.Model{ID: 3}, UserID: 8, ExpiresAt: time.Now().Add(time.Hour)}, appErr.ErrSessionNotFound},
+		{"already revoked", &models.Session{Model: gorm.Model{ID: 3}, UserID: 7, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: ptrTime(time.Now())}, appErr.ErrSessionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTokenService()
+			if tt.session == nil {
+				f.sessions.On("FindByID", mock.Anything, uint(3)).Return(nil, nil)
+			} else {
+				f.sessions.On("FindByID", mock.Anything, uint(3)).Return(tt.session, nil)
+			}
+			f.refresh.On("RevokeFamily", mock.Anything, "family", mock.Anything).Return(nil)
+			f.sessions.On("RevokeFamily", mock.Anything, "family", mock.Anything).Return(nil)
+			f.revocations.On("RevokeSession", mock.Anything, uint(7), uint(3), mock.Anything).Return(nil)
+
+			err := f.svc.RevokeSession(context.Background(), 7, 3)
+
+			assert.Equal(t, tt.err, err)
+			if tt.err == nil {
+				f.refresh.AssertExpectations(t)
+				f.sessions.AssertExpectations(t)
+				f.revocations.AssertExpectations(t)
+			} else {
+				f.revocations.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestTokenService_RevokeOtherSessions(t *testing.T) {
+	f := newTokenService()
+
+	f.sessions.On("FindActiveByUser", mock.Anything, uint(7), mock.Anything).Return([]models.Session{
+		{Model: gorm.Model{ID: 3}, UserID: 7, FamilyID: "current"},
+		{Model: gorm.Model{ID: 4}, UserID: 7, FamilyID: "other"},
+	}, nil)
+	f.refresh.On("RevokeFamily", mock.Anything, "other", mock.Anything).Return(nil)
+	f.sessions.On("RevokeFamily", mock.Anything, "other", mock.Anything).Return(nil)
+	f.revocations.On("RevokeSession", mock.Anything, uint(7), uint(4), mock.Anything).Return(nil)
+
+	require.NoError(t, f.svc.RevokeOtherSessions(context.Background(), 7, 3))
+	f.refresh.AssertNotCalled(t, "RevokeFamily", mock.Anything, "current", mock.Anything)
+	f.revocations.AssertNotCalled(t, "RevokeSession", mock.Anything, uint(7), uint(3), mock.Anything)
+	f.revocations.AssertExpectations(t)
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+func accessClaims(t *testing.T, token string) jwt.MapClaims {
+	t.Helper()
+
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (any, error) { return []byte("secret"), nil })
+	require.NoError(t, err)
+	return parsed.Claims.(jwt.MapClaims)
+}
@@ -2,28 +2,44 @@ package service
 
 import (
 	"context"
-	"time"
 
 	"github.com/corradoisidoro/sentinel-rbac/internal/config"
 	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
 	"github.com/corradoisidoro/sentinel-rbac/internal/models"
 	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
-	"github.com/golang-jwt/jwt"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService interface {
 	Register(ctx context.Context, email, password, role string) (*models.User, error)
-	Login(ctx context.Context, email, password string) (string, error)
+	// Login returns a token pair, or an MFA challenge for VerifyMFA when the
+	// user has MFA enabled.
+	Login(ctx context.Context, email, password string, client ClientInfo) (*TokenPair, *MFAChallenge, error)
+	VerifyMFA(ctx context.Context, mfaToken, code string) (*TokenPair, error)
+	// Reauthenticate checks the user's password or, with MFA enabled, a
+	// code again and refreshes the auth_time of the session.
+	Reauthenticate(ctx context.Context, userID, sessionID uint, password, code string) (*TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Logout(ctx context.Context, access *authz.Claims, refreshToken string) error
+	ChangePassword(ctx context.Context, userID uint, current, password string) error
+	RevokeTokens(ctx context.Context, userID uint) error
+	Sessions(ctx context.Context, userID uint) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uint) error
+	RevokeOtherSessions(ctx context.Context, userID, currentID uint) error
+	FindUser(ctx context.Context, id uint) (*models.User, error)
+	FindUserByEmail(ctx context.Context, email string) (*models.User, error)
 }
 
 type userService struct {
 	repo   repository.UserRepository
 	config config.Config
+	tokens TokenService
+	mfa    MFAService
 }
 
-func NewUserService(repo repository.UserRepository, config config.Config) UserService {
-	return &userService{repo: repo, config: config}
+func NewUserService(repo repository.UserRepository, config config.Config, tokens TokenService, mfa MFAService) UserService {
+	return &userService{repo: repo, config: config, tokens: tokens, mfa: mfa}
 }
 
 func (s *userService) Register(ctx context.Context, email, password, role string) (*models.User, error) {
@@ -57,48 +73,220 @@ func (s *userService) Register(ctx context.Context, email, password, role string
 	return user, nil
 }
 
-func (s *userService) Login(ctx context.Context, email, password string) (string, error) {
+func (s *userService) Login(ctx context.Context, email, password string, client ClientInfo) (*TokenPair, *MFAChallenge, error) {
 	if email == "" || password == "" || len(password) < 6 {
-		return "", appErr.ErrInvalidInput
+		return nil, nil, appErr.ErrInvalidInput
 	}
 
 	existing, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
-		return "", appErr.ErrInternal
+		return nil, nil, appErr.ErrInternal
 	}
 	if existing == nil {
-		return "", appErr.ErrUserNotFound
+		return nil, nil, appErr.ErrUserNotFound
 	}
 
 	// Look up the user by email
 	user, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
-		return "", appErr.ErrInternal
+		return nil, nil, appErr.ErrInternal
 	}
 	if user == nil {
-		return "", appErr.ErrUserNotFound
+		return nil, nil, appErr.ErrUserNotFound
 	}
 
 	// Compare sent in pass with saves hashed pass
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return "", appErr.ErrInvalidPassword
+		return nil, nil, appErr.ErrInvalidPassword
 	}
 
-	// If valid, generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  user.ID,
-		"exp":  time.Now().Add(time.Hour * 24 * 30).Unix(), // 30 days
-		"role": user.Role,
-	})
+	// With MFA enabled, the session only starts once a code is verified
+	if user.MFAEnabled {
+		challenge, err := s.mfa.Challenge(ctx, user.ID, client)
+		return nil, challenge, err
+	}
+
+	// If valid, start a session with an access and refresh token pair
+	pair, err := s.tokens.Issue(ctx, user, client, []string{AuthMethodPassword})
+	return pair, nil, err
+}
+
+// VerifyMFA completes a login with the challenge token Login returned and a
+// TOTP or recovery code, and starts the session.
+func (s *userService) VerifyMFA(ctx context.Context, mfaToken, code string) (*TokenPair, error) {
+	userID, client, err := s.mfa.Complete(ctx, mfaToken, code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.FindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Sign and get the complete encoded token as a string using the secretkey
-	secretKey := []byte(s.config.JWTSecret)
-	tokenString, err := token.SignedString(secretKey)
+	return s.tokens.Issue(ctx, user, client, []string{AuthMethodPassword, AuthMethodOTP, AuthMethodMFA})
+}
+
+// Reauthenticate lets a logged-in user prove their identity again before a
+// sensitive action. Users with MFA enabled must re-enter a TOTP or recovery
+// code, optionally with their password; other users their password. The
+// session's auth_time is reset and a new access token returned. Wrong
+// passwords and codes count towards the MFA lockout shared with logins, see
+// MFAService.Attempt.
+func (s *userService) Reauthenticate(ctx context.Context, userID, sessionID uint, password, code string) (*TokenPair, error) {
+	// Tokens issued before sessions were recorded have nothing to upgrade.
+	if sessionID == 0 {
+		return nil, appErr.ErrSessionNotFound
+	}
+
+	user, err := s.FindUser(ctx, userID)
 	if err != nil {
-		return "", appErr.ErrFailedToGenerateToken
+		return nil, err
+	}
+
+	if err := s.mfa.Lockout(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	var methods []string
+	if password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			if err := s.mfa.Attempt(ctx, user.ID, false); err != nil {
+				return nil, err
+			}
+			return nil, appErr.ErrInvalidPassword
+		}
+		methods = append(methods, AuthMethodPassword)
+	}
+
+	if user.MFAEnabled {
+		if code == "" {
+			return nil, appErr.ErrMFARequired
+		}
+		if err := s.mfa.Verify(ctx, user.ID, code); err != nil {
+			return nil, err
+		}
+		methods = append(methods, AuthMethodOTP)
+	} else {
+		if password == "" {
+			return nil, appErr.ErrInvalidInput
+		}
+		// Verify clears the count for users with MFA; a correct password
+		// alone must not, or it would reset the count between wrong codes.
+		if err := s.mfa.Attempt(ctx, user.ID, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(methods) > 1 {
+		methods = append(methods, AuthMethodMFA)
+	}
+
+	return s.tokens.Reauthenticate(ctx, user, sessionID, methods)
+}
+
+// Refresh exchanges a refresh token for a new token pair, see TokenService.
+func (s *userService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	return s.tokens.Refresh(ctx, refreshToken)
+}
+
+// Logout ends the session the access token belongs to, revoking its refresh
+// tokens and every access token issued in it. It also revokes the access
+// token itself, for tokens from before sessions were recorded, and the
+// given refresh token with every token rotated from it.
+func (s *userService) Logout(ctx context.Context, access *authz.Claims, refreshToken string) error {
+	if access != nil && access.SessionID != 0 {
+		err := s.tokens.RevokeSession(ctx, access.Subject, access.SessionID)
+		if err != nil && err != appErr.ErrSessionNotFound {
+			return err
+		}
 	}
 
-	// Sent back token and user info
-	return tokenString, nil
+	if access != nil && access.ID != "" {
+		if err := s.tokens.RevokeAccess(ctx, access.Subject, access.ID, access.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	return s.tokens.Revoke(ctx, refreshToken)
+}
+
+// ChangePassword replaces the user's password after checking the current
+// one, then revokes all of the user's tokens so every session has to log in
+// again.
+func (s *userService) ChangePassword(ctx context.Context, userID uint, current, password string) error {
+	if current == "" || len(password) < 8 {
+		return appErr.ErrInvalidInput
+	}
+
+	user, err := s.FindUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(current)); err != nil {
+		return appErr.ErrInvalidPassword
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return appErr.ErrInternal
+	}
+
+	if err := s.repo.UpdatePassword(ctx, user.ID, string(hashedPassword)); err != nil {
+		return appErr.ErrInternal
+	}
+
+	return s.tokens.RevokeAll(ctx, user.ID)
+}
+
+// RevokeTokens revokes all of the user's tokens, e.g. when an administrator
+// suspects an account is compromised.
+func (s *userService) RevokeTokens(ctx context.Context, userID uint) error {
+	if _, err := s.FindUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.tokens.RevokeAll(ctx, userID)
+}
+
+// Sessions lists the user's active sessions, see TokenService.
+func (s *userService) Sessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	return s.tokens.Sessions(ctx, userID)
+}
+
+// RevokeSession logs out one of the user's sessions.
+func (s *userService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	return s.tokens.RevokeSession(ctx, userID, sessionID)
+}
+
+// RevokeOtherSessions logs out every session of the user but the current
+// one.
+func (s *userService) RevokeOtherSessions(ctx context.Context, userID, currentID uint) error {
+	return s.tokens.RevokeOtherSessions(ctx, userID, currentID)
+}
+
+func (s *userService) FindUser(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	if user == nil {
+		return nil, appErr.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *userService) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, appErr.ErrInternal
+	}
+	if user == nil {
+		return nil, appErr.ErrUserNotFound
+	}
+	return user, nil
 }
@@ -2,22 +2,29 @@ package service_test
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/corradoisidoro/sentinel-rbac/internal/config"
 	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
 	"github.com/corradoisidoro/sentinel-rbac/internal/models"
+	"github.com/corradoisidoro/sentinel-rbac/internal/repository"
 	"github.com/corradoisidoro/sentinel-rbac/internal/repository/mocks"
 	"github.com/corradoisidoro/sentinel-rbac/internal/service"
+	serviceMocks "github.com/corradoisidoro/sentinel-rbac/internal/service/mocks"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 func TestUserService_SignUp_Success(t *testing.T) {
 	repo := new(mocks.UserRepositoryMock)
 	config := config.Config{}
-	svc := service.NewUserService(repo, config)
+	svc := service.NewUserService(repo, config, new(serviceMocks.TokenServiceMock), new(serviceMocks.MFAServiceMock))
 
 	repo.On("FindByEmail", mock.Anything, "test@example.com").
 		Return(nil, nil)
@@ -39,7 +46,7 @@ func TestUserService_SignUp_Success(t *testing.T) {
 func TestUserService_SignUp_UserAlreadyExists(t *testing.T) {
 	repo := new(mocks.UserRepositoryMock)
 	config := config.Config{}
-	svc := service.NewUserService(repo, config)
+	svc := service.NewUserService(repo, config, new(serviceMocks.TokenServiceMock), new(serviceMocks.MFAServiceMock))
 
 	repo.On("FindByEmail", mock.Anything, "test@example.com").
 		Return(&models.User{Email: "test@example.com"}, nil)
@@ -56,7 +63,7 @@ func TestUserService_SignUp_UserAlreadyExists(t *testing.T) {
 func TestUserService_SignUp_InvalidInput(t *testing.T) {
 	repo := new(mocks.UserRepositoryMock)
 	config := config.Config{}
-	svc := service.NewUserService(repo, config)
+	svc := service.NewUserService(repo, config, new(serviceMocks.TokenServiceMock), new(serviceMocks.MFAServiceMock))
 
 	user, err := svc.Register(context.Background(), "", "123", "user")
 
@@ -64,3 +71,278 @@ func TestUserService_SignUp_InvalidInput(t *testing.T) {
 	assert.Nil(t, user)
 	assert.Equal(t, appErr.ErrInvalidInput, err)
 }
+
+func TestUserService_Login_IssuesTokenPair(t *testing.T) {
+	repo := new(mocks.UserRepositoryMock)
+	tokens := new(serviceMocks.TokenServiceMock)
+	svc := service.NewUserService(repo, config.Config{}, tokens, new(serviceMocks.MFAServiceMock))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.User{Email: "test@example.com", Password: string(hash), Role: "user"}
+
+	repo.On("FindByEmail", mock.Anything, "test@example.com").Return(user, nil)
+	pair := &service.TokenPair{AccessToken: "access", RefreshToken: "refresh"}
+	tokens.On("Issue", mock.Anything, user, mock.Anything, []string{"pwd"}).Return(pair, nil)
+
+	got, challenge, err := svc.Login(context.Background(), "test@example.com", "password123", service.ClientInfo{})
+
+	require.NoError(t, err)
+	assert.Equal(t, pair, got)
+	assert.Nil(t, challenge)
+	tokens.AssertExpectations(t)
+}
+
+func TestUserService_Login_MFAChallenge(t *testing.T) {
+	repo := new(mocks.UserRepositoryMock)
+	tokens := new(serviceMocks.TokenServiceMock)
+	mfa := new(serviceMocks.MFAServiceMock)
+	svc := service.NewUserService(repo, config.Config{}, tokens, mfa)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.User{Model: gorm.Model{ID: 7}, Email: "test@example.com", Password: string(hash), MFAEnabled: true}
+	client := service.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "curl/8.0"}
+
+	repo.On("FindByEmail", mock.Anything, "test@example.com").Return(user, nil)
+	repo.On("FindById", mock.Anything, uint(7)).Return(user, nil)
+	challenge := &service.MFAChallenge{Token: "challenge", ExpiresAt: time.Now().Add(5 * time.Minute)}
+	mfa.On("Challenge", mock.Anything, uint(7), client).Return(challenge, nil)
+
+	got, gotChallenge, err := svc.Login(context.Background(), "test@example.com", "password123", client)
+
+	require.NoError(t, err)
+	assert.Nil(t, got, "no session before the code is verified")
+	assert.Equal(t, challenge, gotChallenge)
+	tokens.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	pair := &service.TokenPair{AccessToken: "access", RefreshToken: "refresh"}
+	mfa.On("Complete", mock.Anything, "challenge", "123456").Return(uint(7), client, nil)
+	tokens.On("Issue", mock.Anything, user, client, []string{"pwd", "otp", "mfa"}).Return(pair, nil)
+
+	got, err = svc.VerifyMFA(context.Background(), "challenge", "123456")
+
+	require.NoError(t, err)
+	assert.Equal(t, pair, got)
+	tokens.AssertExpectations(t)
+}
+
+func TestUserService_Login_InvalidPassword(t *testing.T) {
+	repo := new(mocks.UserRepositoryMock)
+	tokens := new(serviceMocks.TokenServiceMock)
+	svc := service.NewUserService(repo, config.Config{}, tokens, new(serviceMocks.MFAServiceMock))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo.On("FindByEmail", mock.Anything, "test@example.com").
+		Return(&models.User{Email: "test@example.com", Password: string(hash)}, nil)
+
+	got, challenge, err := svc.Login(context.Background(), "test@example.com", "wrongpassword", service.ClientInfo{})
+
+	assert.Nil(t, got)
+	assert.Nil(t, challenge)
+	assert.Equal(t, appErr.ErrInvalidPassword, err)
+	tokens.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_Reauthenticate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		mfaEnabled bool
+		password   string
+		code       string
+		codeErr    error
+		methods    []string
+		err        error
+	}{
+		{"password", false, "password123", "", nil, []string{"pwd"}, nil},
+		{"wrong password", false, "wrong-password", "", nil, nil, appErr.ErrInvalidPassword},
+		{"nothing", false, "", "", nil, nil, appErr.ErrInvalidInput},
+		{"mfa code", true, "", "123456", nil, []string{"otp"}, nil},
+		{"password and mfa code", true, "password123", "123456", nil, []string{"pwd", "otp", "mfa"}, nil},
+		{"mfa user without code", true, "password123", "", nil, nil, appErr.ErrMFARequired},
+		{"wrong mfa code", true, "", "000000", appErr.ErrInvalidMFACode, nil, appErr.ErrInvalidMFACode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mocks.UserRepositoryMock)
+			tokens := new(serviceMocks.TokenServiceMock)
+			mfa := new(serviceMocks.MFAServiceMock)
+			svc := service.NewUserService(repo, config.Config{}, tokens, mfa)
+
+			user := &models.User{Model: gorm.Model{ID: 7}, Password: string(hash), MFAEnabled: tt.mfaEnabled}
+			repo.On("FindById", mock.Anything, uint(7)).Return(user, nil)
+			mfa.On("Lockout", mock.Anything, uint(7)).Return(nil)
+			mfa.On("Attempt", mock.Anything, uint(7), mock.Anything).Return(nil)
+			mfa.On("Verify", mock.Anything, uint(7), tt.code).Return(tt.codeErr)
+			pair := &service.TokenPair{AccessToken: "access"}
+			tokens.On("Reauthenticate", mock.Anything, user, uint(3), tt.methods).Return(pair, nil)
+
+			got, err := svc.Reauthenticate(context.Background(), 7, 3, tt.password, tt.code)
+
+			assert.Equal(t, tt.err, err)
+			if tt.err == appErr.ErrInvalidPassword {
+				mfa.AssertCalled(t, "Attempt", mock.Anything, uint(7), false)
+			}
+			if tt.err == nil {
+				assert.Equal(t, pair, got)
+				tokens.AssertExpectations(t)
+			} else {
+				tokens.AssertNotCalled(t, "Reauthenticate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+
+	_, err = service.NewUserService(new(mocks.UserRepositoryMock), config.Config{}, nil, nil).
+		Reauthenticate(context.Background(), 7, 0, "password123", "")
+	assert.Equal(t, appErr.ErrSessionNotFound, err, "tokens without a session")
+}
+
+func TestUserService_Reauthenticate_Lockout(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := repository.Connect(filepath.Join(t.TempDir(), "sentinel.db"))
+	require.NoError(t, err)
+	require.NoError(t, repository.Migrate(db))
+
+	users := repository.NewUserRepository(db)
+	mfa := service.NewMFAService(repository.NewMFARepository(db), config.Config{})
+	svc := service.NewUserService(users, config.Config{}, new(serviceMocks.TokenServiceMock), mfa)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.User{Email: "alice@example.com", Password: string(hash), Role: "user"}
+	require.NoError(t, users.Create(ctx, user))
+
+	setup, err := mfa.Enroll(ctx, user)
+	require.NoError(t, err)
+	recoveryCodes, err := mfa.Confirm(ctx, user, currentCode(t, setup.Secret))
+	require.NoError(t, err)
+
+	// Wrong codes at login and wrong passwords on reauthentication share one
+	// count, so neither can be used to guess past the limit.
+	_, challenge, err := svc.Login(ctx, user.Email, "password123", service.ClientInfo{})
+	require.NoError(t, err)
+	for range service.MFAMaxAttempts - 1 {
+		_, err := svc.VerifyMFA(ctx, challenge.Token, "zzzz-zzzz")
+		require.Equal(t, appErr.ErrInvalidMFACode, err)
+	}
+	for range service.MFAMaxFailures - (service.MFAMaxAttempts - 1) {
+		_, err := svc.Reauthenticate(ctx, user.ID, 1, "wrong-password", recoveryCodes[0])
+		require.Equal(t, appErr.ErrInvalidPassword, err)
+	}
+
+	_, err = svc.Reauthenticate(ctx, user.ID, 1, "password123", recoveryCodes[0])
+	assert.Equal(t, appErr.ErrMFALocked, err, "locked out even with the right password and code")
+
+	_, _, err = svc.Login(ctx, user.Email, "password123", service.ClientInfo{})
+	assert.Equal(t, appErr.ErrMFALocked, err)
+}
+
+func TestUserService_ChangePassword_RevokesTokens(t *testing.T) {
+	repo := new(mocks.UserRepositoryMock)
+	tokens := new(serviceMocks.TokenServiceMock)
+	svc := service.NewUserService(repo, config.Config{}, tokens, new(serviceMocks.MFAServiceMock))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.User{Model: gorm.Model{ID: 7}, Password: string(hash)}
+
+	repo.On("FindById", mock.Anything, uint(7)).Return(user, nil)
+	repo.On("UpdatePassword", mock.Anything, uint(7), mock.MatchedBy(func(h string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(h), []byte("new-password")) == nil
+	})).Return(nil)
+	tokens.On("RevokeAll", mock.Anything, uint(7)).Return(nil)
+
+	err = svc.ChangePassword(context.Background(), 7, "password123", "new-password")
+
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+	tokens.AssertExpectations(t)
+}
+
+func TestUserService_ChangePassword_WrongCurrentPassword(t *testing.T) {
+	repo := new(mocks.UserRepositoryMock)
+	tokens := new(serviceMocks.TokenServiceMock)
+	svc := service.NewUserService(repo, config.Config{}, tokens, new(serviceMocks.MFAServiceMock))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo.On("FindById", mock.Anything, uint(7)).
+		Return(&models.User{Model: gorm.Model{ID: 7}, Password: string(hash)}, nil)
+
+	err = svc.ChangePassword(context.Background(), 7, "wrong-password", "new-password")
+
+	assert.Equal(t, appErr.ErrInvalidPassword, err)
+	repo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	tokens.AssertNotCalled(t, "RevokeAll", mock.Anything, mock.Anything)
+}
+
+func TestUserService_Logout_RevokesAccessAndRefreshTokens(t *testing.T) {
+	tokens := new(serviceMocks.TokenServiceMock)
+	svc := service.NewUserService(new(mocks.UserRepositoryMock), config.Config{}, tokens, new(serviceMocks.MFAServiceMock))
+
+	exp := time.Now().Add(time.Minute)
+	tokens.On("RevokeSession", mock.Anything, uint(7), uint(3)).Return(nil)
+	tokens.On("RevokeAccess", mock.Anything, uint(7), "jti", exp).Return(nil)
+	tokens.On("Revoke", mock.Anything, "refresh").Return(nil)
+
+	err := svc.Logout(context.Background(), &authz.Claims{ID: "jti", Subject: 7, SessionID: 3, ExpiresAt: exp}, "refresh")
+
+	require.NoError(t, err)
+	tokens.AssertExpectations(t)
+}
+
+func TestUserService_Logout_SessionAlreadyRevoked(t *testing.T) {
+	tokens := new(serviceMocks.TokenServiceMock)
+	svc := service.NewUserService(new(mocks.UserRepositoryMock), config.Config{}, tokens, new(serviceMocks.MFAServiceMock))
+
+	exp := time.Now().Add(time.Minute)
+	tokens.On("RevokeSession", mock.Anything, uint(7), uint(3)).Return(appErr.ErrSessionNotFound)
+	tokens.On("RevokeAccess", mock.Anything, uint(7), "jti", exp).Return(nil)
+
+	err := svc.Logout(context.Background(), &authz.Claims{ID: "jti", Subject: 7, SessionID: 3, ExpiresAt: exp}, "")
+
+	require.NoError(t, err)
+	tokens.AssertExpectations(t)
+}
+
+func TestUserService_Logout_EndsSession(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := repository.Connect(filepath.Join(t.TempDir(), "sentinel.db"))
+	require.NoError(t, err)
+	require.NoError(t, repository.Migrate(db))
+
+	users := repository.NewUserRepository(db)
+	revocations, err := service.NewRevocationStore(ctx, repository.NewRevokedTokenRepository(db))
+	require.NoError(t, err)
+	keys := tokenKeys()
+	tokens := service.NewTokenService(repository.NewRefreshTokenRepository(db), repository.NewSessionRepository(db), users, revocations, keys, tokenConfig)
+	svc := service.NewUserService(users, tokenConfig, tokens, new(serviceMocks.MFAServiceMock))
+
+	user := &models.User{Email: "alice@example.com", Password: "x", Role: "user"}
+	require.NoError(t, users.Create(ctx, user))
+
+	pair, err := tokens.Issue(ctx, user, service.ClientInfo{}, []string{service.AuthMethodPassword})
+	require.NoError(t, err)
+
+	claims, err := authz.NewValidator(keys, authz.WithIssuer(tokenConfig.JWTIssuer), authz.WithAudience(tokenConfig.JWTAudience)).
+		Validate(pair.AccessToken)
+	require.NoError(t, err)
+	require.NotZero(t, claims.SessionID)
+
+	// A cookie client logs out without sending its refresh token.
+	require.NoError(t, svc.Logout(ctx, claims, ""))
+
+	_, err = tokens.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, appErr.ErrInvalidRefreshToken)
+
+	sessions, err := tokens.Sessions(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -0,0 +1,303 @@
+package authz_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func signToken(t *testing.T, secret []byte, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	require.NoError(t, err)
+	return token
+}
+
+func TestHMACValidator(t *testing.T) {
+	secret := []byte("secret")
+	v := authz.NewHMACValidator(secret)
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"valid", signToken(t, secret, jwt.MapClaims{"sub": 7, "role": "editor", "exp": time.Now().Add(time.Hour).Unix()}), nil},
+		{"with session", signToken(t, secret, jwt.MapClaims{"sub": 7, "sid": 3, "role": "editor", "exp": time.Now().Add(time.Hour).Unix()}), nil},
+		{"string session", signToken(t, secret, jwt.MapClaims{"sub": 7, "sid": "3"}), authz.ErrInvalidClaims},
+		{"expired", signToken(t, secret, jwt.MapClaims{"sub": 7, "exp": time.Now().Add(-time.Hour).Unix()}), authz.ErrInvalidToken},
+		{"wrong secret", signToken(t, []byte("other"), jwt.MapClaims{"sub": 7}), authz.ErrInvalidToken},
+		{"garbage", "invalid", authz.ErrInvalidToken},
+		{"missing subject", signToken(t, secret, jwt.MapClaims{"role": "editor"}), authz.ErrInvalidSubject},
+		{"string subject", signToken(t, secret, jwt.MapClaims{"sub": "7"}), authz.ErrInvalidSubject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Validate(tt.token)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, uint(7), claims.Subject)
+			require.Equal(t, "editor", claims.Role)
+			require.False(t, claims.ExpiresAt.IsZero())
+			if _, ok := claims.Raw["sid"]; ok {
+				require.Equal(t, uint(3), claims.SessionID)
+			}
+		})
+	}
+}
+
+func TestValidator_RegisteredClaims(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1_700_000_000, 0)
+
+	v := authz.NewHMACValidator(secret,
+		authz.WithIssuer("sentinel"),
+		authz.WithAudience("api"),
+		authz.WithLeeway(30*time.Second),
+		authz.WithRequiredClaims("exp", "jti"),
+		authz.WithClock(func() time.Time { return now }),
+	)
+
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub": 7,
+			"jti": "abc",
+			"iss": "sentinel",
+			"aud": "api",
+			"iat": now.Unix(),
+			"nbf": now.Unix(),
+			"exp": now.Add(time.Minute).Unix(),
+		}
+		for k, val := range overrides {
+			if val == nil {
+				delete(c, k)
+			} else {
+				c[k] = val
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		err    error
+	}{
+		{"valid", claims(nil), nil},
+		{"audience list", claims(jwt.MapClaims{"aud": []string{"other", "api"}}), nil},
+		{"expired within leeway", claims(jwt.MapClaims{"exp": now.Add(-20 * time.Second).Unix()}), nil},
+		{"expired", claims(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()}), authz.ErrInvalidToken},
+		{"not yet valid within leeway", claims(jwt.MapClaims{"nbf": now.Add(20 * time.Second).Unix()}), nil},
+		{"not yet valid", claims(jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()}), authz.ErrInvalidToken},
+		{"issued in the future", claims(jwt.MapClaims{"iat": now.Add(time.Minute).Unix()}), authz.ErrInvalidToken},
+		{"other issuer", claims(jwt.MapClaims{"iss": "someone-else"}), authz.ErrInvalidIssuer},
+		{"other audience", claims(jwt.MapClaims{"aud": "billing"}), authz.ErrInvalidAudience},
+		{"missing audience", claims(jwt.MapClaims{"aud": nil}), authz.ErrInvalidAudience},
+		{"missing jti", claims(jwt.MapClaims{"jti": nil}), authz.ErrInvalidClaims},
+		{"missing exp", claims(jwt.MapClaims{"exp": nil}), authz.ErrInvalidClaims},
+		{"malformed exp", claims(jwt.MapClaims{"exp": "tomorrow"}), authz.ErrInvalidClaims},
+		{"authentication context", claims(jwt.MapClaims{"auth_time": now.Add(-time.Hour).Unix(), "amr": []string{"pwd", "otp"}}), nil},
+		{"malformed auth_time", claims(jwt.MapClaims{"auth_time": "an hour ago"}), authz.ErrInvalidClaims},
+		{"malformed amr", claims(jwt.MapClaims{"amr": []int{1}}), authz.ErrInvalidClaims},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := v.Validate(signToken(t, secret, tt.claims))
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "sentinel", c.Issuer)
+			require.Contains(t, c.Audience, "api")
+			if _, ok := tt.claims["auth_time"]; ok {
+				require.Equal(t, now.Add(-time.Hour), c.AuthTime)
+				require.Equal(t, []string{"pwd", "otp"}, c.AuthMethods)
+			}
+		})
+	}
+}
+
+func TestValidator_PublicKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signers := []struct {
+		kid    string
+		method jwt.SigningMethod
+		key    any
+		public any
+	}{
+		{kid: "rsa", method: jwt.SigningMethodRS256, key: rsaKey, public: &rsaKey.PublicKey},
+		{kid: "ec", method: jwt.SigningMethodES256, key: ecKey, public: &ecKey.PublicKey},
+		{kid: "ed", method: jwt.SigningMethodEdDSA, key: edKey, public: edPublic},
+	}
+
+	// Round-trip the keys through a JWKS document, as a verifier would.
+	var set authz.JWKS
+	for _, s := range signers {
+		jwk, err := authz.NewJWK(s.kid, s.method.Alg(), s.public)
+		require.NoError(t, err)
+		set.Keys = append(set.Keys, jwk)
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	keys, err := authz.ParseJWKS(data)
+	require.NoError(t, err)
+	v := authz.NewValidator(keys)
+
+	claims := jwt.MapClaims{"sub": float64(1), "exp": time.Now().Add(time.Hour).Unix()}
+
+	for _, s := range signers {
+		token := jwt.NewWithClaims(s.method, claims)
+		token.Header["kid"] = s.kid
+		tokenString, err := token.SignedString(s.key)
+		require.NoError(t, err)
+
+		c, err := v.Validate(tokenString)
+		require.NoError(t, err, s.kid)
+		require.Equal(t, uint(1), c.Subject)
+	}
+
+	// An HS256 token "signed" with the public key must not verify.
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = "rsa"
+	forgedString, err := forged.SignedString(der)
+	require.NoError(t, err)
+
+	_, err = v.Validate(forgedString)
+	require.ErrorIs(t, err, authz.ErrInvalidToken)
+}
+
+func TestEvaluate(t *testing.T) {
+	grants := []authz.Grant{
+		{Permission: "billing:*", Effect: authz.EffectAllow, Role: "accountant"},
+		{Permission: "billing:invoices:read", Effect: authz.EffectAllow, Role: "viewer"},
+		{Permission: "billing:invoices:delete", Effect: authz.EffectDeny, Role: "contractor"},
+		{Permission: "users:read", Effect: authz.EffectAllow},
+		{Permission: "reports:*", Effect: "Deny", Role: "auditor"},
+	}
+
+	tests := []struct {
+		name    string
+		mode    authz.Mode
+		perms   []string
+		allowed bool
+		grant   *authz.Grant
+		reason  string
+	}{
+		{"most specific allow", authz.RequireAll, []string{"billing:invoices:read"}, true, &grants[1], `"billing:invoices:read" granted by role "viewer" allow "billing:invoices:read"`},
+		{"wildcard allow", authz.RequireAll, []string{"billing:payments:refund"}, true, &grants[0], `"billing:payments:refund" granted by role "accountant" allow "billing:*"`},
+		{"deny overrides allow", authz.RequireAll, []string{"billing:invoices:delete"}, false, &grants[2], `"billing:invoices:delete" denied by role "contractor" deny "billing:invoices:delete"`},
+		{"unknown effect denies", authz.RequireAll, []string{"reports:read"}, false, &grants[4], `"reports:read" denied by role "auditor" Deny "reports:*"`},
+		{"direct grant", authz.RequireAll, []string{"users:read"}, true, &grants[3], `"users:read" granted by user allow "users:read"`},
+		{"no grant", authz.RequireAll, []string{"users:delete"}, false, nil, `no grant for "users:delete"`},
+		{"all granted", authz.RequireAll, []string{"users:read", "billing:invoices:read"}, true, nil, `all of ["users:read" "billing:invoices:read"] granted`},
+		{"all with one missing", authz.RequireAll, []string{"users:read", "users:delete"}, false, nil, `no grant for "users:delete"`},
+		{"any granted", authz.RequireAny, []string{"users:delete", "users:read"}, true, &grants[3], `"users:read" granted by user allow "users:read"`},
+		{"any reports deny", authz.RequireAny, []string{"users:delete", "billing:invoices:delete"}, false, &grants[2], `"billing:invoices:delete" denied by role "contractor" deny "billing:invoices:delete"`},
+		{"any none granted", authz.RequireAny, []string{"users:delete", "users:write"}, false, nil, `no grant for any of ["users:delete" "users:write"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := authz.Evaluate(grants, tt.mode, tt.perms...)
+			require.Equal(t, tt.allowed, d.Allowed)
+			require.Equal(t, tt.grant, d.Grant)
+			require.Equal(t, tt.reason, d.Reason)
+		})
+	}
+}
+
+func TestNewRoleTable_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		defs []authz.RoleDef
+	}{
+		{"unnamed", []authz.RoleDef{{}}},
+		{"duplicate", []authz.RoleDef{{Name: "viewer"}, {Name: "viewer"}}},
+		{"unknown parent", []authz.RoleDef{{Name: "editor", Inherits: []string{"viewer"}}}},
+		{"self", []authz.RoleDef{{Name: "editor", Inherits: []string{"editor"}}}},
+		{"cycle", []authz.RoleDef{
+			{Name: "a", Inherits: []string{"b"}},
+			{Name: "b", Inherits: []string{"c"}},
+			{Name: "c", Inherits: []string{"a"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authz.NewRoleTable(tt.defs...)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestEvaluator_RoleTable(t *testing.T) {
+	table, err := authz.ParseRoleTable([]byte(`
+roles:
+  - name: viewer
+    allow: [invoices:read]
+  - name: editor
+    inherits: [viewer]
+    allow: [invoices:*]
+    deny: [invoices:delete]
+  - name: admin
+    inherits: [editor]
+`))
+	require.NoError(t, err)
+
+	e := authz.NewEvaluator(table)
+	ctx := context.Background()
+	admin := &authz.Claims{Subject: 1, Role: "admin"}
+
+	ok, err := e.HasRole(ctx, admin, "viewer")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = e.HasRole(ctx, &authz.Claims{Subject: 2, Role: "viewer"}, "admin")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	d, err := e.Authorize(ctx, admin, authz.RequireAll, "invoices:write")
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+	require.Equal(t, "editor", d.Grant.Role)
+
+	d, err = e.Authorize(ctx, admin, authz.RequireAll, "invoices:delete")
+	require.NoError(t, err)
+	require.False(t, d.Allowed)
+	require.Equal(t, `"invoices:delete" denied by role "editor" deny "invoices:delete"`, d.Reason)
+
+	d, err = e.Authorize(ctx, &authz.Claims{Subject: 3, Role: "unknown"}, authz.RequireAll, "invoices:read")
+	require.NoError(t, err)
+	require.False(t, d.Allowed)
+
+	_, err = e.Authorize(ctx, admin, authz.RequireAll)
+	require.ErrorIs(t, err, authz.ErrNoPermissions)
+}
@@ -0,0 +1,121 @@
+package authz
+
+import "fmt"
+
+// Grant effects.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Grant is a permission pattern allowed or denied to a subject, either
+// through Role or, when Role is empty, directly. Any Effect other than
+// EffectAllow denies.
+type Grant struct {
+	Permission string
+	Effect     string
+	Role       string
+}
+
+// Mode controls how a set of required permissions is evaluated.
+type Mode int
+
+const (
+	// RequireAll passes only if every required permission is granted.
+	RequireAll Mode = iota
+	// RequireAny passes if at least one required permission is granted.
+	RequireAny
+)
+
+// Decision is the outcome of Evaluate. Permission is the required permission
+// that settled the outcome and Grant, which points into the evaluated
+// grants, the grant that matched it, if any.
+type Decision struct {
+	Allowed    bool
+	Permission string
+	Grant      *Grant
+	Reason     string
+}
+
+// Evaluate decides whether grants satisfy the required permissions. For each
+// permission a matching deny grant always wins, whatever its specificity;
+// otherwise the most specific allow grant (see BestMatch) is reported as the
+// reason access was granted.
+func Evaluate(grants []Grant, mode Mode, perms ...string) Decision {
+	var last Decision
+	var firstDeny *Decision
+
+	for _, p := range perms {
+		d := evaluatePermission(grants, p)
+		last = d
+
+		switch {
+		case d.Allowed && mode == RequireAny:
+			return d
+		case !d.Allowed && mode == RequireAll:
+			return d
+		case !d.Allowed && d.Grant != nil && firstDeny == nil:
+			firstDeny = &d
+		}
+	}
+
+	if len(perms) == 1 {
+		return last
+	}
+	if mode == RequireAll {
+		return Decision{Allowed: true, Reason: fmt.Sprintf("all of %q granted", perms)}
+	}
+	if firstDeny != nil {
+		return *firstDeny
+	}
+
+	return Decision{Reason: fmt.Sprintf("no grant for any of %q", perms)}
+}
+
+func evaluatePermission(grants []Grant, required string) Decision {
+	var allows []string
+	byPattern := make(map[string]int, len(grants))
+
+	for i := range grants {
+		g := &grants[i]
+		if !Match(g.Permission, required) {
+			continue
+		}
+
+		if g.Effect != EffectAllow {
+			return Decision{
+				Permission: required,
+				Grant:      g,
+				Reason:     fmt.Sprintf("%q denied by %s", required, describeGrant(*g)),
+			}
+		}
+
+		if _, ok := byPattern[g.Permission]; !ok {
+			byPattern[g.Permission] = i
+			allows = append(allows, g.Permission)
+		}
+	}
+
+	best, ok := BestMatch(allows, required)
+	if !ok {
+		return Decision{
+			Permission: required,
+			Reason:     fmt.Sprintf("no grant for %q", required),
+		}
+	}
+
+	allow := &grants[byPattern[best]]
+	return Decision{
+		Allowed:    true,
+		Permission: required,
+		Grant:      allow,
+		Reason:     fmt.Sprintf("%q granted by %s", required, describeGrant(*allow)),
+	}
+}
+
+func describeGrant(g Grant) string {
+	if g.Role != "" {
+		return fmt.Sprintf("role %q %s %q", g.Role, g.Effect, g.Permission)
+	}
+	return fmt.Sprintf("user %s %q", g.Effect, g.Permission)
+}
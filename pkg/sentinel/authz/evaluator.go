@@ -0,0 +1,52 @@
+package authz
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoPermissions is returned when an authorization check names no
+// permissions.
+var ErrNoPermissions = errors.New("no permissions to check")
+
+// Evaluator answers role and permission checks for authenticated subjects
+// using the roles and grants of a Source.
+type Evaluator struct {
+	source Source
+}
+
+func NewEvaluator(source Source) *Evaluator {
+	return &Evaluator{source: source}
+}
+
+// Authorize evaluates perms against the subject's grants with Evaluate.
+func (e *Evaluator) Authorize(ctx context.Context, claims *Claims, mode Mode, perms ...string) (Decision, error) {
+	if len(perms) == 0 {
+		return Decision{}, ErrNoPermissions
+	}
+
+	grants, err := e.source.Grants(ctx, claims)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	return Evaluate(grants, mode, perms...), nil
+}
+
+// HasRole reports whether the subject holds, directly or by inheritance, one
+// of the given roles.
+func (e *Evaluator) HasRole(ctx context.Context, claims *Claims, roles ...string) (bool, error) {
+	effective, err := e.source.Roles(ctx, claims)
+	if err != nil {
+		return false, err
+	}
+
+	for _, have := range effective {
+		for _, want := range roles {
+			if have == want {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
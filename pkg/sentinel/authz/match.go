@@ -0,0 +1,128 @@
+// Package authz is the embeddable core of sentinel-rbac: permission matching,
+// the allow/deny evaluator, token validation and a static role table. It has
+// no database dependency, so other services can enforce access in-process
+// and only call the sentinel-rbac server for management.
+package authz
+
+import (
+	"sort"
+	"strings"
+)
+
+// Separator splits a permission into namespaced segments, e.g.
+// "billing:invoices:read" has the segments billing, invoices and read.
+const Separator = ":"
+
+// Wildcard matches any segment of a permission.
+const Wildcard = "*"
+
+// Match reports whether a granted permission covers the required one.
+// Matching is done segment by segment:
+//
+//   - a literal segment matches only the identical segment;
+//   - "*" in any but the last position matches exactly one segment,
+//     so "*:read" covers "invoices:read" but not "billing:invoices:read";
+//   - "*" in the last position matches one or more remaining segments,
+//     so "billing:*" covers the whole billing subtree and "*" covers everything.
+//
+// The required permission itself is always treated literally.
+func Match(grant, required string) bool {
+	if grant == "" || required == "" {
+		return false
+	}
+	if grant == required {
+		return true
+	}
+
+	g := strings.Split(grant, Separator)
+	r := strings.Split(required, Separator)
+
+	for i, seg := range g {
+		if seg == "" {
+			return false
+		}
+
+		last := i == len(g)-1
+		if i >= len(r) {
+			return false
+		}
+
+		switch {
+		case seg == Wildcard && last:
+			return true
+		case seg == Wildcard:
+			continue
+		case seg != r[i]:
+			return false
+		}
+	}
+
+	return len(g) == len(r)
+}
+
+// BestMatch returns the grant that covers the required permission with the
+// highest precedence. Precedence, from highest to lowest:
+//
+//  1. an exact match;
+//  2. the grant with more literal (non-wildcard) segments;
+//  3. the grant whose first wildcard appears later, i.e. the deeper prefix;
+//  4. the grant with more segments;
+//  5. lexical order, to keep results deterministic.
+func BestMatch(grants []string, required string) (string, bool) {
+	var matches []string
+	for _, g := range grants {
+		if Match(g, required) {
+			matches = append(matches, g)
+		}
+	}
+
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return morePrecise(matches[i], matches[j], required)
+	})
+
+	return matches[0], true
+}
+
+func morePrecise(a, b, required string) bool {
+	if (a == required) != (b == required) {
+		return a == required
+	}
+
+	la, wa, na := permissionShape(a)
+	lb, wb, nb := permissionShape(b)
+
+	switch {
+	case la != lb:
+		return la > lb
+	case wa != wb:
+		return wa > wb
+	case na != nb:
+		return na > nb
+	default:
+		return a < b
+	}
+}
+
+// permissionShape returns the number of literal segments, the index of the
+// first wildcard (or the segment count when there is none) and the total
+// number of segments of a permission pattern.
+func permissionShape(p string) (literals, firstWildcard, segments int) {
+	parts := strings.Split(p, Separator)
+	firstWildcard = len(parts)
+
+	for i, seg := range parts {
+		if seg == Wildcard {
+			if i < firstWildcard {
+				firstWildcard = i
+			}
+			continue
+		}
+		literals++
+	}
+
+	return literals, firstWildcard, len(parts)
+}
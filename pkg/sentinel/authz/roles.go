@@ -0,0 +1,125 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source resolves the effective roles and grants of an authenticated
+// subject.
+type Source interface {
+	Roles(ctx context.Context, claims *Claims) ([]string, error)
+	Grants(ctx context.Context, claims *Claims) ([]Grant, error)
+}
+
+// RoleDef declares a role, the roles it inherits from and its grants. The
+// fields match the roles section of a sentinel-rbac policy file.
+type RoleDef struct {
+	Name     string   `json:"name" yaml:"name"`
+	Inherits []string `json:"inherits,omitempty" yaml:"inherits,omitempty"`
+	Allow    []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	Deny     []string `json:"deny,omitempty" yaml:"deny,omitempty"`
+}
+
+// RoleTable is a static, in-memory Source. Subjects get the role named in
+// their token plus everything it inherits.
+type RoleTable struct {
+	roles map[string]RoleDef
+}
+
+// NewRoleTable builds a RoleTable, rejecting duplicate or unknown roles and
+// inheritance cycles.
+func NewRoleTable(defs ...RoleDef) (*RoleTable, error) {
+	t := &RoleTable{roles: make(map[string]RoleDef, len(defs))}
+
+	for _, d := range defs {
+		if d.Name == "" {
+			return nil, fmt.Errorf("authz: role without a name")
+		}
+		if _, ok := t.roles[d.Name]; ok {
+			return nil, fmt.Errorf("authz: role %q declared twice", d.Name)
+		}
+		t.roles[d.Name] = d
+	}
+
+	for _, d := range defs {
+		for _, p := range d.Inherits {
+			if _, ok := t.roles[p]; !ok {
+				return nil, fmt.Errorf("authz: role %q inherits unknown role %q", d.Name, p)
+			}
+		}
+		for _, inherited := range t.effective(d.Inherits...) {
+			if inherited == d.Name {
+				return nil, fmt.Errorf("authz: role %q inherits from itself", d.Name)
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// ParseRoleTable reads the roles section of a YAML or JSON policy file, such
+// as the output of "sentinelctl export".
+func ParseRoleTable(data []byte) (*RoleTable, error) {
+	var doc struct {
+		Roles []RoleDef `yaml:"roles"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("authz: %w", err)
+	}
+	return NewRoleTable(doc.Roles...)
+}
+
+func (t *RoleTable) Roles(_ context.Context, claims *Claims) ([]string, error) {
+	if claims == nil || claims.Role == "" {
+		return nil, nil
+	}
+	if _, ok := t.roles[claims.Role]; !ok {
+		return []string{claims.Role}, nil
+	}
+	return t.effective(claims.Role), nil
+}
+
+func (t *RoleTable) Grants(ctx context.Context, claims *Claims) ([]Grant, error) {
+	roles, err := t.Roles(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []Grant
+	for _, name := range roles {
+		d := t.roles[name]
+		for _, p := range d.Allow {
+			grants = append(grants, Grant{Permission: p, Effect: EffectAllow, Role: name})
+		}
+		for _, p := range d.Deny {
+			grants = append(grants, Grant{Permission: p, Effect: EffectDeny, Role: name})
+		}
+	}
+	return grants, nil
+}
+
+// effective returns start followed by every role it inherits, breadth-first,
+// each role once.
+func (t *RoleTable) effective(start ...string) []string {
+	seen := make(map[string]bool, len(start))
+	var result []string
+	queue := append([]string(nil), start...)
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+
+		queue = append(queue, t.roles[name].Inherits...)
+	}
+
+	return result
+}
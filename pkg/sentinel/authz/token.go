@@ -0,0 +1,289 @@
+package authz
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrNoToken          = errors.New("no token provided")
+	ErrInvalidToken     = errors.New("invalid or expired token")
+	ErrInvalidClaims    = errors.New("invalid token claims")
+	ErrInvalidSubject   = errors.New("invalid token subject")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrInvalidAudience  = errors.New("invalid token audience")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Claims are the validated contents of a sentinel-rbac access token.
+type Claims struct {
+	ID        string
+	Subject   uint
+	SessionID uint
+	Role      string
+	Issuer    string
+	Audience  []string
+	IssuedAt  time.Time
+	NotBefore time.Time
+	ExpiresAt time.Time
+	// AuthTime is when the user last proved their identity in the session
+	// and AuthMethods how, e.g. "pwd" or "otp", from the "auth_time" and
+	// "amr" claims. Refreshing a token does not change them.
+	AuthTime    time.Time
+	AuthMethods []string
+	Raw         map[string]any
+}
+
+// TokenValidator verifies an access token and returns its claims. Errors
+// are ErrInvalidToken, ErrInvalidClaims, ErrInvalidSubject, ErrInvalidIssuer
+// or ErrInvalidAudience.
+type TokenValidator interface {
+	Validate(tokenString string) (*Claims, error)
+}
+
+// KeySet looks up the key that verifies a token by the token's "kid"
+// header, which is empty for tokens without one. HMAC keys are []byte;
+// public keys are *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+type KeySet interface {
+	VerificationKey(kid string) (any, error)
+}
+
+// HMACKeys is a KeySet of HMAC secrets by key ID. The secret under "" verifies
+// tokens without a "kid" header.
+type HMACKeys map[string][]byte
+
+func (k HMACKeys) VerificationKey(kid string) (any, error) {
+	secret, ok := k[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return secret, nil
+}
+
+type validator struct {
+	keys     KeySet
+	issuer   string
+	audience string
+	leeway   time.Duration
+	required []string
+	now      func() time.Time
+}
+
+// ValidatorOption tightens the checks a validator applies to the registered
+// claims. Without options it only rejects tokens that are expired, not yet
+// valid or issued in the future, and tokens lacking "exp" pass.
+type ValidatorOption func(*validator)
+
+// WithIssuer rejects tokens whose "iss" is not issuer with ErrInvalidIssuer.
+func WithIssuer(issuer string) ValidatorOption {
+	return func(v *validator) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience rejects tokens whose "aud" does not name audience with
+// ErrInvalidAudience.
+func WithAudience(audience string) ValidatorOption {
+	return func(v *validator) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway tolerates clock skew of up to leeway between the issuer and the
+// validator when checking "exp", "nbf" and "iat".
+func WithLeeway(leeway time.Duration) ValidatorOption {
+	return func(v *validator) {
+		v.leeway = leeway
+	}
+}
+
+// WithRequiredClaims rejects tokens missing any of the named claims, such as
+// "exp", "iat" or "jti", with ErrInvalidClaims.
+func WithRequiredClaims(names ...string) ValidatorOption {
+	return func(v *validator) {
+		v.required = names
+	}
+}
+
+// WithClock replaces time.Now for the time-based checks.
+func WithClock(now func() time.Time) ValidatorOption {
+	return func(v *validator) {
+		v.now = now
+	}
+}
+
+// NewValidator validates tokens against the key their "kid" header selects
+// from keys. The signing method must match the key type, so a token cannot
+// pass off a public key as an HMAC secret.
+func NewValidator(keys KeySet, opts ...ValidatorOption) TokenValidator {
+	v := &validator{keys: keys, now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// NewHMACValidator validates HS256/HS384/HS512 tokens signed with secret,
+// the shared JWT_SECRET of the sentinel-rbac server, whatever their "kid".
+func NewHMACValidator(secret []byte, opts ...ValidatorOption) TokenValidator {
+	return NewValidator(singleKey{secret}, opts...)
+}
+
+type singleKey struct {
+	key any
+}
+
+func (k singleKey) VerificationKey(string) (any, error) {
+	return k.key, nil
+}
+
+func (v *validator) Validate(tokenString string) (*Claims, error) {
+	// Registered claims are checked below, with leeway.
+	parser := jwt.Parser{SkipClaimsValidation: true}
+
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.keys.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if !methodMatchesKey(token.Method, key) {
+			return nil, fmt.Errorf("unexpected signing method %v for key type %T", token.Header["alg"], key)
+		}
+		return key, nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidClaims
+	}
+
+	claims, err := claimsFromMap(mapClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.checkRegistered(mapClaims, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *validator) checkRegistered(raw jwt.MapClaims, c *Claims) error {
+	for _, name := range v.required {
+		if _, ok := raw[name]; !ok {
+			return ErrInvalidClaims
+		}
+	}
+
+	now := v.now()
+	if !c.ExpiresAt.IsZero() && now.After(c.ExpiresAt.Add(v.leeway)) {
+		return ErrInvalidToken
+	}
+	if !c.NotBefore.IsZero() && now.Add(v.leeway).Before(c.NotBefore) {
+		return ErrInvalidToken
+	}
+	if !c.IssuedAt.IsZero() && now.Add(v.leeway).Before(c.IssuedAt) {
+		return ErrInvalidToken
+	}
+
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return ErrInvalidIssuer
+	}
+
+	if v.audience != "" {
+		for _, aud := range c.Audience {
+			if aud == v.audience {
+				return nil
+			}
+		}
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+func methodMatchesKey(method jwt.SigningMethod, key any) bool {
+	switch key.(type) {
+	case []byte:
+		_, ok := method.(*jwt.SigningMethodHMAC)
+		return ok
+	case *rsa.PublicKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+	case *ecdsa.PublicKey:
+		_, ok := method.(*jwt.SigningMethodECDSA)
+		return ok
+	case ed25519.PublicKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
+
+func claimsFromMap(claims jwt.MapClaims) (*Claims, error) {
+	sub, ok := claims["sub"].(float64)
+	if !ok || sub <= 0 {
+		return nil, ErrInvalidSubject
+	}
+
+	c := &Claims{Subject: uint(sub), Raw: claims}
+	c.ID, _ = claims["jti"].(string)
+	c.Role, _ = claims["role"].(string)
+	c.Issuer, _ = claims["iss"].(string)
+
+	switch sid := claims["sid"].(type) {
+	case nil:
+	case float64:
+		if sid <= 0 {
+			return nil, ErrInvalidClaims
+		}
+		c.SessionID = uint(sid)
+	default:
+		return nil, ErrInvalidClaims
+	}
+
+	for name, dst := range map[string]*[]string{"aud": &c.Audience, "amr": &c.AuthMethods} {
+		switch v := claims[name].(type) {
+		case nil:
+		case string:
+			*dst = []string{v}
+		case []any:
+			for _, a := range v {
+				s, ok := a.(string)
+				if !ok {
+					return nil, ErrInvalidClaims
+				}
+				*dst = append(*dst, s)
+			}
+		default:
+			return nil, ErrInvalidClaims
+		}
+	}
+
+	for name, dst := range map[string]*time.Time{"iat": &c.IssuedAt, "nbf": &c.NotBefore, "exp": &c.ExpiresAt, "auth_time": &c.AuthTime} {
+		switch t := claims[name].(type) {
+		case nil:
+		case float64:
+			*dst = time.Unix(int64(t), 0)
+		default:
+			return nil, ErrInvalidClaims
+		}
+	}
+
+	return c, nil
+}
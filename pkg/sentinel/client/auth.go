@@ -0,0 +1,318 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+)
+
+// User is an account as returned by Register and Profile.
+type User struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// RegisterRequest creates an account. Role is "user" or "admin".
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// Ping checks that the server is up.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodGet, "/ping", nil, nil, nil)
+	return err
+}
+
+// PublicKeys fetches the public keys the server signs access tokens with,
+// for authz.NewValidator to verify tokens locally. HMAC keys are never
+// published.
+func (c *Client) PublicKeys(ctx context.Context) (authz.PublicKeys, error) {
+	var set authz.JWKS
+	if _, err := c.do(ctx, http.MethodGet, "/.well-known/jwks.json", nil, nil, &set); err != nil {
+		return nil, err
+	}
+	return set.PublicKeys()
+}
+
+// Register creates an account.
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (*User, error) {
+	var user User
+	if _, err := c.do(ctx, http.MethodPost, "/api/auth/register", nil, req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Tokens is an access token and the refresh token that renews it.
+type Tokens struct {
+	AccessToken  string `json:"token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// MFAChallenge is the error Login returns for accounts with MFA enabled. It
+// unwraps to ErrMFARequired; pass Token and a code to VerifyMFA to finish
+// logging in.
+type MFAChallenge struct {
+	Token     string
+	ExpiresIn int
+}
+
+func (e *MFAChallenge) Error() string {
+	return "sentinel: " + ErrMFARequired.Error()
+}
+
+func (e *MFAChallenge) Unwrap() error {
+	return ErrMFARequired
+}
+
+// Login authenticates and stores the returned tokens on the client. For
+// accounts with MFA enabled it returns an *MFAChallenge instead.
+func (c *Client) Login(ctx context.Context, email, password string) (*Tokens, error) {
+	req := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{email, password}
+
+	var resp struct {
+		Tokens
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/api/auth/login", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.MFARequired {
+		return nil, &MFAChallenge{Token: resp.MFAToken, ExpiresIn: resp.ExpiresIn}
+	}
+
+	c.SetToken(resp.AccessToken)
+	c.SetRefreshToken(resp.RefreshToken)
+	return &resp.Tokens, nil
+}
+
+// VerifyMFA completes a login that returned an *MFAChallenge with a TOTP or
+// recovery code, and stores the returned tokens on the client.
+func (c *Client) VerifyMFA(ctx context.Context, mfaToken, code string) (*Tokens, error) {
+	req := struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}{mfaToken, code}
+
+	return c.requestTokens(ctx, "/api/auth/mfa/verify", req)
+}
+
+// Reauthenticate confirms the user's identity again for routes that answer
+// ErrReauthRequired, with the password or, for accounts with MFA, a code.
+// The new access token is stored on the client; the refresh token is kept.
+func (c *Client) Reauthenticate(ctx context.Context, password, code string) (*Tokens, error) {
+	req := struct {
+		Password string `json:"password,omitempty"`
+		Code     string `json:"code,omitempty"`
+	}{password, code}
+
+	var tokens Tokens
+	if _, err := c.do(ctx, http.MethodPost, "/api/auth/reauthenticate", nil, req, &tokens); err != nil {
+		return nil, err
+	}
+
+	c.SetToken(tokens.AccessToken)
+	tokens.RefreshToken = c.RefreshToken()
+	return &tokens, nil
+}
+
+// Refresh exchanges the client's refresh token for new tokens and stores
+// them. Refresh tokens are single-use; ErrRefreshTokenReused means the token
+// had already been used and every token of the login has been revoked.
+func (c *Client) Refresh(ctx context.Context) (*Tokens, error) {
+	req := struct {
+		RefreshToken string `json:"refresh_token"`
+	}{c.RefreshToken()}
+
+	return c.requestTokens(ctx, "/api/auth/refresh", req)
+}
+
+// Logout ends the session, revokes the client's refresh token and forgets
+// both tokens.
+func (c *Client) Logout(ctx context.Context) error {
+	var req any
+	if refresh := c.RefreshToken(); refresh != "" {
+		req = struct {
+			RefreshToken string `json:"refresh_token"`
+		}{refresh}
+	}
+
+	if _, err := c.do(ctx, http.MethodPost, "/api/auth/logout", nil, req, nil); err != nil {
+		return err
+	}
+
+	c.SetToken("")
+	c.SetRefreshToken("")
+	return nil
+}
+
+func (c *Client) requestTokens(ctx context.Context, path string, req any) (*Tokens, error) {
+	var tokens Tokens
+	if _, err := c.do(ctx, http.MethodPost, path, nil, req, &tokens); err != nil {
+		return nil, err
+	}
+
+	c.SetToken(tokens.AccessToken)
+	c.SetRefreshToken(tokens.RefreshToken)
+	return &tokens, nil
+}
+
+// Profile returns the authenticated user.
+func (c *Client) Profile(ctx context.Context) (*User, error) {
+	var resp struct {
+		User User `json:"user"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/api/users/profile", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.User, nil
+}
+
+// ChangePassword changes the authenticated user's password. The server
+// revokes all of the user's tokens, so the client forgets its own and must
+// Login again.
+func (c *Client) ChangePassword(ctx context.Context, current, password string) error {
+	req := struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}{current, password}
+
+	if _, err := c.do(ctx, http.MethodPut, "/api/users/password", nil, req, nil); err != nil {
+		return err
+	}
+
+	c.SetToken("")
+	c.SetRefreshToken("")
+	return nil
+}
+
+// ForgotPassword asks the server to email a password reset link to email.
+// It succeeds whether or not an account uses that email.
+func (c *Client) ForgotPassword(ctx context.Context, email string) error {
+	req := struct {
+		Email string `json:"email"`
+	}{email}
+
+	_, err := c.do(ctx, http.MethodPost, "/api/auth/password/forgot", nil, req, nil)
+	return err
+}
+
+// ResetPassword sets a new password with the token from a password reset
+// email. The server ends all of the user's sessions, so the client forgets
+// its tokens and must Login again.
+func (c *Client) ResetPassword(ctx context.Context, token, password string) error {
+	req := struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}{token, password}
+
+	if _, err := c.do(ctx, http.MethodPost, "/api/auth/password/reset", nil, req, nil); err != nil {
+		return err
+	}
+
+	c.SetToken("")
+	c.SetRefreshToken("")
+	return nil
+}
+
+// TOTPSetup is a pending TOTP enrollment. Show URI as a QR code, or Secret
+// for manual entry, then call ConfirmTOTP with a code from the app.
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// EnrollTOTP starts TOTP enrollment for the authenticated user, replacing any
+// pending enrollment.
+func (c *Client) EnrollTOTP(ctx context.Context) (*TOTPSetup, error) {
+	var setup TOTPSetup
+	if _, err := c.do(ctx, http.MethodPost, "/api/users/mfa/totp", nil, nil, &setup); err != nil {
+		return nil, err
+	}
+	return &setup, nil
+}
+
+// ConfirmTOTP enables MFA with a code from the authenticator app and returns
+// the one-time recovery codes. They are not shown again.
+func (c *Client) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	req := struct {
+		Code string `json:"code"`
+	}{code}
+
+	var resp struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/api/users/mfa/totp/confirm", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.RecoveryCodes, nil
+}
+
+// Session is a login of the authenticated user. Current marks the session
+// of the client's own access token.
+type Session struct {
+	ID         uint      `json:"id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// Sessions lists the authenticated user's active sessions, most recently
+// used first.
+func (c *Client) Sessions(ctx context.Context) ([]Session, error) {
+	var resp struct {
+		Sessions []Session `json:"sessions"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/api/users/sessions", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Sessions, nil
+}
+
+// RevokeSession logs out one of the authenticated user's sessions.
+func (c *Client) RevokeSession(ctx context.Context, id uint) error {
+	path := "/api/users/sessions/" + strconv.FormatUint(uint64(id), 10)
+	_, err := c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+	return err
+}
+
+// RevokeOtherSessions logs out every session of the authenticated user
+// except the client's own.
+func (c *Client) RevokeOtherSessions(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/users/sessions", nil, nil, nil)
+	return err
+}
+
+// RevokeUserTokens revokes every token issued to the user. Admin only.
+func (c *Client) RevokeUserTokens(ctx context.Context, userID uint) error {
+	path := "/api/users/" + strconv.FormatUint(uint64(userID), 10) + "/revoke-tokens"
+	_, err := c.do(ctx, http.MethodPost, path, nil, nil, nil)
+	return err
+}
+
+// Admin calls the admin-only endpoint and returns its message.
+func (c *Client) Admin(ctx context.Context) (string, error) {
+	var resp struct {
+		Message string `json:"message"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/api/users/admin", nil, nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Message, nil
+}
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Resource identifies an object for resource-scoped checks.
+type Resource struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Check is one (action, resource) pair of a bulk check.
+type Check struct {
+	Action   string    `json:"action"`
+	Resource *Resource `json:"resource,omitempty"`
+}
+
+// CheckResult is the outcome of one Check.
+type CheckResult struct {
+	Check
+	Allowed bool `json:"allowed"`
+}
+
+// Grant is a permission allowed or denied to a user, through Role or, when
+// User is set, directly.
+type Grant struct {
+	Permission string `json:"permission"`
+	Effect     string `json:"effect"`
+	Role       string `json:"role,omitempty"`
+	User       bool   `json:"user,omitempty"`
+}
+
+// Decision is the outcome of evaluating one or more permissions.
+type Decision struct {
+	Permission string `json:"permission,omitempty"`
+	Allowed    bool   `json:"allowed"`
+	Reason     string `json:"reason"`
+	Grant      *Grant `json:"grant,omitempty"`
+}
+
+// RoleSource is an effective role and how the user holds it.
+type RoleSource struct {
+	Role   string `json:"role"`
+	Source string `json:"source"`
+	Via    string `json:"via,omitempty"`
+}
+
+// Subject identifies the user to explain a decision for, by ID or email.
+type Subject struct {
+	ID    uint   `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// ExplainRequest asks why Subject is allowed or denied Actions. Mode is
+// "all" (the default) or "any".
+type ExplainRequest struct {
+	Subject  Subject   `json:"subject"`
+	Actions  []string  `json:"actions"`
+	Mode     string    `json:"mode,omitempty"`
+	Resource *Resource `json:"resource,omitempty"`
+}
+
+// Explanation is a decision with the roles, grants and deny rules behind it.
+type Explanation struct {
+	Subject User `json:"subject"`
+	Decision
+	Checks []Decision   `json:"checks"`
+	Roles  []RoleSource `json:"roles"`
+	Grants []Grant      `json:"grants"`
+	Denies []Grant      `json:"denies"`
+}
+
+// ForwardRequest describes a proxied request to authorize with Forward.
+type ForwardRequest struct {
+	Method string
+	Host   string
+	URI    string
+}
+
+// Identity is the caller a forward-auth check allowed. It is empty for
+// public routes.
+type Identity struct {
+	UserID uint
+	Email  string
+	Roles  []string
+}
+
+// Check evaluates a batch of checks for the authenticated user. Results are
+// in the order of checks.
+func (c *Client) Check(ctx context.Context, checks ...Check) ([]CheckResult, error) {
+	req := struct {
+		Checks []Check `json:"checks"`
+	}{checks}
+
+	var resp struct {
+		Results []CheckResult `json:"results"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/api/authz/check", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Can reports whether the authenticated user may perform action, optionally
+// on resource.
+func (c *Client) Can(ctx context.Context, action string, resource *Resource) (bool, error) {
+	results, err := c.Check(ctx, Check{Action: action, Resource: resource})
+	if err != nil {
+		return false, err
+	}
+	return len(results) == 1 && results[0].Allowed, nil
+}
+
+// Explain returns the decision for req and its explanation. Admin only.
+func (c *Client) Explain(ctx context.Context, req ExplainRequest) (*Explanation, error) {
+	var exp Explanation
+	if _, err := c.do(ctx, http.MethodPost, "/api/authz/explain", nil, req, &exp); err != nil {
+		return nil, err
+	}
+	return &exp, nil
+}
+
+// Forward performs a forward-auth check, as a reverse proxy would, for the
+// client's token.
+func (c *Client) Forward(ctx context.Context, req ForwardRequest) (*Identity, error) {
+	header := http.Header{}
+	header.Set("X-Forwarded-Method", req.Method)
+	header.Set("X-Forwarded-Uri", req.URI)
+	if req.Host != "" {
+		header.Set("X-Forwarded-Host", req.Host)
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, "/api/authz/forward", header, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	id := &Identity{Email: resp.Header.Get("X-Auth-User-Email")}
+	if v, err := strconv.ParseUint(resp.Header.Get("X-Auth-User-Id"), 10, 64); err == nil {
+		id.UserID = uint(v)
+	}
+	if roles := resp.Header.Get("X-Auth-User-Roles"); roles != "" {
+		id.Roles = strings.Split(roles, ",")
+	}
+	return id, nil
+}
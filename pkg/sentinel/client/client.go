@@ -0,0 +1,245 @@
+// Package client is a typed Go client for the sentinel-rbac HTTP API.
+//
+// A Client keeps the tokens returned by Login and Refresh and sends the access
+// token with every request, as the Authorization cookie or, with WithBearer,
+// as a bearer header.
+// Requests rejected by the rate limiter are retried, honoring Retry-After.
+// Non-2xx responses are returned as *APIError, which unwraps to the server's
+// error values (ErrPermissionDenied, ErrInvalidCredentials, ...).
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CookieName is the cookie the server reads the access token from.
+const CookieName = "Authorization"
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 500 * time.Millisecond
+	defaultMaxRetryWait = 30 * time.Second
+)
+
+type Client struct {
+	baseURL      *url.URL
+	httpClient   *http.Client
+	bearer       bool
+	maxRetries   int
+	retryBackoff time.Duration
+	maxRetryWait time.Duration
+
+	mu           sync.RWMutex
+	token        string
+	refreshToken string
+}
+
+// Option configures optional Client settings.
+type Option func(*Client)
+
+// WithHTTPClient sends requests with hc instead of http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken starts the client with an existing access token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithBearer sends the token as an "Authorization: Bearer" header instead of
+// the Authorization cookie.
+func WithBearer() Option {
+	return func(c *Client) {
+		c.bearer = true
+	}
+}
+
+// WithRetry sets how often a 429 response is retried, the initial backoff
+// used when the server sends no Retry-After, and the longest single wait.
+// maxRetries of 0 disables retries.
+func WithRetry(maxRetries int, backoff, maxWait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+		c.maxRetryWait = maxWait
+	}
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "https://auth.example.com".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("sentinel: invalid base URL %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:      u,
+		httpClient:   http.DefaultClient,
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+		maxRetryWait: defaultMaxRetryWait,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Token returns the access token the client authenticates with.
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetToken replaces the access token the client authenticates with.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// RefreshToken returns the refresh token Refresh will use.
+func (c *Client) RefreshToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.refreshToken
+}
+
+// SetRefreshToken replaces the refresh token Refresh will use.
+func (c *Client) SetRefreshToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshToken = token
+}
+
+// do sends a request with a JSON body (when in is non-nil) and decodes a 2xx
+// JSON response into out (when non-nil), retrying 429 responses.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, in, out any) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, fmt.Errorf("sentinel: encode request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, header, body)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out != nil {
+				if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+					return nil, fmt.Errorf("sentinel: decode response: %w", err)
+				}
+			}
+			return resp, nil
+		}
+
+		apiErr := readAPIError(resp)
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+
+		wait := apiErr.RetryAfter
+		if wait <= 0 {
+			wait = c.retryBackoff << attempt
+		}
+		if wait > c.maxRetryWait {
+			wait = c.maxRetryWait
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("sentinel: %w", err)
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if token := c.Token(); token != "" {
+		if c.bearer {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else {
+			req.AddCookie(&http.Cookie{Name: CookieName, Value: token})
+		}
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func readAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	var body struct {
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(data, &body) == nil {
+		apiErr.Message = body.Error
+		apiErr.Reason = body.Reason
+	}
+
+	return apiErr
+}
+
+// parseRetryAfter reads a Retry-After value in seconds or as an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
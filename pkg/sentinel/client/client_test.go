@@ -0,0 +1,374 @@
+package client_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/client"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(t *testing.T, handler http.Handler, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestNew_InvalidBaseURL(t *testing.T) {
+	_, err := client.New("localhost:8080")
+	require.Error(t, err)
+}
+
+func TestPublicKeys(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	jwk, err := authz.NewJWK("k1", "EdDSA", public)
+	require.NoError(t, err)
+
+	c := newClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/.well-known/jwks.json", r.URL.Path)
+		writeJSON(w, http.StatusOK, authz.JWKS{Keys: []authz.JWK{jwk}})
+	}))
+
+	keys, err := c.PublicKeys(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, authz.PublicKeys{"k1": public}, keys)
+}
+
+func TestLogin_StoresTokenForLaterRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "a@b.com", req["email"])
+
+		writeJSON(w, http.StatusOK, map[string]any{"message": "logged in successfully", "token": "tok", "expires_in": 900, "refresh_token": "ref"})
+	})
+	mux.HandleFunc("/api/users/profile", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(client.CookieName)
+		if err != nil || cookie.Value != "tok" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "no token provided"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message": "User is authenticated",
+			"user":    map[string]any{"ID": 3, "Email": "a@b.com", "Role": "admin"},
+		})
+	})
+
+	c := newClient(t, mux)
+	ctx := context.Background()
+
+	_, err := c.Profile(ctx)
+	require.ErrorIs(t, err, client.ErrNoTokenProvided)
+
+	tokens, err := c.Login(ctx, "a@b.com", "password123")
+	require.NoError(t, err)
+	require.Equal(t, "tok", tokens.AccessToken)
+	require.Equal(t, "ref", c.RefreshToken())
+
+	user, err := c.Profile(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint(3), user.ID)
+	require.Equal(t, "admin", user.Role)
+}
+
+func TestLogin_MFAChallenge(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"message": "mfa required", "mfa_required": true, "mfa_token": "mfa-tok", "expires_in": 300})
+	})
+	mux.HandleFunc("/api/auth/mfa/verify", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "mfa-tok", req["mfa_token"])
+
+		if req["code"] != "123456" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid mfa code"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"token": "tok", "expires_in": 900, "refresh_token": "ref"})
+	})
+
+	c := newClient(t, mux)
+	ctx := context.Background()
+
+	_, err := c.Login(ctx, "a@b.com", "password123")
+	require.ErrorIs(t, err, client.ErrMFARequired)
+	require.Empty(t, c.Token())
+
+	var challenge *client.MFAChallenge
+	require.ErrorAs(t, err, &challenge)
+	require.Equal(t, 300, challenge.ExpiresIn)
+
+	_, err = c.VerifyMFA(ctx, challenge.Token, "000000")
+	require.ErrorIs(t, err, client.ErrInvalidMFACode)
+
+	tokens, err := c.VerifyMFA(ctx, challenge.Token, "123456")
+	require.NoError(t, err)
+	require.Equal(t, "tok", tokens.AccessToken)
+	require.Equal(t, "ref", c.RefreshToken())
+}
+
+func TestRefresh_RotatesTokens(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if req["refresh_token"] != "ref-1" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "refresh token reuse detected"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"token": "tok-2", "expires_in": 900, "refresh_token": "ref-2"})
+	})
+
+	c := newClient(t, mux, client.WithToken("tok-1"))
+	c.SetRefreshToken("ref-1")
+	ctx := context.Background()
+
+	_, err := c.Refresh(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "tok-2", c.Token())
+	require.Equal(t, "ref-2", c.RefreshToken())
+
+	_, err = c.Refresh(ctx)
+	require.ErrorIs(t, err, client.ErrRefreshTokenReused)
+	require.Equal(t, "tok-2", c.Token())
+}
+
+func TestSessions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/sessions", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": []map[string]any{
+			{"id": 3, "ip_address": "203.0.113.7", "user_agent": "curl/8.0", "current": true},
+			{"id": 4, "ip_address": "198.51.100.2"},
+		}})
+	})
+	mux.HandleFunc("DELETE /api/users/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "4" {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "session revoked"})
+	})
+
+	c := newClient(t, mux, client.WithToken("tok"))
+	ctx := context.Background()
+
+	sessions, err := c.Sessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.True(t, sessions[0].Current)
+	require.Equal(t, "curl/8.0", sessions[0].UserAgent)
+
+	require.NoError(t, c.RevokeSession(ctx, 4))
+	require.ErrorIs(t, c.RevokeSession(ctx, 5), client.ErrSessionNotFound)
+}
+
+func TestReauthenticate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users/1/revoke-tokens", func(w http.ResponseWriter, r *http.Request) {
+		cookie, _ := r.Cookie(client.CookieName)
+		if cookie == nil || cookie.Value != "fresh" {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "reauthentication required", "max_age": 600})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "tokens revoked"})
+	})
+	mux.HandleFunc("/api/auth/reauthenticate", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, map[string]string{"password": "password123"}, req)
+
+		writeJSON(w, http.StatusOK, map[string]any{"token": "fresh", "expires_in": 900})
+	})
+
+	c := newClient(t, mux, client.WithToken("stale"))
+	c.SetRefreshToken("ref")
+	ctx := context.Background()
+
+	require.ErrorIs(t, c.RevokeUserTokens(ctx, 1), client.ErrReauthRequired)
+
+	tokens, err := c.Reauthenticate(ctx, "password123", "")
+	require.NoError(t, err)
+	require.Equal(t, "fresh", tokens.AccessToken)
+	require.Equal(t, "ref", c.RefreshToken(), "the refresh token is kept")
+
+	require.NoError(t, c.RevokeUserTokens(ctx, 1))
+}
+
+func TestPasswordReset(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/password/forgot", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, map[string]string{"email": "alice@example.com"}, req)
+
+		writeJSON(w, http.StatusAccepted, map[string]string{"message": "if the email is registered, a password reset link has been sent"})
+	})
+	mux.HandleFunc("/api/auth/password/reset", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req["token"] != "good" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid or expired reset token"})
+			return
+		}
+		require.Equal(t, "new-password", req["new_password"])
+
+		writeJSON(w, http.StatusOK, map[string]string{"message": "password reset, please log in again"})
+	})
+
+	c := newClient(t, mux, client.WithToken("tok"))
+	c.SetRefreshToken("ref")
+	ctx := context.Background()
+
+	require.NoError(t, c.ForgotPassword(ctx, "alice@example.com"))
+
+	require.ErrorIs(t, c.ResetPassword(ctx, "bad", "new-password"), client.ErrInvalidResetToken)
+	require.Equal(t, "tok", c.Token())
+
+	require.NoError(t, c.ResetPassword(ctx, "good", "new-password"))
+	require.Empty(t, c.Token())
+	require.Empty(t, c.RefreshToken())
+}
+
+func TestBearerTransport(t *testing.T) {
+	c := newClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		_, err := r.Cookie(client.CookieName)
+		require.ErrorIs(t, err, http.ErrNoCookie)
+
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Welcome to the admin dashboard"})
+	}), client.WithToken("tok"), client.WithBearer())
+
+	msg, err := c.Admin(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Welcome to the admin dashboard", msg)
+}
+
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   map[string]string
+		want   error
+	}{
+		{"invalid credentials", http.StatusUnauthorized, map[string]string{"error": "invalid email or password"}, client.ErrInvalidCredentials},
+		{"conflict", http.StatusConflict, map[string]string{"error": "user already exists"}, client.ErrUserAlreadyExists},
+		{"permission denied", http.StatusForbidden, map[string]string{"error": "permission denied", "reason": `no grant for "invoices:read"`}, client.ErrPermissionDenied},
+		{"expired token", http.StatusUnauthorized, map[string]string{"error": "invalid or expired token"}, client.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, tt.status, tt.body)
+			}))
+
+			_, err := c.Check(context.Background(), client.Check{Action: "invoices:read"})
+			require.ErrorIs(t, err, tt.want)
+
+			var apiErr *client.APIError
+			require.True(t, errors.As(err, &apiErr))
+			require.Equal(t, tt.status, apiErr.StatusCode)
+			require.Equal(t, tt.body["reason"], apiErr.Reason)
+		})
+	}
+}
+
+func TestRetryOn429(t *testing.T) {
+	var calls atomic.Int32
+
+	c := newClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Checks []client.Check `json:"checks"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Checks, 2)
+
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "too many requests from this IP"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"results": []map[string]any{
+			{"action": "invoices:read", "allowed": true},
+			{"action": "invoices:delete", "allowed": false},
+		}})
+	}), client.WithRetry(3, time.Millisecond, time.Second))
+
+	results, err := c.Check(context.Background(),
+		client.Check{Action: "invoices:read"},
+		client.Check{Action: "invoices:delete"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), calls.Load())
+	require.True(t, results[0].Allowed)
+	require.False(t, results[1].Allowed)
+}
+
+func TestRetryOn429_Exhausted(t *testing.T) {
+	var calls atomic.Int32
+
+	c := newClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "global rate limit exceeded"})
+	}), client.WithRetry(2, time.Millisecond, time.Second))
+
+	err := c.Ping(context.Background())
+	require.ErrorIs(t, err, client.ErrRateLimited)
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetryOn429_ContextCanceled(t *testing.T) {
+	c := newClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "route rate limit exceeded"})
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := c.Ping(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestForward(t *testing.T) {
+	c := newClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/authz/forward", r.URL.Path)
+		require.Equal(t, "DELETE", r.Header.Get("X-Forwarded-Method"))
+		require.Equal(t, "/invoices/7", r.Header.Get("X-Forwarded-Uri"))
+
+		w.Header().Set("X-Auth-User-Id", "3")
+		w.Header().Set("X-Auth-User-Email", "a@b.com")
+		w.Header().Set("X-Auth-User-Roles", "admin,editor")
+		w.WriteHeader(http.StatusOK)
+	}), client.WithToken("tok"))
+
+	id, err := c.Forward(context.Background(), client.ForwardRequest{Method: "DELETE", URI: "/invoices/7"})
+	require.NoError(t, err)
+	require.Equal(t, &client.Identity{UserID: 3, Email: "a@b.com", Roles: []string{"admin", "editor"}}, id)
+}
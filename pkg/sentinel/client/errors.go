@@ -0,0 +1,111 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	appErr "github.com/corradoisidoro/sentinel-rbac/internal/errors"
+)
+
+// Errors reported by the server. APIError unwraps to one of them, so callers
+// can test with errors.Is.
+var (
+	ErrUserAlreadyExists    = appErr.ErrUserAlreadyExists
+	ErrUserNotFound         = appErr.ErrUserNotFound
+	ErrInvalidInput         = appErr.ErrInvalidInput
+	ErrInternal             = appErr.ErrInternal
+	ErrInvalidRole          = appErr.ErrInvalidRole
+	ErrResourceNotFound     = appErr.ErrResourceNotFound
+	ErrSessionNotFound      = appErr.ErrSessionNotFound
+	ErrInvalidCredentials   = appErr.ErrInvalidPassword
+	ErrNoTokenProvided      = appErr.ErrNoTokenProvided
+	ErrInvalidToken         = appErr.ErrTokenExpiredOrInvalid
+	ErrTokenRevoked         = appErr.ErrTokenRevoked
+	ErrInvalidTokenSubject  = appErr.ErrInvalidTokenSubject
+	ErrInvalidClaims        = appErr.ErrInvalidClaims
+	ErrInvalidTokenIssuer   = appErr.ErrInvalidTokenIssuer
+	ErrInvalidTokenAudience = appErr.ErrInvalidTokenAudience
+	ErrInvalidRefreshToken  = appErr.ErrInvalidRefreshToken
+	ErrRefreshTokenReused   = appErr.ErrRefreshTokenReused
+	ErrReauthRequired       = appErr.ErrReauthRequired
+	ErrInvalidResetToken    = appErr.ErrInvalidResetToken
+	ErrPermissionDenied     = appErr.ErrPermissionDenied
+	ErrUnauthorized         = appErr.ErrUnauthorized
+	ErrRoleNotFound         = appErr.ErrRoleNotFound
+	ErrBadRequestBody       = appErr.ErrFailedToParseRequestBody
+	ErrMFARequired          = appErr.ErrMFARequired
+	ErrInvalidMFACode       = appErr.ErrInvalidMFACode
+	ErrInvalidMFAToken      = appErr.ErrInvalidMFAToken
+	ErrMFAAlreadyEnabled    = appErr.ErrMFAAlreadyEnabled
+	ErrMFANotEnrolled       = appErr.ErrMFANotEnrolled
+
+	// ErrRateLimited is reported for 429 responses once retries are exhausted.
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
+
+var knownErrors = []error{
+	ErrUserAlreadyExists,
+	ErrUserNotFound,
+	ErrInvalidInput,
+	ErrInternal,
+	ErrInvalidRole,
+	ErrResourceNotFound,
+	ErrSessionNotFound,
+	ErrInvalidCredentials,
+	ErrNoTokenProvided,
+	ErrInvalidToken,
+	ErrTokenRevoked,
+	ErrInvalidTokenSubject,
+	ErrInvalidClaims,
+	ErrInvalidTokenIssuer,
+	ErrInvalidTokenAudience,
+	ErrInvalidRefreshToken,
+	ErrRefreshTokenReused,
+	ErrReauthRequired,
+	ErrInvalidResetToken,
+	ErrPermissionDenied,
+	ErrUnauthorized,
+	ErrRoleNotFound,
+	ErrBadRequestBody,
+	ErrMFARequired,
+	ErrInvalidMFACode,
+	ErrInvalidMFAToken,
+	ErrMFAAlreadyEnabled,
+	ErrMFANotEnrolled,
+}
+
+// APIError is a non-2xx response from the server. Message is the "error"
+// field of the body and Reason the "reason" field of a 403, if any.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Reason != "" {
+		return fmt.Sprintf("sentinel: %d %s: %s", e.StatusCode, msg, e.Reason)
+	}
+	return fmt.Sprintf("sentinel: %d %s", e.StatusCode, msg)
+}
+
+// Unwrap returns the server error matching Message, ErrRateLimited for 429
+// responses, or nil.
+func (e *APIError) Unwrap() error {
+	for _, err := range knownErrors {
+		if err.Error() == e.Message {
+			return err
+		}
+	}
+	if e.StatusCode == http.StatusTooManyRequests {
+		return ErrRateLimited
+	}
+	return nil
+}
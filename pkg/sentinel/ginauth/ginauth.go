@@ -0,0 +1,154 @@
+// Package ginauth is Gin middleware enforcing sentinel-rbac tokens and
+// permissions in-process, using the validator and evaluator of package
+// authz. Responses match those of the sentinel-rbac server: 401 with
+// {"error": ...} and 403 with {"error": "permission denied", "reason": ...}.
+package ginauth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/gin-gonic/gin"
+)
+
+// ClaimsKey is the Gin context key RequireAuth stores *authz.Claims under.
+const ClaimsKey = "sentinel.claims"
+
+// DefaultCookieName is the cookie the sentinel-rbac server sets on login.
+const DefaultCookieName = "Authorization"
+
+type Middleware struct {
+	validator  authz.TokenValidator
+	evaluator  *authz.Evaluator
+	cookieName string
+}
+
+// Option configures optional Middleware settings.
+type Option func(*Middleware)
+
+// WithCookieName reads the token from the named cookie instead of
+// DefaultCookieName.
+func WithCookieName(name string) Option {
+	return func(m *Middleware) {
+		m.cookieName = name
+	}
+}
+
+// New returns middleware validating tokens with validator and answering role
+// and permission checks with evaluator. evaluator may be nil when only
+// RequireAuth is used.
+func New(validator authz.TokenValidator, evaluator *authz.Evaluator, opts ...Option) *Middleware {
+	m := &Middleware{
+		validator:  validator,
+		evaluator:  evaluator,
+		cookieName: DefaultCookieName,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// RequireAuth validates the token from the cookie or an
+// "Authorization: Bearer" header and stores its claims under ClaimsKey.
+func (m *Middleware) RequireAuth(c *gin.Context) {
+	tokenString := m.token(c)
+	if tokenString == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": authz.ErrNoToken.Error()})
+		return
+	}
+
+	claims, err := m.validator.Validate(tokenString)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Set(ClaimsKey, claims)
+	c.Next()
+}
+
+// RequireRole allows the request if the subject holds one of roles, directly
+// or by inheritance.
+func (m *Middleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return m.authorizeWith(func(c *gin.Context, claims *authz.Claims) (authz.Decision, error) {
+		ok, err := m.evaluator.HasRole(c.Request.Context(), claims, roles...)
+		if err != nil || ok {
+			return authz.Decision{Allowed: ok}, err
+		}
+		return authz.Decision{Reason: "missing role, need one of " + strings.Join(roles, ", ")}, nil
+	})
+}
+
+// RequirePermission allows the request only if the subject holds every
+// listed permission.
+func (m *Middleware) RequirePermission(perms ...string) gin.HandlerFunc {
+	return m.requirePermissions(authz.RequireAll, perms)
+}
+
+// RequireAnyPermission allows the request if the subject holds at least one
+// of the listed permissions.
+func (m *Middleware) RequireAnyPermission(perms ...string) gin.HandlerFunc {
+	return m.requirePermissions(authz.RequireAny, perms)
+}
+
+// Claims returns the claims RequireAuth stored on c.
+func Claims(c *gin.Context) (*authz.Claims, bool) {
+	val, exists := c.Get(ClaimsKey)
+	if !exists {
+		return nil, false
+	}
+
+	claims, ok := val.(*authz.Claims)
+	return claims, ok && claims != nil
+}
+
+func (m *Middleware) requirePermissions(mode authz.Mode, perms []string) gin.HandlerFunc {
+	return m.authorizeWith(func(c *gin.Context, claims *authz.Claims) (authz.Decision, error) {
+		return m.evaluator.Authorize(c.Request.Context(), claims, mode, perms...)
+	})
+}
+
+func (m *Middleware) authorizeWith(decide func(c *gin.Context, claims *authz.Claims) (authz.Decision, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := Claims(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": authz.ErrNoToken.Error()})
+			return
+		}
+
+		if m.evaluator == nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "authorizer not configured"})
+			return
+		}
+
+		decision, err := decide(c, claims)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "authorization failed"})
+			return
+		}
+
+		if !decision.Allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":  authz.ErrPermissionDenied.Error(),
+				"reason": decision.Reason,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (m *Middleware) token(c *gin.Context) string {
+	if cookie, err := c.Cookie(m.cookieName); err == nil && cookie != "" {
+		return cookie
+	}
+
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
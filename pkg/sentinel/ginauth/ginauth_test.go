@@ -0,0 +1,141 @@
+package ginauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/authz"
+	"github.com/corradoisidoro/sentinel-rbac/pkg/sentinel/ginauth"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("secret")
+
+func newMiddleware(t *testing.T, opts ...ginauth.Option) *ginauth.Middleware {
+	t.Helper()
+
+	table, err := authz.NewRoleTable(
+		authz.RoleDef{Name: "viewer", Allow: []string{"invoices:read"}},
+		authz.RoleDef{Name: "editor", Inherits: []string{"viewer"}, Allow: []string{"invoices:write"}, Deny: []string{"invoices:delete"}},
+	)
+	require.NoError(t, err)
+
+	return ginauth.New(authz.NewHMACValidator(secret), authz.NewEvaluator(table), opts...)
+}
+
+func newRouter(handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/protected", append(handlers, func(c *gin.Context) {
+		claims, _ := ginauth.Claims(c)
+		c.JSON(http.StatusOK, gin.H{"sub": claims.Subject})
+	})...)
+	return r
+}
+
+func token(t *testing.T, role string) string {
+	t.Helper()
+
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":  7,
+		"role": role,
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}).SignedString(secret)
+	require.NoError(t, err)
+	return s
+}
+
+func TestRequireAuth(t *testing.T) {
+	m := newMiddleware(t, ginauth.WithCookieName("session"))
+	r := newRouter(m.RequireAuth)
+
+	tests := []struct {
+		name   string
+		setup  func(req *http.Request)
+		status int
+	}{
+		{"no token", func(req *http.Request) {}, http.StatusUnauthorized},
+		{"invalid token", func(req *http.Request) {
+			req.AddCookie(&http.Cookie{Name: "session", Value: "invalid"})
+		}, http.StatusUnauthorized},
+		{"cookie", func(req *http.Request) {
+			req.AddCookie(&http.Cookie{Name: "session", Value: token(t, "viewer")})
+		}, http.StatusOK},
+		{"default cookie name ignored", func(req *http.Request) {
+			req.AddCookie(&http.Cookie{Name: ginauth.DefaultCookieName, Value: token(t, "viewer")})
+		}, http.StatusUnauthorized},
+		{"bearer", func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+token(t, "viewer"))
+		}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/protected", nil)
+			tt.setup(req)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				require.JSONEq(t, `{"sub":7}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireRoleAndPermission(t *testing.T) {
+	m := newMiddleware(t)
+
+	tests := []struct {
+		name   string
+		guard  gin.HandlerFunc
+		role   string
+		status int
+		reason string
+	}{
+		{"inherited role", m.RequireRole("viewer"), "editor", http.StatusOK, ""},
+		{"missing role", m.RequireRole("editor"), "viewer", http.StatusForbidden, "missing role, need one of editor"},
+		{"inherited permission", m.RequirePermission("invoices:read"), "editor", http.StatusOK, ""},
+		{"all required", m.RequirePermission("invoices:read", "invoices:write"), "viewer", http.StatusForbidden, `no grant for "invoices:write"`},
+		{"any granted", m.RequireAnyPermission("invoices:write", "invoices:read"), "viewer", http.StatusOK, ""},
+		{"deny", m.RequirePermission("invoices:delete"), "editor", http.StatusForbidden, `"invoices:delete" denied by role "editor" deny "invoices:delete"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRouter(m.RequireAuth, tt.guard)
+
+			req := httptest.NewRequest("GET", "/protected", nil)
+			req.AddCookie(&http.Cookie{Name: ginauth.DefaultCookieName, Value: token(t, tt.role)})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusForbidden {
+				var body map[string]string
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				require.Equal(t, "permission denied", body["error"])
+				require.Equal(t, tt.reason, body["reason"])
+			}
+		})
+	}
+}
+
+func TestRequirePermission_WithoutAuth(t *testing.T) {
+	m := newMiddleware(t)
+	r := newRouter(m.RequirePermission("invoices:read"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/protected", nil))
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}